@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"api/model"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+const (
+	// APIKeyPrefix starts every API key, telling keys apart from JWTs.
+	APIKeyPrefix        = "cg_"
+	apiKeySecretLength  = 32
+	apiKeyDisplayLength = len(APIKeyPrefix) + 8
+)
+
+// NewAPIKey returns a key for the user together with its secret, which is
+// shown to the caller once, and the hash of the secret to store.
+func NewAPIKey(userID string, name string, expiresAt *time.Time) (key model.APIKey, secret string, hash string) {
+	id := make([]byte, 16)
+	rand.Read(id)
+	random := make([]byte, apiKeySecretLength)
+	rand.Read(random)
+	secret = APIKeyPrefix + base64.RawURLEncoding.EncodeToString(random)
+
+	key = model.APIKey{
+		ID:     hex.EncodeToString(id),
+		UserID: userID,
+		Name:   name,
+		Prefix: secret[:apiKeyDisplayLength],
+	}
+	if expiresAt != nil {
+		formatted := expiresAt.UTC().Format(time.RFC3339)
+		key.ExpiresAt = &formatted
+	}
+	return key, secret, HashAPIKey(secret)
+}
+
+// HashAPIKey returns the stored form of an API key. Keys are random, so a
+// fast hash suffices.
+func HashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsAPIKey reports whether a bearer credential is an API key rather than a JWT.
+func IsAPIKey(credential string) bool {
+	return strings.HasPrefix(credential, APIKeyPrefix)
+}
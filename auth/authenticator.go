@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"api/config"
+	"api/model"
+	"api/repository"
+	"api/tenant"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// ErrInvalidCredentials is returned for credentials that do not identify a
+// user: malformed, badly signed, expired or revoked.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// lastUsedResolution limits how often an API key's last use is written.
+const lastUsedResolution = time.Minute
+
+// Authenticator resolves bearer JWTs and API keys to the user they belong
+// to, with the permissions granted by the user's roles.
+type Authenticator struct {
+	tokens               *TokenVerifier
+	anonymousPermissions []string
+	apiKeyRepository     *repository.APIKeyRepository
+	userRepository       *repository.UserRepository
+	roleRepository       *repository.RoleRepository
+	customerRepository   *repository.CustomerRepository
+}
+
+func NewAuthenticator(cfg config.AuthConfig, apiKeyRepository *repository.APIKeyRepository, userRepository *repository.UserRepository, roleRepository *repository.RoleRepository, customerRepository *repository.CustomerRepository) (*Authenticator, error) {
+	tokens, err := NewTokenVerifier(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Authenticator{
+		tokens:               tokens,
+		anonymousPermissions: cfg.AnonymousPermissions,
+		apiKeyRepository:     apiKeyRepository,
+		userRepository:       userRepository,
+		roleRepository:       roleRepository,
+		customerRepository:   customerRepository,
+	}, nil
+}
+
+// Anonymous returns a copy of ctx serving a caller without credentials with
+// the configured anonymous permissions.
+func (authenticator *Authenticator) Anonymous(ctx context.Context) context.Context {
+	return WithAnonymousPermissions(ctx, authenticator.anonymousPermissions)
+}
+
+// AuthenticateToken verifies a JWT whose subject is a user ID or, for
+// customer tokens, a customer ID. The token's tenant claim must name the
+// tenant ctx serves.
+func (authenticator *Authenticator) AuthenticateToken(ctx context.Context, token string) (model.Principal, error) {
+	if authenticator.tokens == nil {
+		return model.Principal{}, fmt.Errorf("%w: bearer tokens are not enabled", ErrInvalidCredentials)
+	}
+	claims, err := authenticator.tokens.Verify(token)
+	if err != nil {
+		return model.Principal{}, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	if claims.Tenant != tenant.FromContext(ctx) {
+		return model.Principal{}, fmt.Errorf("%w: token is for another tenant", ErrInvalidCredentials)
+	}
+	if claims.Kind == TokenKindCustomer {
+		return authenticator.customerPrincipal(ctx, claims.Subject)
+	}
+	return authenticator.principal(ctx, claims.Subject, model.AuthMethodJWT, "")
+}
+
+// TokenTenant returns the tenant claim of a valid bearer JWT, or "" for
+// invalid tokens and tokens without one.
+func (authenticator *Authenticator) TokenTenant(token string) string {
+	if authenticator.tokens == nil {
+		return ""
+	}
+	claims, err := authenticator.tokens.Verify(token)
+	if err != nil {
+		return ""
+	}
+	return claims.Tenant
+}
+
+// AuthenticateAPIKey looks up an API key by its hash and checks that it is
+// neither revoked nor expired.
+func (authenticator *Authenticator) AuthenticateAPIKey(ctx context.Context, secret string) (model.Principal, error) {
+	key, err := authenticator.apiKeyRepository.GetAPIKeyByHash(ctx, HashAPIKey(secret))
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Principal{}, fmt.Errorf("%w: unknown API key", ErrInvalidCredentials)
+	}
+	if err != nil {
+		return model.Principal{}, err
+	}
+	if key.RevokedAt != nil {
+		return model.Principal{}, fmt.Errorf("%w: API key was revoked", ErrInvalidCredentials)
+	}
+	now := time.Now()
+	if key.ExpiresAt != nil {
+		expiresAt, err := time.Parse(time.RFC3339, *key.ExpiresAt)
+		if err != nil {
+			return model.Principal{}, err
+		}
+		if !now.Before(expiresAt) {
+			return model.Principal{}, fmt.Errorf("%w: API key expired", ErrInvalidCredentials)
+		}
+	}
+
+	principal, err := authenticator.principal(ctx, key.UserID, model.AuthMethodAPIKey, key.ID)
+	if err != nil {
+		return principal, err
+	}
+	principal.DailyQuota = key.DailyQuota
+	if stale(key.LastUsedAt, now) {
+		if err := authenticator.apiKeyRepository.TouchAPIKey(ctx, key.ID, now); err != nil {
+			slog.WarnContext(ctx, "failed to record API key use", "key_id", key.ID, "error", err)
+		}
+	}
+	return principal, nil
+}
+
+func (authenticator *Authenticator) principal(ctx context.Context, userID string, method string, keyID string) (model.Principal, error) {
+	user, err := authenticator.userRepository.GetUserByID(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Principal{}, fmt.Errorf("%w: unknown user", ErrInvalidCredentials)
+	}
+	if err != nil {
+		return model.Principal{}, err
+	}
+	permissions, err := authenticator.roleRepository.GetUserPermissions(ctx, user.ID)
+	if err != nil {
+		return model.Principal{}, err
+	}
+	return model.Principal{
+		Type:        model.PrincipalTypeUser,
+		UserID:      user.ID,
+		Name:        user.Name,
+		Method:      method,
+		KeyID:       keyID,
+		Permissions: permissions,
+	}, nil
+}
+
+func (authenticator *Authenticator) customerPrincipal(ctx context.Context, customerID string) (model.Principal, error) {
+	customer, err := authenticator.customerRepository.GetCustomerByID(ctx, customerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Principal{}, fmt.Errorf("%w: unknown customer", ErrInvalidCredentials)
+	}
+	if err != nil {
+		return model.Principal{}, err
+	}
+	return model.Principal{
+		Type:        model.PrincipalTypeCustomer,
+		CustomerID:  customer.ID,
+		Name:        customer.Name,
+		Method:      model.AuthMethodJWT,
+		Permissions: slices.Clone(CustomerPermissions),
+	}, nil
+}
+
+func stale(lastUsedAt *string, now time.Time) bool {
+	if lastUsedAt == nil {
+		return true
+	}
+	last, err := time.Parse(time.RFC3339, *lastUsedAt)
+	return err != nil || now.Sub(last) >= lastUsedResolution
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"api/model"
+	"context"
+	"strings"
+)
+
+// Permissions declared by routes and checked by handlers.
+const (
+	PermissionProductsRead  = "products:read"
+	PermissionProductsWrite = "products:write"
+	// PermissionProductsPrice is needed in addition to products:write to set
+	// or change a product's price.
+	PermissionProductsPrice  = "products:price"
+	PermissionCustomersRead  = "customers:read"
+	PermissionCustomersWrite = "customers:write"
+	PermissionOrdersRead     = "orders:read"
+	PermissionOrdersWrite    = "orders:write"
+	PermissionOrdersDelete   = "orders:delete"
+	PermissionAuditRead      = "audit:read"
+	PermissionWebhooksManage = "webhooks:manage"
+	PermissionEventsRead     = "events:read"
+	PermissionAdmin          = "admin"
+
+	// The ":own" permissions limit the caller to its own customer record and
+	// orders. The unscoped permission implies them.
+	PermissionOwnCustomerRead = "customers:read:own"
+	PermissionOwnOrdersRead   = "orders:read:own"
+	PermissionOwnOrdersWrite  = "orders:write:own"
+)
+
+// CustomerPermissions are held by every customer principal.
+var CustomerPermissions = []string{
+	PermissionOwnCustomerRead,
+	PermissionOwnOrdersRead,
+	PermissionOwnOrdersWrite,
+}
+
+// Grants reports whether the granted permissions include permission, either
+// exactly, through "*" or through a "resource:*" wildcard, or for an ":own"
+// permission through the unscoped one.
+func Grants(granted []string, permission string) bool {
+	resource, _, _ := strings.Cut(permission, ":")
+	for _, grant := range granted {
+		if grant == permission || grant == "*" || grant == resource+":*" {
+			return true
+		}
+	}
+	if unscoped, ok := strings.CutSuffix(permission, ":own"); ok {
+		return Grants(granted, unscoped)
+	}
+	return false
+}
+
+// OwnCustomer returns the customer the caller is limited to when it does
+// not hold the unscoped permission, typically because it is a customer
+// itself. Callers without a linked customer are limited to an empty ID,
+// which matches nothing.
+func OwnCustomer(ctx context.Context, permission string) (customerID string, scoped bool) {
+	if HasPermission(ctx, permission) {
+		return "", false
+	}
+	principal, _ := PrincipalFromContext(ctx)
+	return principal.CustomerID, true
+}
+
+// Actor identifies the principal in the audit log and scopes its
+// idempotency keys: the user ID, or "customer:" and the customer ID.
+func Actor(principal model.Principal) string {
+	if principal.Type == model.PrincipalTypeCustomer {
+		return model.PrincipalTypeCustomer + ":" + principal.CustomerID
+	}
+	return principal.UserID
+}
+
+// HasPermission reports whether the caller stored in ctx holds permission.
+// Anonymous callers hold those given by WithAnonymousPermissions, if any.
+func HasPermission(ctx context.Context, permission string) bool {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		permissions, _ := ctx.Value(anonymousPermissionsContextKey{}).([]string)
+		return Grants(permissions, permission)
+	}
+	return Grants(principal.Permissions, permission)
+}
@@ -0,0 +1,27 @@
+package auth
+
+import (
+	"api/model"
+	"context"
+)
+
+type principalContextKey struct{}
+
+type anonymousPermissionsContextKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the authenticated caller.
+func WithPrincipal(ctx context.Context, principal model.Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// PrincipalFromContext returns the caller stored in ctx, if the request was authenticated.
+func PrincipalFromContext(ctx context.Context) (model.Principal, bool) {
+	principal, ok := ctx.Value(principalContextKey{}).(model.Principal)
+	return principal, ok
+}
+
+// WithAnonymousPermissions returns a copy of ctx granting permissions to its
+// caller for as long as it is not authenticated.
+func WithAnonymousPermissions(ctx context.Context, permissions []string) context.Context {
+	return context.WithValue(ctx, anonymousPermissionsContextKey{}, permissions)
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"api/config"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TokenKindCustomer marks tokens issued to customers, whose subject is a
+// customer ID rather than a user ID.
+const TokenKindCustomer = "customer"
+
+// Claims are the JWT claims read from bearer tokens.
+type Claims struct {
+	jwt.RegisteredClaims
+	// Kind is "customer" for customer tokens and empty for user tokens.
+	Kind string `json:"kind,omitempty"`
+	// Tenant is the tenant whose users or customers the subject belongs
+	// to; empty for the main database.
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// TokenVerifier checks the signature and claims of bearer JWTs.
+type TokenVerifier struct {
+	secret  []byte
+	rsaKeys map[string]*rsa.PublicKey
+	parser  *jwt.Parser
+}
+
+// NewTokenVerifier returns a verifier for the algorithms cfg provides keys
+// for, or nil when JWTs are not configured.
+func NewTokenVerifier(cfg config.AuthConfig) (*TokenVerifier, error) {
+	verifier := &TokenVerifier{}
+	var methods []string
+	if cfg.JWTSecret != "" {
+		verifier.secret = []byte(cfg.JWTSecret)
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.JWKSFile != "" {
+		keys, err := LoadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		verifier.rsaKeys = keys
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(methods) == 0 {
+		return nil, nil
+	}
+
+	options := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithExpirationRequired()}
+	if cfg.Issuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		options = append(options, jwt.WithAudience(cfg.Audience))
+	}
+	verifier.parser = jwt.NewParser(options...)
+	return verifier, nil
+}
+
+// Verify returns the claims of a valid token.
+func (verifier *TokenVerifier) Verify(token string) (Claims, error) {
+	var claims Claims
+	_, err := verifier.parser.ParseWithClaims(token, &claims, verifier.key)
+	if err != nil {
+		return claims, err
+	}
+	if claims.Subject == "" {
+		return claims, errors.New("token has no subject")
+	}
+	if claims.Kind != "" && claims.Kind != TokenKindCustomer {
+		return claims, fmt.Errorf("unknown token kind %q", claims.Kind)
+	}
+	return claims, nil
+}
+
+func (verifier *TokenVerifier) key(token *jwt.Token) (any, error) {
+	switch token.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		return verifier.secret, nil
+	case jwt.SigningMethodRS256.Alg():
+		kid, _ := token.Header["kid"].(string)
+		key, ok := verifier.rsaKeys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key ID %q", kid)
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+}
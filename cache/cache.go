@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Cache keeps up to maxEntries entries for ttl each, evicting the least
+// recently used first. It is safe for concurrent use.
+type Cache[K comparable, V any] struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu    sync.Mutex
+	items map[K]*list.Element
+	order *list.List
+	// generation is incremented by Clear so that loads started before it
+	// are not stored.
+	generation uint64
+}
+
+type item[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+func New[K comparable, V any](ttl time.Duration, maxEntries int) *Cache[K, V] {
+	return &Cache[K, V]{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		items:      map[K]*list.Element{},
+		order:      list.New(),
+	}
+}
+
+// Get returns the value stored under key, calling load to read and store it
+// when it is missing or expired. Errors are not cached.
+func (cache *Cache[K, V]) Get(key K, load func() (V, error)) (V, error) {
+	now := time.Now()
+	cache.mu.Lock()
+	if element, ok := cache.items[key]; ok {
+		cached := element.Value.(*item[K, V])
+		if now.Before(cached.expires) {
+			cache.order.MoveToFront(element)
+			cache.mu.Unlock()
+			return cached.value, nil
+		}
+		cache.remove(element)
+	}
+	generation := cache.generation
+	cache.mu.Unlock()
+
+	value, err := load()
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if generation != cache.generation {
+		// A write cleared the cache while loading; value may predate it
+		return value, nil
+	}
+	if element, ok := cache.items[key]; ok {
+		cache.remove(element)
+	}
+	cache.items[key] = cache.order.PushFront(&item[K, V]{key: key, value: value, expires: now.Add(cache.ttl)})
+	for cache.order.Len() > cache.maxEntries {
+		cache.remove(cache.order.Back())
+	}
+	return value, nil
+}
+
+// Clear removes every entry.
+func (cache *Cache[K, V]) Clear() {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.generation++
+	clear(cache.items)
+	cache.order.Init()
+}
+
+// Len returns the number of entries, including expired ones not yet evicted.
+func (cache *Cache[K, V]) Len() int {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	return cache.order.Len()
+}
+
+func (cache *Cache[K, V]) remove(element *list.Element) {
+	cache.order.Remove(element)
+	delete(cache.items, element.Value.(*item[K, V]).key)
+}
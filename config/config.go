@@ -0,0 +1,302 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the settings shared by every command. Values are resolved
+// from, in increasing order of precedence: the defaults, a YAML or TOML
+// config file, CLEANGO_* environment variables and command-line flags.
+//
+// Every setting has an environment variable named after its file key, e.g.
+// server.listen_addr is CLEANGO_SERVER_LISTEN_ADDR. Lists are comma-separated
+// and durations use Go syntax such as "24h". Fields tagged secret are
+// redacted by "config print".
+type Config struct {
+	Server      ServerConfig      `yaml:"server" toml:"server"`
+	Database    DatabaseConfig    `yaml:"database" toml:"database"`
+	CORS        CORSConfig        `yaml:"cors" toml:"cors"`
+	Log         LogConfig         `yaml:"log" toml:"log"`
+	Outbox      OutboxConfig      `yaml:"outbox" toml:"outbox"`
+	Idempotency IdempotencyConfig `yaml:"idempotency" toml:"idempotency"`
+	Tracing     TracingConfig     `yaml:"tracing" toml:"tracing"`
+	Auth        AuthConfig        `yaml:"auth" toml:"auth"`
+	Tenancy     TenancyConfig     `yaml:"tenancy" toml:"tenancy"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit" toml:"rate_limit"`
+	Cache       CacheConfig       `yaml:"cache" toml:"cache"`
+	API         APIConfig         `yaml:"api" toml:"api"`
+	GRPC        GRPCConfig        `yaml:"grpc" toml:"grpc"`
+}
+
+type ServerConfig struct {
+	ListenAddr string `yaml:"listen_addr" toml:"listen_addr"`
+	// ShutdownTimeout bounds how long in-flight requests are drained on SIGINT or SIGTERM.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+}
+
+type DatabaseConfig struct {
+	Path          string `yaml:"path" toml:"path"`
+	MigrationsDir string `yaml:"migrations_dir" toml:"migrations_dir"`
+}
+
+type CORSConfig struct {
+	AllowOrigins     []string `yaml:"allow_origins" toml:"allow_origins"`
+	AllowCredentials bool     `yaml:"allow_credentials" toml:"allow_credentials"`
+}
+
+type LogConfig struct {
+	// Level is debug, info, warn or error; it can be changed at runtime
+	// through PUT /admin/log-level.
+	Level string `yaml:"level" toml:"level"`
+	// Format is "json" or "text".
+	Format string `yaml:"format" toml:"format"`
+	// Requests enables the access log.
+	Requests bool `yaml:"requests" toml:"requests"`
+}
+
+// OutboxConfig configures the optional sinks domain events are published to
+// besides the in-process bus and webhook subscriptions.
+type OutboxConfig struct {
+	WebhookURL  string `yaml:"webhook_url" toml:"webhook_url" secret:"true"`
+	NATSAddr    string `yaml:"nats_addr" toml:"nats_addr"`
+	NATSSubject string `yaml:"nats_subject" toml:"nats_subject"`
+}
+
+type IdempotencyConfig struct {
+	TTL time.Duration `yaml:"ttl" toml:"ttl"`
+}
+
+// TracingConfig selects where OpenTelemetry spans are exported.
+type TracingConfig struct {
+	// Exporter is "none", "otlp" (OTLP over HTTP) or "stdout" (JSON lines,
+	// written to File when set).
+	Exporter    string  `yaml:"exporter" toml:"exporter"`
+	ServiceName string  `yaml:"service_name" toml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio"`
+	// Endpoint is the OTLP collector's host:port; empty uses OTEL_EXPORTER_OTLP_ENDPOINT.
+	Endpoint string `yaml:"endpoint" toml:"endpoint"`
+	Insecure bool   `yaml:"insecure" toml:"insecure"`
+	// Headers are sent with every OTLP export, as "key=value,...".
+	Headers string `yaml:"headers" toml:"headers" secret:"true"`
+	File    string `yaml:"file" toml:"file"`
+}
+
+// AuthConfig selects how callers are identified. API keys created with
+// "cleango key create" or POST /auth/keys are always accepted; bearer JWTs
+// are accepted once JWTSecret (HS256) or JWKSFile (RS256) is set.
+type AuthConfig struct {
+	// Required rejects requests without credentials; when false they are
+	// served anonymously, but invalid credentials are still rejected.
+	Required  bool   `yaml:"required" toml:"required"`
+	JWTSecret string `yaml:"jwt_secret" toml:"jwt_secret" secret:"true"`
+	// JWKSFile is a local JSON Web Key Set holding the RSA public keys
+	// tokens may be signed with, selected by the token's kid.
+	JWKSFile string `yaml:"jwks_file" toml:"jwks_file"`
+	// Issuer and Audience, when set, must match the iss and aud claims.
+	Issuer   string `yaml:"issuer" toml:"issuer"`
+	Audience string `yaml:"audience" toml:"audience"`
+	// AnonymousPermissions are held by requests without credentials when
+	// Required is false, e.g. products:read to publish the catalogue.
+	AnonymousPermissions []string `yaml:"anonymous_permissions" toml:"anonymous_permissions"`
+}
+
+// TenancyConfig enables serving several storefronts from one process. Each
+// tenant has its own database file in Dir and is selected by the request's
+// Host header or the tenant claim of its bearer token; other requests use
+// the main database, which also holds the tenant registry. The outbox
+// webhook_url and NATS sinks only receive the main database's events.
+type TenancyConfig struct {
+	Enabled bool   `yaml:"enabled" toml:"enabled"`
+	Dir     string `yaml:"dir" toml:"dir"`
+}
+
+// RateLimitConfig throttles callers with token buckets: every request takes
+// a token from each bucket it counts against, and tokens are added back at
+// a rate per second up to a burst size.
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+	// Store keeps the buckets in "memory" or in the main "sqlite" database,
+	// where every process using it shares them.
+	Store string `yaml:"store" toml:"store"`
+	// CallerRate and CallerBurst limit each API key, user or customer;
+	// anonymous requests count against their IP instead.
+	CallerRate  float64 `yaml:"caller_rate" toml:"caller_rate"`
+	CallerBurst int     `yaml:"caller_burst" toml:"caller_burst"`
+	// IPRate and IPBurst limit each client IP, whoever the caller.
+	IPRate  float64 `yaml:"ip_rate" toml:"ip_rate"`
+	IPBurst int     `yaml:"ip_burst" toml:"ip_burst"`
+	// Routes further limit each caller per route group, the first path
+	// segment after any version, as "group=rate/burst" entries such as
+	// "orders=1/10".
+	Routes []string `yaml:"routes" toml:"routes"`
+	// DailyQuota caps the requests made with each API key per UTC day; 0 is
+	// unlimited. Keys created with a daily_quota override it.
+	DailyQuota int `yaml:"daily_quota" toml:"daily_quota"`
+}
+
+// CacheConfig keeps product and customer reads in the process. Writes made
+// by the process empty the cache at once; writes by other processes sharing
+// the database, such as "cleango import", are seen once entries expire.
+type CacheConfig struct {
+	Enabled bool          `yaml:"enabled" toml:"enabled"`
+	TTL     time.Duration `yaml:"ttl" toml:"ttl"`
+	// MaxEntries bounds the lists and the single rows kept of each resource.
+	MaxEntries int `yaml:"max_entries" toml:"max_entries"`
+}
+
+// APIConfig announces the retirement of API versions. The API is served
+// under /v1 and /v2, and at the root as an unversioned alias of v1.
+type APIConfig struct {
+	// Deprecations are "version=date" or "version=date/sunset" entries,
+	// with YYYY-MM-DD dates, for the versions "unversioned", "v1" and "v2".
+	// Deprecated versions are answered with Deprecation and Sunset headers
+	// and, from the sunset date, with 410 Gone.
+	Deprecations []string `yaml:"deprecations" toml:"deprecations"`
+}
+
+// GRPCConfig configures the gRPC server started alongside the HTTP server.
+// It serves products, customers and orders with the authentication and
+// permissions of the REST API, and supports server reflection.
+type GRPCConfig struct {
+	Enabled    bool   `yaml:"enabled" toml:"enabled"`
+	ListenAddr string `yaml:"listen_addr" toml:"listen_addr"`
+}
+
+// Deprecation is when an API version was deprecated and, unless zero, when
+// it stops being served.
+type Deprecation struct {
+	Date   time.Time
+	Sunset time.Time
+}
+
+// APIVersions are the versions Deprecations may name.
+var APIVersions = []string{"unversioned", "v1", "v2"}
+
+// DeprecationSchedule parses Deprecations by version.
+func (config APIConfig) DeprecationSchedule() (map[string]Deprecation, error) {
+	schedule := map[string]Deprecation{}
+	for _, entry := range config.Deprecations {
+		version, value, ok := strings.Cut(entry, "=")
+		date, sunset, hasSunset := strings.Cut(value, "/")
+		deprecation := Deprecation{}
+		var err error
+		if ok {
+			deprecation.Date, err = time.Parse(time.DateOnly, date)
+			if err == nil && hasSunset {
+				deprecation.Sunset, err = time.Parse(time.DateOnly, sunset)
+			}
+		}
+		if !ok || err != nil || !slices.Contains(APIVersions, version) || (hasSunset && deprecation.Sunset.Before(deprecation.Date)) {
+			return nil, fmt.Errorf("invalid entry %q, expected version=YYYY-MM-DD[/YYYY-MM-DD] for one of %s", entry, strings.Join(APIVersions, ", "))
+		}
+		schedule[version] = deprecation
+	}
+	return schedule, nil
+}
+
+// RateLimit is a token bucket size and refill rate.
+type RateLimit struct {
+	// Rate is the number of tokens added per second.
+	Rate  float64
+	Burst int
+}
+
+// RouteLimits parses Routes by route group.
+func (config RateLimitConfig) RouteLimits() (map[string]RateLimit, error) {
+	limits := map[string]RateLimit{}
+	for _, entry := range config.Routes {
+		group, value, ok := strings.Cut(entry, "=")
+		rate, burst, ok2 := strings.Cut(value, "/")
+		limit := RateLimit{}
+		var err error
+		if ok && ok2 {
+			limit.Rate, err = strconv.ParseFloat(rate, 64)
+			if err == nil {
+				limit.Burst, err = strconv.Atoi(burst)
+			}
+		}
+		if !ok || !ok2 || err != nil || group == "" || limit.Rate <= 0 || limit.Burst < 1 {
+			return nil, fmt.Errorf("invalid entry %q, expected group=rate/burst", entry)
+		}
+		limits[group] = limit
+	}
+	return limits, nil
+}
+
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			ListenAddr:      ":8080",
+			ShutdownTimeout: 30 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Path:          "database/database.db",
+			MigrationsDir: "database/migrations",
+		},
+		CORS: CORSConfig{
+			AllowOrigins: []string{"*"},
+		},
+		Log: LogConfig{
+			Level:    "info",
+			Format:   "json",
+			Requests: true,
+		},
+		Outbox: OutboxConfig{
+			NATSSubject: "cleango",
+		},
+		Idempotency: IdempotencyConfig{
+			TTL: 24 * time.Hour,
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "cleango",
+			SampleRatio: 1,
+		},
+		Auth: AuthConfig{
+			Required:             true,
+			AnonymousPermissions: []string{"products:read"},
+		},
+		Tenancy: TenancyConfig{
+			Dir: "database/tenants",
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:     true,
+			Store:       "memory",
+			CallerRate:  20,
+			CallerBurst: 100,
+			IPRate:      50,
+			IPBurst:     200,
+		},
+		Cache: CacheConfig{
+			Enabled:    true,
+			TTL:        time.Minute,
+			MaxEntries: 1000,
+		},
+		GRPC: GRPCConfig{
+			Enabled:    true,
+			ListenAddr: ":9090",
+		},
+	}
+}
+
+// MigrationsURL returns the migrations directory as a migrate source URL.
+func (config *Config) MigrationsURL() string {
+	return "file://" + config.Database.MigrationsDir
+}
+
+var tenantIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// TenantDatabasePath returns the database file of the tenant with the given
+// ID. IDs are lowercase letters, digits and dashes.
+func (config *Config) TenantDatabasePath(id string) (string, error) {
+	if !tenantIDPattern.MatchString(id) {
+		return "", fmt.Errorf("invalid tenant ID %q, expected lowercase letters, digits and dashes", id)
+	}
+	return filepath.Join(config.Tenancy.Dir, id+".db"), nil
+}
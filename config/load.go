@@ -0,0 +1,217 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	envPrefix = "CLEANGO_"
+	// EnvConfigFile names the config file when the -config flag is not given.
+	EnvConfigFile = envPrefix + "CONFIG"
+)
+
+// Flags are the shared command-line flags. Only flags that were set override
+// the file and environment.
+type Flags struct {
+	fs            *flag.FlagSet
+	path          string
+	listenAddr    string
+	databasePath  string
+	migrationsDir string
+	tenant        string
+}
+
+// RegisterFlags adds the shared flags to fs.
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	flags := &Flags{fs: fs}
+	defaults := Default()
+	fs.StringVar(&flags.path, "config", "", "config file (.yaml, .yml or .toml; default $"+EnvConfigFile+")")
+	fs.StringVar(&flags.listenAddr, "listen", defaults.Server.ListenAddr, "HTTP listen address")
+	fs.StringVar(&flags.databasePath, "db", defaults.Database.Path, "SQLite database file")
+	fs.StringVar(&flags.migrationsDir, "migrations", defaults.Database.MigrationsDir, "migrations directory")
+	fs.StringVar(&flags.tenant, "tenant", "", "use the database of this tenant instead of the main database")
+	return flags
+}
+
+// Load resolves the configuration once fs has been parsed and validates it.
+func (flags *Flags) Load() (*Config, error) {
+	path := flags.path
+	if path == "" {
+		path = os.Getenv(EnvConfigFile)
+	}
+
+	config := Default()
+	if path != "" {
+		if err := config.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := config.loadEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+
+	flags.fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "listen":
+			config.Server.ListenAddr = flags.listenAddr
+		case "db":
+			config.Database.Path = flags.databasePath
+		case "migrations":
+			config.Database.MigrationsDir = flags.migrationsDir
+		}
+	})
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if flags.tenant != "" {
+		path, err := config.TenantDatabasePath(flags.tenant)
+		if err != nil {
+			return nil, fmt.Errorf("config: -tenant: %w", err)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("config: -tenant: tenant %q is not provisioned", flags.tenant)
+		}
+		config.Database.Path = path
+	}
+	return config, nil
+}
+
+// loadFile overlays the settings in a YAML or TOML file, chosen by extension.
+// Unknown keys are an error so that typos do not go unnoticed.
+func (config *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("config: %s: %w", path, err)
+		}
+	case ".toml":
+		metadata, err := toml.Decode(string(data), config)
+		if err != nil {
+			return fmt.Errorf("config: %s: %w", path, err)
+		}
+		if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("config: %s: unknown key %s", path, undecoded[0])
+		}
+	default:
+		return fmt.Errorf("config: %s: unsupported file type, expected .yaml, .yml or .toml", path)
+	}
+	return nil
+}
+
+// loadEnv overlays the CLEANGO_* variables returned by lookup.
+func (config *Config) loadEnv(lookup func(string) (string, bool)) error {
+	var errs []error
+	for _, setting := range settings(config) {
+		value, ok := lookup(setting.env())
+		if !ok {
+			continue
+		}
+		if err := setting.set(value); err != nil {
+			errs = append(errs, fmt.Errorf("config: %s: %w", setting.env(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// setting is a leaf field of Config.
+type setting struct {
+	keys   []string
+	value  reflect.Value
+	secret bool
+}
+
+// settings lists the leaf fields of config in declaration order.
+func settings(config *Config) []setting {
+	var result []setting
+	var walk func(value reflect.Value, keys []string)
+	walk = func(value reflect.Value, keys []string) {
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			path := append(slices.Clone(keys), field.Tag.Get("yaml"))
+			if field.Type.Kind() == reflect.Struct {
+				walk(value.Field(i), path)
+				continue
+			}
+			result = append(result, setting{keys: path, value: value.Field(i), secret: field.Tag.Get("secret") == "true"})
+		}
+	}
+	walk(reflect.ValueOf(config).Elem(), nil)
+	return result
+}
+
+// key returns the dotted file key, e.g. "server.listen_addr".
+func (setting setting) key() string {
+	return strings.Join(setting.keys, ".")
+}
+
+func (setting setting) env() string {
+	return envPrefix + strings.ToUpper(strings.Join(setting.keys, "_"))
+}
+
+func (setting setting) set(value string) error {
+	switch target := setting.value.Addr().Interface().(type) {
+	case *string:
+		*target = value
+	case *bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		*target = parsed
+	case *float64:
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		*target = parsed
+	case *int:
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		*target = parsed
+	case *time.Duration:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		*target = parsed
+	case *[]string:
+		*target = splitList(value)
+	default:
+		return fmt.Errorf("unsupported setting type %s", setting.value.Type())
+	}
+	return nil
+}
+
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "REDACTED"
+
+// Redacted returns a copy of config with every non-empty secret replaced.
+func (config *Config) Redacted() *Config {
+	copied := *config
+	for _, setting := range settings(&copied) {
+		if setting.secret && !setting.value.IsZero() && setting.value.Kind() == reflect.String {
+			setting.value.SetString(redacted)
+		}
+	}
+	return &copied
+}
+
+// Print writes the redacted config to w as "yaml" or "toml".
+func (config *Config) Print(w io.Writer, format string) error {
+	redactedConfig := config.Redacted()
+	switch format {
+	case "yaml", "":
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(redactedConfig); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case "toml":
+		return toml.NewEncoder(w).Encode(redactedConfig)
+	default:
+		return fmt.Errorf("unsupported format %q, expected yaml or toml", format)
+	}
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Validate reports every invalid setting at once.
+func (config *Config) Validate() error {
+	var errs []error
+	invalid := func(key string, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("config: %s: %s", key, fmt.Sprintf(format, args...)))
+	}
+
+	if _, _, err := net.SplitHostPort(config.Server.ListenAddr); err != nil {
+		invalid("server.listen_addr", "invalid address %q, expected host:port", config.Server.ListenAddr)
+	}
+	if config.Server.ShutdownTimeout <= 0 {
+		invalid("server.shutdown_timeout", "must be positive")
+	}
+
+	if config.Database.Path == "" {
+		invalid("database.path", "is required")
+	}
+	if info, err := os.Stat(config.Database.MigrationsDir); err != nil || !info.IsDir() {
+		invalid("database.migrations_dir", "%q is not a directory", config.Database.MigrationsDir)
+	}
+
+	if len(config.CORS.AllowOrigins) == 0 {
+		invalid("cors.allow_origins", "must not be empty")
+	}
+	for _, origin := range config.CORS.AllowOrigins {
+		if origin == "*" {
+			if config.CORS.AllowCredentials {
+				invalid("cors.allow_origins", `"*" cannot be combined with allow_credentials`)
+			}
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" || strings.TrimPrefix(origin, u.Scheme+"://") != u.Host {
+			invalid("cors.allow_origins", "invalid origin %q, expected scheme://host[:port]", origin)
+		}
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(config.Log.Level)); err != nil {
+		invalid("log.level", "unknown level %q, expected debug, info, warn or error", config.Log.Level)
+	}
+	switch config.Log.Format {
+	case "json", "text":
+	default:
+		invalid("log.format", "unknown format %q, expected json or text", config.Log.Format)
+	}
+
+	if config.Outbox.WebhookURL != "" {
+		if u, err := url.Parse(config.Outbox.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			// The URL itself is not echoed as it may carry credentials.
+			invalid("outbox.webhook_url", "must be an absolute http or https URL")
+		}
+	}
+	if config.Outbox.NATSAddr != "" {
+		if _, _, err := net.SplitHostPort(config.Outbox.NATSAddr); err != nil {
+			invalid("outbox.nats_addr", "invalid address %q, expected host:port", config.Outbox.NATSAddr)
+		}
+	}
+	if config.Outbox.NATSSubject == "" {
+		invalid("outbox.nats_subject", "is required")
+	}
+
+	if config.Idempotency.TTL <= 0 {
+		invalid("idempotency.ttl", "must be positive")
+	}
+
+	switch config.Tracing.Exporter {
+	case "none", "otlp", "stdout":
+	default:
+		invalid("tracing.exporter", "unknown exporter %q, expected none, otlp or stdout", config.Tracing.Exporter)
+	}
+	if config.Tracing.ServiceName == "" {
+		invalid("tracing.service_name", "is required")
+	}
+	if config.Tracing.SampleRatio < 0 || config.Tracing.SampleRatio > 1 {
+		invalid("tracing.sample_ratio", "must be between 0 and 1")
+	}
+	if config.Tracing.Endpoint != "" {
+		if _, _, err := net.SplitHostPort(config.Tracing.Endpoint); err != nil {
+			invalid("tracing.endpoint", "invalid address %q, expected host:port", config.Tracing.Endpoint)
+		}
+	}
+
+	if config.Auth.JWTSecret != "" && len(config.Auth.JWTSecret) < 32 {
+		invalid("auth.jwt_secret", "must be at least 32 bytes")
+	}
+	if config.Auth.JWKSFile != "" {
+		if info, err := os.Stat(config.Auth.JWKSFile); err != nil || info.IsDir() {
+			invalid("auth.jwks_file", "%q is not a file", config.Auth.JWKSFile)
+		}
+	}
+
+	if config.Tenancy.Enabled && config.Tenancy.Dir == "" {
+		invalid("tenancy.dir", "is required")
+	}
+
+	if config.RateLimit.Enabled {
+		switch config.RateLimit.Store {
+		case "memory", "sqlite":
+		default:
+			invalid("rate_limit.store", "unknown store %q, expected memory or sqlite", config.RateLimit.Store)
+		}
+		if config.RateLimit.CallerRate <= 0 || config.RateLimit.CallerBurst < 1 {
+			invalid("rate_limit.caller_rate", "caller_rate and caller_burst must be positive")
+		}
+		if config.RateLimit.IPRate <= 0 || config.RateLimit.IPBurst < 1 {
+			invalid("rate_limit.ip_rate", "ip_rate and ip_burst must be positive")
+		}
+		if _, err := config.RateLimit.RouteLimits(); err != nil {
+			invalid("rate_limit.routes", "%v", err)
+		}
+	}
+	if config.RateLimit.DailyQuota < 0 {
+		invalid("rate_limit.daily_quota", "must not be negative")
+	}
+
+	if config.Cache.Enabled {
+		if config.Cache.TTL <= 0 {
+			invalid("cache.ttl", "must be positive")
+		}
+		if config.Cache.MaxEntries < 1 {
+			invalid("cache.max_entries", "must be positive")
+		}
+	}
+
+	if _, err := config.API.DeprecationSchedule(); err != nil {
+		invalid("api.deprecations", "%v", err)
+	}
+
+	if config.GRPC.Enabled {
+		if _, _, err := net.SplitHostPort(config.GRPC.ListenAddr); err != nil {
+			invalid("grpc.listen_addr", "invalid address %q, expected host:port", config.GRPC.ListenAddr)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// configCommand runs "config print".
+func configCommand(args []string) error {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "Usage: cleango config print [flags]")
+		return errors.New("config: expected print")
+	}
+
+	fs, flags := newFlagSet("config print")
+	format := fs.String("format", "yaml", "output format: yaml or toml")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	cfg, err := flags.Load()
+	if err != nil {
+		return err
+	}
+	return cfg.Print(os.Stdout, *format)
+}
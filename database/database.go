@@ -1,28 +1,40 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 
+	"github.com/XSAM/otelsql"
 	migrate "github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	_ "github.com/mattn/go-sqlite3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// InitializeDB opens the database and applies pending migrations. Queries
+// made within a trace, such as while serving a request, get a span each;
+// untraced work like the background workers' polling does not start traces.
 func InitializeDB(dbFile string, migrationDir string) (*sql.DB, error) {
-	db, err := sql.Open("sqlite3", dbFile)
+	db, err := otelsql.Open("sqlite3", dbFile,
+		otelsql.WithAttributes(attribute.String("db.system", "sqlite")),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			DisableErrSkip:       true,
+			OmitConnResetSession: true,
+			OmitRows:             true,
+			OmitConnectorConnect: true,
+			SpanFilter: func(ctx context.Context, method otelsql.Method, query string, args []driver.NamedValue) bool {
+				return trace.SpanContextFromContext(ctx).IsValid()
+			},
+		}),
+	)
 	if err != nil {
 		return nil, err
 	}
 
-	if migrationDir == "" {
-		migrationDir = "file://database/migrations"
-	}
-
-	m, err := migrate.New(
-		migrationDir,
-		"sqlite3://"+dbFile,
-	)
+	m, err := NewMigrate(dbFile, migrationDir)
 	if err != nil {
 		return nil, err
 	}
@@ -33,3 +45,16 @@ func InitializeDB(dbFile string, migrationDir string) (*sql.DB, error) {
 
 	return db, nil
 }
+
+// NewMigrate returns a migrate instance for the database file, reading
+// migrations from migrationDir (a source URL such as "file://database/migrations").
+func NewMigrate(dbFile string, migrationDir string) (*migrate.Migrate, error) {
+	if migrationDir == "" {
+		migrationDir = "file://database/migrations"
+	}
+
+	return migrate.New(
+		migrationDir,
+		"sqlite3://"+dbFile,
+	)
+}
@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS roles (
+    name TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role TEXT NOT NULL,
+    permission TEXT NOT NULL,
+    PRIMARY KEY (role, permission),
+    FOREIGN KEY (role) REFERENCES roles (name)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id TEXT NOT NULL,
+    role TEXT NOT NULL,
+    PRIMARY KEY (user_id, role),
+    FOREIGN KEY (user_id) REFERENCES users (id),
+    FOREIGN KEY (role) REFERENCES roles (name)
+);
+
+INSERT INTO roles (name, description) VALUES
+    ('admin', 'Full access, including deleting orders and administration'),
+    ('support', 'Read the catalog and customers, and manage orders'),
+    ('catalog', 'Manage products without changing their prices'),
+    ('pricing', 'Manage products, including their prices');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', '*'),
+    ('support', 'products:read'),
+    ('support', 'customers:read'),
+    ('support', 'orders:read'),
+    ('support', 'orders:write'),
+    ('catalog', 'products:read'),
+    ('catalog', 'products:write'),
+    ('pricing', 'products:read'),
+    ('pricing', 'products:write'),
+    ('pricing', 'products:price');
@@ -0,0 +1,7 @@
+-- Tenants are registered in the main database; each has its own database file.
+CREATE TABLE IF NOT EXISTS tenants (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    host TEXT UNIQUE,
+    created_at TEXT NOT NULL
+);
@@ -0,0 +1,3 @@
+ALTER TABLE api_keys DROP COLUMN daily_quota;
+DROP TABLE IF EXISTS api_key_usage;
+DROP TABLE IF EXISTS rate_limit_buckets;
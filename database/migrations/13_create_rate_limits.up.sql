@@ -0,0 +1,19 @@
+-- Token buckets of the SQLite rate limit store; updated_at is in Unix milliseconds.
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+    key TEXT PRIMARY KEY,
+    tokens REAL NOT NULL,
+    updated_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_updated ON rate_limit_buckets (updated_at);
+
+-- Requests per API key and UTC day.
+CREATE TABLE IF NOT EXISTS api_key_usage (
+    key_id TEXT NOT NULL,
+    day TEXT NOT NULL,
+    requests INTEGER NOT NULL,
+    PRIMARY KEY (key_id, day),
+    FOREIGN KEY (key_id) REFERENCES api_keys (id) ON DELETE CASCADE
+);
+
+ALTER TABLE api_keys ADD COLUMN daily_quota INTEGER;
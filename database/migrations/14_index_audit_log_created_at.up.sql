@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_audit_log_resource_created_at ON audit_log (resource, created_at);
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS outbox (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_type TEXT NOT NULL,
+    resource TEXT NOT NULL,
+    resource_id TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    occurred_at TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TEXT NOT NULL,
+    published_at TEXT,
+    dead_at TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox (published_at, next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS outbox_deliveries (
+    event_id INTEGER NOT NULL,
+    sink TEXT NOT NULL,
+    delivered_at TEXT NOT NULL,
+    PRIMARY KEY (event_id, sink),
+    FOREIGN KEY (event_id) REFERENCES outbox (id) ON DELETE CASCADE
+);
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"regexp"
+	"strconv"
+
+	migrate "github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+)
+
+var upMigrationFile = regexp.MustCompile(`^(\d+)_.*\.up\.sql$`)
+
+// LatestVersion returns the highest migration version found in dir, a plain
+// directory path.
+func LatestVersion(dir string) (uint, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var latest uint
+	for _, entry := range entries {
+		match := upMigrationFile.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		latest = max(latest, uint(version))
+	}
+	return latest, nil
+}
+
+// Version returns the schema version recorded by migrate and whether the last
+// migration failed part way. It returns migrate.ErrNilVersion when no
+// migration has been applied.
+func Version(db *sql.DB) (uint, bool, error) {
+	var version int64
+	var dirty bool
+	err := db.QueryRow("SELECT version, dirty FROM "+sqlite3.DefaultMigrationsTable+" LIMIT 1").Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && version < 0) {
+		return 0, false, migrate.ErrNilVersion
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return uint(version), dirty, nil
+}
@@ -0,0 +1,34 @@
+package dto
+
+import "api/model"
+
+// CustomerRequest is the body of customer writes.
+type CustomerRequest struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// CustomerResponse is a customer as served by the API.
+type CustomerResponse struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Customer returns the customer the request describes.
+func (request CustomerRequest) Customer() model.Customer {
+	return model.Customer{
+		ID:   request.ID,
+		Name: request.Name,
+	}
+}
+
+func NewCustomerResponse(customer model.Customer) CustomerResponse {
+	return CustomerResponse{
+		ID:   customer.ID,
+		Name: customer.Name,
+	}
+}
+
+func NewCustomerResponses(customers []model.Customer) []CustomerResponse {
+	return mapAll(customers, NewCustomerResponse)
+}
@@ -0,0 +1,13 @@
+// Package dto holds the request and response bodies of the API. Requests
+// are mapped to domain models and models to responses explicitly, so that
+// clients cannot set fields the API does not accept and new model fields
+// are not served until a response type includes them.
+package dto
+
+func mapAll[M any, R any](models []M, convert func(M) R) []R {
+	responses := make([]R, len(models))
+	for i, model := range models {
+		responses[i] = convert(model)
+	}
+	return responses
+}
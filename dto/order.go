@@ -0,0 +1,99 @@
+package dto
+
+import "api/model"
+
+// OrderRequest is the body of v1 order writes. The customer and products
+// are referenced by ID only.
+type OrderRequest struct {
+	ID         string             `json:"id"`
+	OrderDate  string             `json:"order_date"`
+	CustomerID string             `json:"customer_id"`
+	OrderItems []OrderItemRequest `json:"order_items"`
+}
+
+type OrderItemRequest struct {
+	ID        string  `json:"id"`
+	ProductID string  `json:"product_id"`
+	Quantity  int     `json:"quantity"`
+	Price     float64 `json:"price"`
+}
+
+// OrderResponse is an order as served by v1, with its customer and the
+// product of each item when they were loaded.
+type OrderResponse struct {
+	ID         string              `json:"id"`
+	OrderDate  string              `json:"order_date"`
+	CustomerID string              `json:"customer_id"`
+	Customer   *CustomerResponse   `json:"customer,omitempty"`
+	OrderItems []OrderItemResponse `json:"order_items"`
+}
+
+type OrderItemResponse struct {
+	ID        string           `json:"id"`
+	OrderID   string           `json:"order_id"`
+	ProductID string           `json:"product_id"`
+	Product   *ProductResponse `json:"product,omitempty"`
+	Quantity  int              `json:"quantity"`
+	Price     float64          `json:"price"`
+}
+
+// Order returns the order the request describes.
+func (request OrderRequest) Order() model.Order {
+	order := model.Order{
+		ID:         request.ID,
+		OrderDate:  request.OrderDate,
+		CustomerID: request.CustomerID,
+		OrderItems: make([]model.OrderItem, len(request.OrderItems)),
+	}
+	for i, item := range request.OrderItems {
+		order.OrderItems[i] = model.OrderItem{
+			ID:        item.ID,
+			OrderID:   request.ID,
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+			Price:     item.Price,
+		}
+	}
+	return order
+}
+
+func NewOrderResponse(order model.Order) OrderResponse {
+	response := OrderResponse{
+		ID:         order.ID,
+		OrderDate:  order.OrderDate,
+		CustomerID: order.CustomerID,
+		Customer:   embedCustomer(order.Customer),
+		OrderItems: make([]OrderItemResponse, len(order.OrderItems)),
+	}
+	for i, item := range order.OrderItems {
+		response.OrderItems[i] = OrderItemResponse{
+			ID:        item.ID,
+			OrderID:   item.OrderID,
+			ProductID: item.ProductID,
+			Product:   embedProduct(item.Product),
+			Quantity:  item.Quantity,
+			Price:     item.Price,
+		}
+	}
+	return response
+}
+
+// embedCustomer returns the response for a customer loaded with an order, or
+// nil when it was not loaded.
+func embedCustomer(customer model.Customer) *CustomerResponse {
+	if customer.ID == "" {
+		return nil
+	}
+	response := NewCustomerResponse(customer)
+	return &response
+}
+
+// embedProduct returns the response for a product loaded with an order
+// item, or nil when it was not loaded.
+func embedProduct(product model.Product) *ProductResponse {
+	if product.ID == "" {
+		return nil
+	}
+	response := NewProductResponse(product)
+	return &response
+}
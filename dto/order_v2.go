@@ -0,0 +1,80 @@
+package dto
+
+import "api/model"
+
+// OrderV2Response is an order as served by /v2. The customer is only
+// embedded, items are named items and carry their subtotal, and the order
+// its total. The customer and products are left out when not loaded.
+type OrderV2Response struct {
+	ID        string                `json:"id"`
+	OrderDate string                `json:"order_date"`
+	Customer  *CustomerResponse     `json:"customer,omitempty"`
+	Items     []OrderItemV2Response `json:"items"`
+	Total     float64               `json:"total"`
+}
+
+type OrderItemV2Response struct {
+	ID        string           `json:"id"`
+	ProductID string           `json:"product_id"`
+	Product   *ProductResponse `json:"product,omitempty"`
+	Quantity  int              `json:"quantity"`
+	UnitPrice float64          `json:"unit_price"`
+	Subtotal  float64          `json:"subtotal"`
+}
+
+// OrderV2Request is the body of /v2 order writes.
+type OrderV2Request struct {
+	ID         string               `json:"id"`
+	OrderDate  string               `json:"order_date"`
+	CustomerID string               `json:"customer_id"`
+	Items      []OrderItemV2Request `json:"items"`
+}
+
+type OrderItemV2Request struct {
+	ID        string  `json:"id"`
+	ProductID string  `json:"product_id"`
+	Quantity  int     `json:"quantity"`
+	UnitPrice float64 `json:"unit_price"`
+}
+
+func NewOrderV2Response(order model.Order) OrderV2Response {
+	response := OrderV2Response{
+		ID:        order.ID,
+		OrderDate: order.OrderDate,
+		Customer:  embedCustomer(order.Customer),
+		Items:     make([]OrderItemV2Response, len(order.OrderItems)),
+	}
+	for i, item := range order.OrderItems {
+		subtotal := float64(item.Quantity) * item.Price
+		response.Items[i] = OrderItemV2Response{
+			ID:        item.ID,
+			ProductID: item.ProductID,
+			Product:   embedProduct(item.Product),
+			Quantity:  item.Quantity,
+			UnitPrice: item.Price,
+			Subtotal:  subtotal,
+		}
+		response.Total += subtotal
+	}
+	return response
+}
+
+// Order returns the order the request describes.
+func (request OrderV2Request) Order() model.Order {
+	order := model.Order{
+		ID:         request.ID,
+		OrderDate:  request.OrderDate,
+		CustomerID: request.CustomerID,
+		OrderItems: make([]model.OrderItem, len(request.Items)),
+	}
+	for i, item := range request.Items {
+		order.OrderItems[i] = model.OrderItem{
+			ID:        item.ID,
+			OrderID:   request.ID,
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+			Price:     item.UnitPrice,
+		}
+	}
+	return order
+}
@@ -0,0 +1,38 @@
+package dto
+
+import "api/model"
+
+// ProductRequest is the body of product writes.
+type ProductRequest struct {
+	ID    string  `json:"id"`
+	Name  string  `json:"name"`
+	Price float64 `json:"price"`
+}
+
+// ProductResponse is a product as served by the API.
+type ProductResponse struct {
+	ID    string  `json:"id"`
+	Name  string  `json:"name"`
+	Price float64 `json:"price"`
+}
+
+// Product returns the product the request describes.
+func (request ProductRequest) Product() model.Product {
+	return model.Product{
+		ID:    request.ID,
+		Name:  request.Name,
+		Price: request.Price,
+	}
+}
+
+func NewProductResponse(product model.Product) ProductResponse {
+	return ProductResponse{
+		ID:    product.ID,
+		Name:  product.Name,
+		Price: product.Price,
+	}
+}
+
+func NewProductResponses(products []model.Product) []ProductResponse {
+	return mapAll(products, NewProductResponse)
+}
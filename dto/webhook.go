@@ -0,0 +1,93 @@
+package dto
+
+import "api/model"
+
+// WebhookRequest is the body of webhook subscription writes.
+type WebhookRequest struct {
+	ID         string   `json:"id"`
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	// Secret signs deliveries; on update an empty secret keeps the current one.
+	Secret string `json:"secret"`
+	// Active defaults to true on create and to the current value on update.
+	Active *bool `json:"active"`
+}
+
+// WebhookResponse is a webhook subscription as served by the API, without
+// its secret.
+type WebhookResponse struct {
+	ID                  string   `json:"id"`
+	URL                 string   `json:"url"`
+	EventTypes          []string `json:"event_types"`
+	Active              bool     `json:"active"`
+	ConsecutiveFailures int      `json:"consecutive_failures"`
+	DisabledAt          *string  `json:"disabled_at"`
+	CreatedAt           string   `json:"created_at"`
+}
+
+type WebhookDeliveryResponse struct {
+	ID             int64   `json:"id"`
+	SubscriptionID string  `json:"subscription_id"`
+	EventID        int64   `json:"event_id"`
+	EventType      string  `json:"event_type"`
+	Status         string  `json:"status" enums:"pending,succeeded,failed"`
+	Attempts       int     `json:"attempts"`
+	ResponseStatus *int    `json:"response_status"`
+	LastError      *string `json:"last_error"`
+	ReplayOf       *int64  `json:"replay_of"`
+	NextAttemptAt  string  `json:"next_attempt_at"`
+	CreatedAt      string  `json:"created_at"`
+	DeliveredAt    *string `json:"delivered_at"`
+}
+
+// WebhookSubscription returns the subscription the request describes.
+func (request WebhookRequest) WebhookSubscription() model.WebhookSubscription {
+	subscription := model.WebhookSubscription{
+		ID:         request.ID,
+		URL:        request.URL,
+		EventTypes: request.EventTypes,
+		Secret:     request.Secret,
+		Active:     true,
+	}
+	if request.Active != nil {
+		subscription.Active = *request.Active
+	}
+	return subscription
+}
+
+func NewWebhookResponse(subscription model.WebhookSubscription) WebhookResponse {
+	return WebhookResponse{
+		ID:                  subscription.ID,
+		URL:                 subscription.URL,
+		EventTypes:          subscription.EventTypes,
+		Active:              subscription.Active,
+		ConsecutiveFailures: subscription.ConsecutiveFailures,
+		DisabledAt:          subscription.DisabledAt,
+		CreatedAt:           subscription.CreatedAt,
+	}
+}
+
+func NewWebhookResponses(subscriptions []model.WebhookSubscription) []WebhookResponse {
+	return mapAll(subscriptions, NewWebhookResponse)
+}
+
+func NewWebhookDeliveryResponse(delivery model.WebhookDelivery) WebhookDeliveryResponse {
+	return WebhookDeliveryResponse{
+		ID:             delivery.ID,
+		SubscriptionID: delivery.SubscriptionID,
+		EventID:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		ResponseStatus: delivery.ResponseStatus,
+		LastError:      delivery.LastError,
+		ReplayOf:       delivery.ReplayOf,
+		NextAttemptAt:  delivery.NextAttemptAt,
+		CreatedAt:      delivery.CreatedAt,
+		DeliveredAt:    delivery.DeliveredAt,
+	}
+}
+
+func NewWebhookDeliveryResponses(deliveries []model.WebhookDelivery) []WebhookDeliveryResponse {
+	return mapAll(deliveries, NewWebhookDeliveryResponse)
+}
@@ -0,0 +1,77 @@
+package events
+
+import (
+	"api/model"
+	"context"
+	"errors"
+	"sync"
+)
+
+// Handler reacts to a domain event published on a Bus.
+type Handler func(ctx context.Context, event model.Event) error
+
+type subscription struct {
+	eventTypes map[string]bool
+	handler    Handler
+}
+
+// Bus is an in-process Sink that fans events out to subscribed handlers.
+type Bus struct {
+	mu            sync.RWMutex
+	nextID        int
+	subscriptions map[int]subscription
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		subscriptions: map[int]subscription{},
+	}
+}
+
+func (bus *Bus) Name() string {
+	return "bus"
+}
+
+// Subscribe registers handler for the given event types, or for every event
+// when none are given. The returned function removes the subscription.
+func (bus *Bus) Subscribe(handler Handler, eventTypes ...string) func() {
+	sub := subscription{handler: handler}
+	if len(eventTypes) > 0 {
+		sub.eventTypes = map[string]bool{}
+		for _, eventType := range eventTypes {
+			sub.eventTypes[eventType] = true
+		}
+	}
+
+	bus.mu.Lock()
+	id := bus.nextID
+	bus.nextID++
+	bus.subscriptions[id] = sub
+	bus.mu.Unlock()
+
+	return func() {
+		bus.mu.Lock()
+		delete(bus.subscriptions, id)
+		bus.mu.Unlock()
+	}
+}
+
+// Publish calls every matching handler and returns their errors joined.
+func (bus *Bus) Publish(ctx context.Context, event model.Event) error {
+	bus.mu.RLock()
+	var handlers []Handler
+	for _, sub := range bus.subscriptions {
+		if sub.eventTypes == nil || sub.eventTypes[event.Type] {
+			handlers = append(handlers, sub.handler)
+		}
+	}
+	bus.mu.RUnlock()
+
+	var errs []error
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
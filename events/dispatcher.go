@@ -0,0 +1,140 @@
+package events
+
+import (
+	"api/health"
+	"api/model"
+	"api/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// Dispatcher polls the outbox and publishes pending events to every sink.
+// Deliveries are recorded per sink and an event is marked published once all
+// sinks accepted it; otherwise it is retried with exponential backoff for the
+// sinks that failed only, so delivery is at least once. Events still failing
+// after MaxAttempts are dead-lettered.
+type Dispatcher struct {
+	outboxRepository *repository.OutboxRepository
+	sinks            []Sink
+	heartbeat        health.Heartbeat
+
+	PollInterval time.Duration
+	BatchSize    int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	MaxAttempts  int
+	// StallAfter is how long Check tolerates no completed batch.
+	StallAfter time.Duration
+}
+
+func NewDispatcher(outboxRepository *repository.OutboxRepository, sinks ...Sink) *Dispatcher {
+	return &Dispatcher{
+		outboxRepository: outboxRepository,
+		sinks:            sinks,
+		PollInterval:     time.Second,
+		BatchSize:        100,
+		BaseBackoff:      time.Second,
+		MaxBackoff:       5 * time.Minute,
+		MaxAttempts:      20,
+		StallAfter:       5 * time.Minute,
+	}
+}
+
+// Run dispatches pending events every PollInterval until ctx is done.
+func (dispatcher *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(dispatcher.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		_, err := dispatcher.DispatchPending(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "outbox dispatcher failed", "error", err)
+		}
+		dispatcher.heartbeat.Beat(err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check reports whether Run is making progress, for the readiness probe.
+func (dispatcher *Dispatcher) Check(ctx context.Context) error {
+	return dispatcher.heartbeat.Check(dispatcher.StallAfter)
+}
+
+// DispatchPending publishes one batch of due events and returns how many were published.
+func (dispatcher *Dispatcher) DispatchPending(ctx context.Context) (int, error) {
+	pending, err := dispatcher.outboxRepository.GetPendingEvents(ctx, time.Now(), dispatcher.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	// The outcome of a publish is recorded even when ctx is cancelled meanwhile
+	record := context.WithoutCancel(ctx)
+	published := 0
+	for _, event := range pending {
+		if ctx.Err() != nil {
+			return published, ctx.Err()
+		}
+
+		delivered, err := dispatcher.outboxRepository.GetDeliveredSinks(ctx, event.ID)
+		if err != nil {
+			return published, err
+		}
+		if err := dispatcher.publish(ctx, record, event, delivered); err != nil {
+			if event.Attempts+1 >= dispatcher.MaxAttempts {
+				slog.ErrorContext(ctx, "outbox event dead-lettered", "event_id", event.ID, "type", event.Type, "attempts", event.Attempts+1, "error", err)
+				if err := dispatcher.outboxRepository.MarkDead(record, event.ID, err, time.Now()); err != nil {
+					return published, err
+				}
+				continue
+			}
+			nextAttemptAt := time.Now().Add(dispatcher.backoff(event.Attempts))
+			if err := dispatcher.outboxRepository.MarkFailed(record, event.ID, err, nextAttemptAt); err != nil {
+				return published, err
+			}
+			continue
+		}
+
+		if err := dispatcher.outboxRepository.MarkPublished(record, event.ID, time.Now()); err != nil {
+			return published, err
+		}
+		published++
+	}
+
+	return published, nil
+}
+
+// publish delivers event to the sinks not in delivered, recording each
+// delivery with record, and returns the errors of the sinks that failed.
+func (dispatcher *Dispatcher) publish(ctx context.Context, record context.Context, event model.Event, delivered []string) error {
+	var errs []error
+	for _, sink := range dispatcher.sinks {
+		if slices.Contains(delivered, sink.Name()) {
+			continue
+		}
+		if err := sink.Publish(ctx, event); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sink.Name(), err))
+			continue
+		}
+		if err := dispatcher.outboxRepository.MarkDelivered(record, event.ID, sink.Name(), time.Now()); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sink.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (dispatcher *Dispatcher) backoff(attempts int) time.Duration {
+	delay := dispatcher.BaseBackoff
+	for i := 0; i < attempts && delay < dispatcher.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, dispatcher.MaxBackoff)
+}
@@ -0,0 +1,78 @@
+package events
+
+import (
+	"api/model"
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// NATSSink publishes events to a NATS-compatible server using the plain text
+// protocol, on the subject "<prefix>.<event type>". It opens a connection per
+// publish and waits for the server's PONG, so a nil error means the server
+// accepted the message.
+type NATSSink struct {
+	addr          string
+	subjectPrefix string
+	timeout       time.Duration
+}
+
+func NewNATSSink(addr string, subjectPrefix string) *NATSSink {
+	return &NATSSink{
+		addr:          addr,
+		subjectPrefix: subjectPrefix,
+		timeout:       5 * time.Second,
+	}
+}
+
+func (sink *NATSSink) Name() string {
+	return "nats"
+}
+
+func (sink *NATSSink) Publish(ctx context.Context, event model.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	dialer := net.Dialer{Timeout: sink.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", sink.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(sink.timeout))
+
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO") {
+		return fmt.Errorf("nats: unexpected greeting %q", strings.TrimSpace(line))
+	}
+
+	subject := sink.subjectPrefix + "." + event.Type
+	_, err = fmt.Fprintf(conn, "CONNECT {\"verbose\":false,\"pedantic\":false}\r\nPUB %s %d\r\n%s\r\nPING\r\n", subject, len(body), body)
+	if err != nil {
+		return err
+	}
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("nats: %s", line)
+		}
+	}
+}
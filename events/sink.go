@@ -0,0 +1,13 @@
+package events
+
+import (
+	"api/model"
+	"context"
+)
+
+// Sink publishes domain events to a destination. The dispatcher delivers at
+// least once, so a sink may see the same event ID more than once.
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, event model.Event) error
+}
@@ -0,0 +1,58 @@
+package graph
+
+import (
+	"api/auth"
+	"context"
+	"log/slog"
+)
+
+// Error codes sent in the extensions of errors, matching the status codes
+// of the REST API.
+const (
+	CodeUnauthenticated = "UNAUTHENTICATED"
+	CodeForbidden       = "FORBIDDEN"
+	CodeNotFound        = "NOT_FOUND"
+	CodeBadUserInput    = "BAD_USER_INPUT"
+	CodeConflict        = "CONFLICT"
+	CodeInternal        = "INTERNAL"
+)
+
+// Error is an error of a field, sent with its code.
+type Error struct {
+	Message string
+	Code    string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Extensions() map[string]any {
+	return map[string]any{"code": e.Code}
+}
+
+// require returns an error unless the caller holds permission.
+func require(ctx context.Context, permission string) error {
+	if auth.HasPermission(ctx, permission) {
+		return nil
+	}
+	if _, ok := auth.PrincipalFromContext(ctx); !ok {
+		return &Error{Message: "Authentication required", Code: CodeUnauthenticated}
+	}
+	return &Error{Message: "Missing permission " + permission, Code: CodeForbidden}
+}
+
+// internalError logs err and returns an error with message only, keeping
+// the underlying error out of the response.
+func internalError(ctx context.Context, message string, err error) error {
+	slog.ErrorContext(ctx, message, "error", err)
+	return &Error{Message: message, Code: CodeInternal}
+}
+
+func notFound(message string) error {
+	return &Error{Message: message, Code: CodeNotFound}
+}
+
+func badUserInput(message string) error {
+	return &Error{Message: message, Code: CodeBadUserInput}
+}
@@ -0,0 +1,114 @@
+// Package graph serves products, customers and orders over GraphQL, with
+// the permissions and customer scoping of the REST API. Relations are
+// loaded in batches per request, see loader.
+package graph
+
+import (
+	"api/model"
+	"api/repository"
+	"context"
+	_ "embed"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+// maxDepth bounds the nesting of queries, such as orders of the customer of
+// an order.
+const maxDepth = 8
+
+// Schema executes GraphQL requests.
+type Schema struct {
+	schema   *graphql.Schema
+	resolver *Resolver
+}
+
+func NewSchema(productRepository *repository.ProductRepository, customerRepository *repository.CustomerRepository, orderRepository *repository.OrderRepository) *Schema {
+	resolver := &Resolver{
+		productRepository:  productRepository,
+		customerRepository: customerRepository,
+		orderRepository:    orderRepository,
+	}
+	return &Schema{
+		schema:   graphql.MustParseSchema(schemaSDL, resolver, graphql.MaxDepth(maxDepth)),
+		resolver: resolver,
+	}
+}
+
+// Exec runs the operation of a request with loaders of its own.
+func (s *Schema) Exec(ctx context.Context, query string, operationName string, variables map[string]any) *graphql.Response {
+	ctx = context.WithValue(ctx, loadersContextKey{}, s.resolver.newLoaders())
+	return s.schema.Exec(ctx, query, operationName, variables)
+}
+
+type loadersContextKey struct{}
+
+// loaders batch the relations of a request. Loaders returning objects prime
+// the relations of all of them, since the resolvers of each run
+// concurrently and would otherwise load them in several batches.
+type loaders struct {
+	customers *loader[string, model.Customer]
+	products  *loader[string, model.Product]
+	// orderItems are keyed by order ID
+	orderItems *loader[string, []model.OrderItem]
+	// orders are keyed by customer ID, most recent first
+	orders *loader[string, []model.Order]
+}
+
+func loadersFromContext(ctx context.Context) *loaders {
+	return ctx.Value(loadersContextKey{}).(*loaders)
+}
+
+func (r *Resolver) newLoaders() *loaders {
+	l := &loaders{}
+	l.customers = newLoader(func(ctx context.Context, ids []string) (map[string]model.Customer, error) {
+		customers, err := r.customerRepository.GetCustomersByIDs(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		byID := make(map[string]model.Customer, len(customers))
+		for _, customer := range customers {
+			byID[customer.ID] = customer
+		}
+		return byID, nil
+	})
+	l.products = newLoader(func(ctx context.Context, ids []string) (map[string]model.Product, error) {
+		products, err := r.productRepository.GetProductsByIDs(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		byID := make(map[string]model.Product, len(products))
+		for _, product := range products {
+			byID[product.ID] = product
+		}
+		return byID, nil
+	})
+	l.orderItems = newLoader(func(ctx context.Context, orderIDs []string) (map[string][]model.OrderItem, error) {
+		orderItems, err := r.orderRepository.GetOrderItemsByOrders(ctx, orderIDs)
+		if err != nil {
+			return nil, err
+		}
+		byOrder := map[string][]model.OrderItem{}
+		for _, orderItem := range orderItems {
+			byOrder[orderItem.OrderID] = append(byOrder[orderItem.OrderID], orderItem)
+			l.products.prime(orderItem.ProductID)
+		}
+		return byOrder, nil
+	})
+	l.orders = newLoader(func(ctx context.Context, customerIDs []string) (map[string][]model.Order, error) {
+		orders, err := r.orderRepository.GetOrdersByCustomers(ctx, customerIDs, repository.OrderIncludes{})
+		if err != nil {
+			return nil, err
+		}
+		byCustomer := map[string][]model.Order{}
+		for _, order := range orders {
+			byCustomer[order.CustomerID] = append(byCustomer[order.CustomerID], order)
+			l.customers.prime(order.CustomerID)
+			l.orderItems.prime(order.ID)
+		}
+		return byCustomer, nil
+	})
+	return l
+}
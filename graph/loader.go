@@ -0,0 +1,73 @@
+package graph
+
+import (
+	"context"
+	"sync"
+)
+
+// loader batches the loads of a request by key. Keys announced with prime
+// are fetched together with the first key loaded after them, so resolving a
+// field on every element of a list costs one query instead of one per
+// element. Results are kept for the rest of the request.
+type loader[K comparable, V any] struct {
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	primed  map[K]struct{}
+	batches map[K]*batch[K, V]
+}
+
+type batch[K comparable, V any] struct {
+	done   chan struct{}
+	values map[K]V
+	err    error
+}
+
+// newLoader returns a loader fetching the values of keys with fetch, which
+// leaves out the keys that have no value.
+func newLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{
+		fetch:   fetch,
+		primed:  map[K]struct{}{},
+		batches: map[K]*batch[K, V]{},
+	}
+}
+
+// prime announces keys that are about to be loaded.
+func (l *loader[K, V]) prime(keys ...K) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, key := range keys {
+		if _, ok := l.batches[key]; !ok {
+			l.primed[key] = struct{}{}
+		}
+	}
+}
+
+// load returns the value of key, the zero value when there is none, waiting
+// for the batch fetching it.
+func (l *loader[K, V]) load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	b, ok := l.batches[key]
+	if ok {
+		l.mu.Unlock()
+		<-b.done
+		return b.values[key], b.err
+	}
+
+	b = &batch[K, V]{done: make(chan struct{})}
+	keys := []K{key}
+	l.batches[key] = b
+	for primed := range l.primed {
+		if _, ok := l.batches[primed]; !ok {
+			keys = append(keys, primed)
+			l.batches[primed] = b
+		}
+	}
+	clear(l.primed)
+	l.mu.Unlock()
+
+	defer close(b.done)
+	b.values, b.err = l.fetch(ctx, keys)
+	return b.values[key], b.err
+}
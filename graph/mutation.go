@@ -0,0 +1,272 @@
+package graph
+
+import (
+	"api/auth"
+	"api/model"
+	"api/repository"
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+type productInput struct {
+	Name  string
+	Price float64
+}
+
+type customerInput struct {
+	Name string
+}
+
+type orderInput struct {
+	CustomerID *graphql.ID
+	OrderDate  string
+	OrderItems []orderItemInput
+}
+
+type orderItemInput struct {
+	ID        graphql.ID
+	ProductID graphql.ID
+	Quantity  int32
+	Price     float64
+}
+
+func (input orderInput) order(id string) model.Order {
+	order := model.Order{
+		ID:         id,
+		OrderDate:  input.OrderDate,
+		CustomerID: string(value(input.CustomerID)),
+		OrderItems: make([]model.OrderItem, len(input.OrderItems)),
+	}
+	for i, item := range input.OrderItems {
+		order.OrderItems[i] = model.OrderItem{
+			ID:        string(item.ID),
+			OrderID:   id,
+			ProductID: string(item.ProductID),
+			Quantity:  int(item.Quantity),
+			Price:     item.Price,
+		}
+	}
+	return order
+}
+
+func (r *Resolver) CreateProduct(ctx context.Context, args struct {
+	ID    graphql.ID
+	Input productInput
+}) (*productResolver, error) {
+	if err := require(ctx, auth.PermissionProductsWrite); err != nil {
+		return nil, err
+	}
+	if !auth.HasPermission(ctx, auth.PermissionProductsPrice) {
+		return nil, priceForbidden()
+	}
+	product := model.Product{ID: string(args.ID), Name: args.Input.Name, Price: args.Input.Price}
+	if err := r.productRepository.CreateProduct(ctx, product); err != nil {
+		return nil, writeError(ctx, "Failed to create product", err)
+	}
+	return newProductResolver(ctx, product), nil
+}
+
+func (r *Resolver) UpdateProduct(ctx context.Context, args struct {
+	ID    graphql.ID
+	Input productInput
+}) (*productResolver, error) {
+	if err := require(ctx, auth.PermissionProductsWrite); err != nil {
+		return nil, err
+	}
+	current, err := r.productRepository.GetProductByID(ctx, string(args.ID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound("Product not found")
+	}
+	if err != nil {
+		return nil, internalError(ctx, "Failed to update product", err)
+	}
+	product := model.Product{ID: current.ID, Name: args.Input.Name, Price: args.Input.Price}
+	// Without products:price updates must keep the current price
+	update := r.productRepository.UpdateProduct
+	if !auth.HasPermission(ctx, auth.PermissionProductsPrice) {
+		update = r.productRepository.UpdateProductKeepingPrice
+	}
+	err = update(ctx, product)
+	if errors.Is(err, repository.ErrPriceChange) {
+		return nil, priceForbidden()
+	}
+	if err != nil {
+		return nil, writeError(ctx, "Failed to update product", err)
+	}
+	return newProductResolver(ctx, product), nil
+}
+
+func (r *Resolver) DeleteProduct(ctx context.Context, args struct{ ID graphql.ID }) (bool, error) {
+	if err := require(ctx, auth.PermissionProductsWrite); err != nil {
+		return false, err
+	}
+	_, err := r.productRepository.GetProductByID(ctx, string(args.ID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, notFound("Product not found")
+	}
+	if err == nil {
+		err = r.productRepository.DeleteProduct(ctx, string(args.ID))
+	}
+	if err != nil {
+		return false, writeError(ctx, "Failed to delete product", err)
+	}
+	return true, nil
+}
+
+func (r *Resolver) CreateCustomer(ctx context.Context, args struct {
+	ID    graphql.ID
+	Input customerInput
+}) (*customerResolver, error) {
+	if err := require(ctx, auth.PermissionCustomersWrite); err != nil {
+		return nil, err
+	}
+	customer := model.Customer{ID: string(args.ID), Name: args.Input.Name}
+	if err := r.customerRepository.CreateCustomer(ctx, customer); err != nil {
+		return nil, writeError(ctx, "Failed to create customer", err)
+	}
+	return newCustomerResolver(ctx, customer), nil
+}
+
+func (r *Resolver) UpdateCustomer(ctx context.Context, args struct {
+	ID    graphql.ID
+	Input customerInput
+}) (*customerResolver, error) {
+	if err := require(ctx, auth.PermissionCustomersWrite); err != nil {
+		return nil, err
+	}
+	_, err := r.customerRepository.GetCustomerByID(ctx, string(args.ID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound("Customer not found")
+	}
+	customer := model.Customer{ID: string(args.ID), Name: args.Input.Name}
+	if err == nil {
+		err = r.customerRepository.UpdateCustomer(ctx, customer)
+	}
+	if err != nil {
+		return nil, writeError(ctx, "Failed to update customer", err)
+	}
+	return newCustomerResolver(ctx, customer), nil
+}
+
+func (r *Resolver) DeleteCustomer(ctx context.Context, args struct{ ID graphql.ID }) (bool, error) {
+	if err := require(ctx, auth.PermissionCustomersWrite); err != nil {
+		return false, err
+	}
+	_, err := r.customerRepository.GetCustomerByID(ctx, string(args.ID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, notFound("Customer not found")
+	}
+	if err == nil {
+		err = r.customerRepository.DeleteCustomer(ctx, string(args.ID))
+	}
+	if err != nil {
+		return false, writeError(ctx, "Failed to delete customer", err)
+	}
+	return true, nil
+}
+
+func (r *Resolver) CreateOrder(ctx context.Context, args struct {
+	ID    graphql.ID
+	Input orderInput
+}) (*orderResolver, error) {
+	if err := require(ctx, auth.PermissionOwnOrdersWrite); err != nil {
+		return nil, err
+	}
+	order := args.Input.order(string(args.ID))
+	if customerID, scoped := auth.OwnCustomer(ctx, auth.PermissionOrdersWrite); scoped {
+		if order.CustomerID != "" && order.CustomerID != customerID {
+			return nil, &Error{Message: "Orders can only be placed for your own customer", Code: CodeForbidden}
+		}
+		order.CustomerID = customerID
+		if err := r.catalogPrices(ctx, &order, "Failed to create order"); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.orderRepository.CreateOrder(ctx, order); err != nil {
+		return nil, writeError(ctx, "Failed to create order", err)
+	}
+	return newOrderResolver(ctx, order), nil
+}
+
+func (r *Resolver) UpdateOrder(ctx context.Context, args struct {
+	ID    graphql.ID
+	Input orderInput
+}) (*orderResolver, error) {
+	if err := require(ctx, auth.PermissionOwnOrdersWrite); err != nil {
+		return nil, err
+	}
+	order := args.Input.order(string(args.ID))
+	current, err := r.orderRepository.GetOrderByID(ctx, order.ID, repository.OrderIncludes{})
+	customerID, scoped := auth.OwnCustomer(ctx, auth.PermissionOrdersWrite)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && scoped && current.CustomerID != customerID) {
+		return nil, notFound("Order not found")
+	}
+	if err != nil {
+		return nil, internalError(ctx, "Failed to update order", err)
+	}
+	if scoped && order.CustomerID != "" && order.CustomerID != customerID {
+		return nil, &Error{Message: "customerId cannot be changed", Code: CodeForbidden}
+	}
+	if order.CustomerID == "" {
+		order.CustomerID = current.CustomerID
+	}
+	if scoped {
+		if err := r.catalogPrices(ctx, &order, "Failed to update order"); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.orderRepository.UpdateOrder(ctx, order); err != nil {
+		return nil, writeError(ctx, "Failed to update order", err)
+	}
+	return newOrderResolver(ctx, order), nil
+}
+
+// catalogPrices sets the item prices of order to the current product prices
+// for callers that may only write their own orders.
+func (r *Resolver) catalogPrices(ctx context.Context, order *model.Order, message string) error {
+	priced, err := r.orderRepository.WithCatalogPrices(ctx, *order)
+	if errors.Is(err, repository.ErrUnknownProduct) {
+		return badUserInput(err.Error())
+	}
+	if err != nil {
+		return internalError(ctx, message, err)
+	}
+	*order = priced
+	return nil
+}
+
+func (r *Resolver) DeleteOrder(ctx context.Context, args struct{ ID graphql.ID }) (bool, error) {
+	if err := require(ctx, auth.PermissionOrdersDelete); err != nil {
+		return false, err
+	}
+	_, err := r.orderRepository.GetOrderByID(ctx, string(args.ID), repository.OrderIncludes{})
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, notFound("Order not found")
+	}
+	if err == nil {
+		err = r.orderRepository.DeleteOrder(ctx, string(args.ID))
+	}
+	if err != nil {
+		return false, writeError(ctx, "Failed to delete order", err)
+	}
+	return true, nil
+}
+
+func priceForbidden() error {
+	return &Error{
+		Message: "Setting or changing a price requires the " + auth.PermissionProductsPrice + " permission",
+		Code:    CodeForbidden,
+	}
+}
+
+// writeError reports constraint violations, such as duplicate IDs or
+// references to missing records, as conflicts and other errors as internal.
+func writeError(ctx context.Context, message string, err error) error {
+	if repository.IsConflict(err) {
+		return &Error{Message: message + ": conflicts with existing data", Code: CodeConflict}
+	}
+	return internalError(ctx, message, err)
+}
@@ -0,0 +1,234 @@
+package graph
+
+import (
+	"api/auth"
+	"api/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+// maxPageSize bounds the first argument of lists, which defaults to 20.
+const maxPageSize = 100
+
+// Resolver resolves the fields of Query and Mutation.
+type Resolver struct {
+	productRepository  *repository.ProductRepository
+	customerRepository *repository.CustomerRepository
+	orderRepository    *repository.OrderRepository
+}
+
+type productFilter struct {
+	NameContains *string
+	MinPrice     *float64
+	MaxPrice     *float64
+}
+
+type customerFilter struct {
+	NameContains *string
+}
+
+type orderFilter struct {
+	CustomerID *graphql.ID
+	From       *string
+	To         *string
+}
+
+func (r *Resolver) Products(ctx context.Context, args struct {
+	First  int32
+	After  *graphql.ID
+	Filter *productFilter
+}) (*connection[*productResolver], error) {
+	if err := require(ctx, auth.PermissionProductsRead); err != nil {
+		return nil, err
+	}
+	page, err := newPage(args.First, args.After)
+	if err != nil {
+		return nil, err
+	}
+	var filter repository.ProductFilter
+	if args.Filter != nil {
+		filter = repository.ProductFilter{
+			NameContains: value(args.Filter.NameContains),
+			MinPrice:     args.Filter.MinPrice,
+			MaxPrice:     args.Filter.MaxPrice,
+		}
+	}
+	products, err := r.productRepository.ListProducts(ctx, filter, page)
+	if err != nil {
+		return nil, internalError(ctx, "Failed to retrieve products", err)
+	}
+	return newConnection(ctx, products, page, newProductResolver), nil
+}
+
+func (r *Resolver) Product(ctx context.Context, args struct{ ID graphql.ID }) (*productResolver, error) {
+	if err := require(ctx, auth.PermissionProductsRead); err != nil {
+		return nil, err
+	}
+	product, err := r.productRepository.GetProductByID(ctx, string(args.ID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, internalError(ctx, "Failed to retrieve product", err)
+	}
+	return newProductResolver(ctx, product), nil
+}
+
+func (r *Resolver) Customers(ctx context.Context, args struct {
+	First  int32
+	After  *graphql.ID
+	Filter *customerFilter
+}) (*connection[*customerResolver], error) {
+	if err := require(ctx, auth.PermissionCustomersRead); err != nil {
+		return nil, err
+	}
+	page, err := newPage(args.First, args.After)
+	if err != nil {
+		return nil, err
+	}
+	var filter repository.CustomerFilter
+	if args.Filter != nil {
+		filter.NameContains = value(args.Filter.NameContains)
+	}
+	customers, err := r.customerRepository.ListCustomers(ctx, filter, page)
+	if err != nil {
+		return nil, internalError(ctx, "Failed to retrieve customers", err)
+	}
+	return newConnection(ctx, customers, page, newCustomerResolver), nil
+}
+
+func (r *Resolver) Customer(ctx context.Context, args struct{ ID graphql.ID }) (*customerResolver, error) {
+	if err := require(ctx, auth.PermissionCustomersRead); err != nil {
+		return nil, err
+	}
+	customer, err := r.customerRepository.GetCustomerByID(ctx, string(args.ID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, internalError(ctx, "Failed to retrieve customer", err)
+	}
+	return newCustomerResolver(ctx, customer), nil
+}
+
+func (r *Resolver) Orders(ctx context.Context, args struct {
+	First  int32
+	After  *graphql.ID
+	Filter *orderFilter
+}) (*connection[*orderResolver], error) {
+	if err := require(ctx, auth.PermissionOwnOrdersRead); err != nil {
+		return nil, err
+	}
+	page, err := newPage(args.First, args.After)
+	if err != nil {
+		return nil, err
+	}
+	var filter repository.OrderFilter
+	if args.Filter != nil {
+		filter = repository.OrderFilter{
+			CustomerID: string(value(args.Filter.CustomerID)),
+			From:       value(args.Filter.From),
+			To:         value(args.Filter.To),
+		}
+	}
+	if customerID, scoped := auth.OwnCustomer(ctx, auth.PermissionOrdersRead); scoped {
+		// Other customers' orders do not exist for customers
+		if filter.CustomerID != "" && filter.CustomerID != customerID {
+			return &connection[*orderResolver]{nodes: []*orderResolver{}}, nil
+		}
+		filter.CustomerID = customerID
+	}
+	orders, err := r.orderRepository.ListOrders(ctx, filter, page, repository.OrderIncludes{})
+	if err != nil {
+		return nil, internalError(ctx, "Failed to retrieve orders", err)
+	}
+	return newConnection(ctx, orders, page, newOrderResolver), nil
+}
+
+func (r *Resolver) Order(ctx context.Context, args struct{ ID graphql.ID }) (*orderResolver, error) {
+	if err := require(ctx, auth.PermissionOwnOrdersRead); err != nil {
+		return nil, err
+	}
+	order, err := r.orderRepository.GetOrderByID(ctx, string(args.ID), repository.OrderIncludes{})
+	if customerID, scoped := auth.OwnCustomer(ctx, auth.PermissionOrdersRead); scoped && err == nil && order.CustomerID != customerID {
+		return nil, nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, internalError(ctx, "Failed to retrieve order", err)
+	}
+	return newOrderResolver(ctx, order), nil
+}
+
+// newPage returns the page of first rows after the row with ID after,
+// asking for one more row to tell whether there is a next page.
+func newPage(first int32, after *graphql.ID) (repository.Page, error) {
+	if first < 1 || first > maxPageSize {
+		return repository.Page{}, badUserInput(fmt.Sprintf("first must be between 1 and %d", maxPageSize))
+	}
+	return repository.Page{After: string(value(after)), Limit: int(first) + 1}, nil
+}
+
+type connection[R any] struct {
+	nodes    []R
+	pageInfo pageInfo
+}
+
+func (c *connection[R]) Nodes() []R {
+	return c.nodes
+}
+
+func (c *connection[R]) PageInfo() *pageInfo {
+	return &c.pageInfo
+}
+
+type pageInfo struct {
+	hasNextPage bool
+	endCursor   *graphql.ID
+}
+
+func (p *pageInfo) HasNextPage() bool {
+	return p.hasNextPage
+}
+
+func (p *pageInfo) EndCursor() *graphql.ID {
+	return p.endCursor
+}
+
+// identified is implemented by the resolvers of paginated types.
+type identified interface {
+	ID() graphql.ID
+}
+
+// newConnection resolves the rows read for page, which holds one row more
+// than asked for when there is a next page.
+func newConnection[M any, R identified](ctx context.Context, rows []M, page repository.Page, resolve func(context.Context, M) R) *connection[R] {
+	c := &connection[R]{nodes: []R{}}
+	if len(rows) == page.Limit {
+		rows = rows[:len(rows)-1]
+		c.pageInfo.hasNextPage = true
+	}
+	for _, row := range rows {
+		c.nodes = append(c.nodes, resolve(ctx, row))
+	}
+	if len(c.nodes) > 0 {
+		id := c.nodes[len(c.nodes)-1].ID()
+		c.pageInfo.endCursor = &id
+	}
+	return c
+}
+
+// value returns what p points to, or the zero value when it is nil.
+func value[T any](p *T) T {
+	var zero T
+	if p == nil {
+		return zero
+	}
+	return *p
+}
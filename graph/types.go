@@ -0,0 +1,156 @@
+package graph
+
+import (
+	"api/auth"
+	"api/model"
+	"context"
+	"errors"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+// Resolvers of the object types. Constructors prime the loaders with the
+// relations of the object, so that the relations of every object of a list
+// are loaded in a single batch.
+
+type productResolver struct {
+	product model.Product
+}
+
+func newProductResolver(ctx context.Context, product model.Product) *productResolver {
+	return &productResolver{product: product}
+}
+
+func (r *productResolver) ID() graphql.ID {
+	return graphql.ID(r.product.ID)
+}
+
+func (r *productResolver) Name() string {
+	return r.product.Name
+}
+
+func (r *productResolver) Price() float64 {
+	return r.product.Price
+}
+
+type customerResolver struct {
+	customer model.Customer
+}
+
+func newCustomerResolver(ctx context.Context, customer model.Customer) *customerResolver {
+	loadersFromContext(ctx).orders.prime(customer.ID)
+	return &customerResolver{customer: customer}
+}
+
+func (r *customerResolver) ID() graphql.ID {
+	return graphql.ID(r.customer.ID)
+}
+
+func (r *customerResolver) Name() string {
+	return r.customer.Name
+}
+
+func (r *customerResolver) Orders(ctx context.Context, args struct{ First *int32 }) ([]*orderResolver, error) {
+	if err := require(ctx, auth.PermissionOwnOrdersRead); err != nil {
+		return nil, err
+	}
+	if args.First != nil && *args.First < 0 {
+		return nil, badUserInput("first must not be negative")
+	}
+	if customerID, scoped := auth.OwnCustomer(ctx, auth.PermissionOrdersRead); scoped && customerID != r.customer.ID {
+		return []*orderResolver{}, nil
+	}
+	orders, err := loadersFromContext(ctx).orders.load(ctx, r.customer.ID)
+	if err != nil {
+		return nil, internalError(ctx, "Failed to retrieve orders", err)
+	}
+	if args.First != nil && int(*args.First) < len(orders) {
+		orders = orders[:*args.First]
+	}
+	resolvers := make([]*orderResolver, len(orders))
+	for i, order := range orders {
+		resolvers[i] = newOrderResolver(ctx, order)
+	}
+	return resolvers, nil
+}
+
+type orderResolver struct {
+	order model.Order
+}
+
+func newOrderResolver(ctx context.Context, order model.Order) *orderResolver {
+	l := loadersFromContext(ctx)
+	l.customers.prime(order.CustomerID)
+	l.orderItems.prime(order.ID)
+	return &orderResolver{order: order}
+}
+
+func (r *orderResolver) ID() graphql.ID {
+	return graphql.ID(r.order.ID)
+}
+
+func (r *orderResolver) OrderDate() string {
+	return r.order.OrderDate
+}
+
+func (r *orderResolver) CustomerID() graphql.ID {
+	return graphql.ID(r.order.CustomerID)
+}
+
+func (r *orderResolver) Customer(ctx context.Context) (*customerResolver, error) {
+	customer, err := loadersFromContext(ctx).customers.load(ctx, r.order.CustomerID)
+	if err == nil && customer.ID == "" {
+		err = errors.New("customer " + r.order.CustomerID + " does not exist")
+	}
+	if err != nil {
+		return nil, internalError(ctx, "Failed to retrieve customer", err)
+	}
+	return newCustomerResolver(ctx, customer), nil
+}
+
+func (r *orderResolver) OrderItems(ctx context.Context) ([]*orderItemResolver, error) {
+	orderItems, err := loadersFromContext(ctx).orderItems.load(ctx, r.order.ID)
+	if err != nil {
+		return nil, internalError(ctx, "Failed to retrieve order items", err)
+	}
+	resolvers := make([]*orderItemResolver, len(orderItems))
+	for i, orderItem := range orderItems {
+		resolvers[i] = &orderItemResolver{orderItem: orderItem}
+	}
+	return resolvers, nil
+}
+
+type orderItemResolver struct {
+	orderItem model.OrderItem
+}
+
+func (r *orderItemResolver) ID() graphql.ID {
+	return graphql.ID(r.orderItem.ID)
+}
+
+func (r *orderItemResolver) OrderID() graphql.ID {
+	return graphql.ID(r.orderItem.OrderID)
+}
+
+func (r *orderItemResolver) ProductID() graphql.ID {
+	return graphql.ID(r.orderItem.ProductID)
+}
+
+func (r *orderItemResolver) Product(ctx context.Context) (*productResolver, error) {
+	product, err := loadersFromContext(ctx).products.load(ctx, r.orderItem.ProductID)
+	if err == nil && product.ID == "" {
+		err = errors.New("product " + r.orderItem.ProductID + " does not exist")
+	}
+	if err != nil {
+		return nil, internalError(ctx, "Failed to retrieve product", err)
+	}
+	return newProductResolver(ctx, product), nil
+}
+
+func (r *orderItemResolver) Quantity() int32 {
+	return int32(r.orderItem.Quantity)
+}
+
+func (r *orderItemResolver) Price() float64 {
+	return r.orderItem.Price
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"api/logging"
+	"api/model"
+	"api/tenant"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AdminHandler struct {
+	logLevel *slog.LevelVar
+}
+
+func NewAdminHandler(logLevel *slog.LevelVar) *AdminHandler {
+	return &AdminHandler{
+		logLevel: logLevel,
+	}
+}
+
+// GetLogLevel godoc
+// @Summary Get log level
+// @Description Get the minimum level of the records written to the log
+// @Tags admin
+// @Produce  json
+// @Success 200 {object} model.LogLevel
+// @Router /admin/log-level [get]
+func (handler *AdminHandler) GetLogLevel(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(model.LogLevel{
+		Level: logging.LevelName(handler.logLevel.Level()),
+	})
+}
+
+// SetLogLevel godoc
+// @Summary Set log level
+// @Description Change the minimum level of the records written to the log until the next restart. Only available outside of a tenant, as the log is shared by all tenants.
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Param level body model.LogLevel true "debug, info, warn or error"
+// @Success 200 {object} model.LogLevel
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /admin/log-level [put]
+func (handler *AdminHandler) SetLogLevel(c *fiber.Ctx) error {
+	if tenant.FromContext(c.UserContext()) != "" {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "The log level can only be changed outside of a tenant",
+		})
+	}
+	var request model.LogLevel
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid log level data",
+		})
+	}
+	level, err := logging.ParseLevel(request.Level)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Unknown log level, expected debug, info, warn or error",
+		})
+	}
+
+	previous := handler.logLevel.Level()
+	handler.logLevel.Set(level)
+	slog.InfoContext(c.UserContext(), "log level changed", "from", logging.LevelName(previous), "to", logging.LevelName(level))
+	return c.Status(fiber.StatusOK).JSON(model.LogLevel{
+		Level: logging.LevelName(level),
+	})
+}
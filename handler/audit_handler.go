@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"api/repository"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AuditHandler struct {
+	auditRepository *repository.AuditRepository
+}
+
+func NewAuditHandler(auditRepository *repository.AuditRepository) *AuditHandler {
+	return &AuditHandler{
+		auditRepository: auditRepository,
+	}
+}
+
+// GetAuditEntries godoc
+// @Summary List audit log entries
+// @Description Get audit log entries, optionally filtered by resource and resource ID
+// @Tags audit
+// @Accept  json
+// @Produce  json
+// @Param resource query string false "Resource type (products, customers, orders)"
+// @Param id query string false "Resource ID"
+// @Success 200 {array} model.AuditEntry
+// @Failure 500 {object} map[string]string
+// @Router /audit [get]
+func (handler *AuditHandler) GetAuditEntries(c *fiber.Ctx) error {
+	entries, err := handler.auditRepository.GetAuditEntries(c.UserContext(), c.Query("resource"), c.Query("id"))
+	if err != nil {
+		return internalError(c, "Failed to retrieve audit log", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(entries)
+}
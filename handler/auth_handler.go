@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"api/auth"
+	"api/model"
+	"api/repository"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	maxAPIKeyNameLength = 100
+	// apiKeyUsageDays is how many days of usage GetAPIKeyUsage returns.
+	apiKeyUsageDays = 30
+)
+
+type AuthHandler struct {
+	apiKeyRepository *repository.APIKeyRepository
+}
+
+func NewAuthHandler(apiKeyRepository *repository.APIKeyRepository) *AuthHandler {
+	return &AuthHandler{
+		apiKeyRepository: apiKeyRepository,
+	}
+}
+
+// GetAPIKeys godoc
+// @Summary List API keys
+// @Description List the caller's API keys, including revoked and expired ones. Secrets are never returned.
+// @Tags auth
+// @Produce  json
+// @Success 200 {array} model.APIKey
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/keys [get]
+func (handler *AuthHandler) GetAPIKeys(c *fiber.Ctx) error {
+	principal, ok := auth.PrincipalFromContext(c.UserContext())
+	if !ok {
+		return authenticationRequired(c)
+	}
+	if principal.Type == model.PrincipalTypeCustomer {
+		return apiKeysForbidden(c)
+	}
+	keys, err := handler.apiKeyRepository.GetAPIKeys(c.UserContext(), principal.UserID)
+	if err != nil {
+		return internalError(c, "Failed to retrieve API keys", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(keys)
+}
+
+// CreateAPIKey godoc
+// @Summary Create API key
+// @Description Create an API key for the caller. The key is only returned in this response; send it as "Authorization: Bearer <key>" or "X-API-Key: <key>". daily_quota overrides the server's default quota of requests per UTC day, 0 meaning unlimited.
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param key body model.APIKeyRequest true "Key to create"
+// @Success 201 {object} model.CreatedAPIKey
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/keys [post]
+func (handler *AuthHandler) CreateAPIKey(c *fiber.Ctx) error {
+	principal, ok := auth.PrincipalFromContext(c.UserContext())
+	if !ok {
+		return authenticationRequired(c)
+	}
+	if principal.Type == model.PrincipalTypeCustomer {
+		return apiKeysForbidden(c)
+	}
+	var request model.APIKeyRequest
+	if err := c.BodyParser(&request); err != nil || request.Name == "" || len(request.Name) > maxAPIKeyNameLength {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid API key data",
+		})
+	}
+	var expiresAt *time.Time
+	if request.ExpiresIn != "" {
+		expiresIn, err := time.ParseDuration(request.ExpiresIn)
+		if err != nil || expiresIn <= 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "expires_in must be a positive duration such as 720h",
+			})
+		}
+		expiry := time.Now().Add(expiresIn)
+		expiresAt = &expiry
+	}
+	if request.DailyQuota != nil && *request.DailyQuota < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "daily_quota must not be negative",
+		})
+	}
+
+	key, secret, hash := auth.NewAPIKey(principal.UserID, request.Name, expiresAt)
+	key.DailyQuota = request.DailyQuota
+	key, err := handler.apiKeyRepository.CreateAPIKey(c.UserContext(), key, hash)
+	if err != nil {
+		return internalError(c, "Failed to create API key", err)
+	}
+	// The secret must not be cached, nor stored for Idempotency-Key replays
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Status(fiber.StatusCreated).JSON(model.CreatedAPIKey{
+		APIKey: key,
+		Key:    secret,
+	})
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke API key
+// @Description Revoke one of the caller's API keys. Requests using it are rejected from then on.
+// @Tags auth
+// @Produce  json
+// @Param id path string true "API key ID"
+// @Success 204
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/keys/{id} [delete]
+func (handler *AuthHandler) RevokeAPIKey(c *fiber.Ctx) error {
+	principal, ok := auth.PrincipalFromContext(c.UserContext())
+	if !ok {
+		return authenticationRequired(c)
+	}
+	if principal.Type == model.PrincipalTypeCustomer {
+		return apiKeysForbidden(c)
+	}
+	err := handler.apiKeyRepository.RevokeAPIKey(c.UserContext(), principal.UserID, c.Params("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "API key not found",
+		})
+	}
+	if err != nil {
+		return internalError(c, "Failed to revoke API key", err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetAPIKeyUsage godoc
+// @Summary Get API key usage
+// @Description Get the number of requests made with one of the caller's API keys per UTC day, over the last 30 days, most recent first. Days without requests are omitted.
+// @Tags auth
+// @Produce  json
+// @Param id path string true "API key ID"
+// @Success 200 {array} model.APIKeyUsage
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /auth/keys/{id}/usage [get]
+func (handler *AuthHandler) GetAPIKeyUsage(c *fiber.Ctx) error {
+	principal, ok := auth.PrincipalFromContext(c.UserContext())
+	if !ok {
+		return authenticationRequired(c)
+	}
+	if principal.Type == model.PrincipalTypeCustomer {
+		return apiKeysForbidden(c)
+	}
+	since := time.Now().UTC().AddDate(0, 0, 1-apiKeyUsageDays).Format(time.DateOnly)
+	usage, err := handler.apiKeyRepository.GetAPIKeyUsage(c.UserContext(), principal.UserID, c.Params("id"), since)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "API key not found",
+		})
+	}
+	if err != nil {
+		return internalError(c, "Failed to retrieve API key usage", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(usage)
+}
+
+// apiKeysForbidden rejects customers: API keys belong to users.
+func apiKeysForbidden(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+		"error": "API keys are only available to users",
+	})
+}
+
+func authenticationRequired(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderWWWAuthenticate, "Bearer")
+	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+		"error": "Authentication required",
+	})
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"api/model"
+	"api/repository"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxBatchOperations bounds a single batch request.
+const maxBatchOperations = 50000
+
+// decodeBatchRequest parses and validates a batch body, decoding the data of
+// each operation with decode. It returns a client error message when the
+// request is invalid.
+func decodeBatchRequest[T any](c *fiber.Ctx, request *model.BatchRequest[T], decode func([]byte) (T, error), id func(T) string) string {
+	var raw model.BatchRequest[json.RawMessage]
+	if err := parseRequest(c, &raw); err != nil {
+		return "Invalid batch data"
+	}
+	request.Mode = raw.Mode
+	request.Operations = make([]model.BatchOperation[T], len(raw.Operations))
+	for i, operation := range raw.Operations {
+		request.Operations[i].Op = operation.Op
+		if len(operation.Data) == 0 {
+			continue
+		}
+		data, err := decode(operation.Data)
+		if err != nil {
+			return fmt.Sprintf("Invalid data at index %d: %v", i, err)
+		}
+		request.Operations[i].Data = data
+	}
+	return validateBatchRequest(request, id)
+}
+
+func validateBatchRequest[T any](request *model.BatchRequest[T], id func(T) string) string {
+	if request.Mode == "" {
+		request.Mode = model.BatchModeAllOrNothing
+	}
+	if request.Mode != model.BatchModeAllOrNothing && request.Mode != model.BatchModeBestEffort {
+		return fmt.Sprintf("Invalid batch mode %q", request.Mode)
+	}
+	if len(request.Operations) == 0 {
+		return "Batch has no operations"
+	}
+	if len(request.Operations) > maxBatchOperations {
+		return fmt.Sprintf("Batch exceeds %d operations", maxBatchOperations)
+	}
+	for i, operation := range request.Operations {
+		switch operation.Op {
+		case model.BatchOpCreate, model.BatchOpUpdate, model.BatchOpDelete:
+		default:
+			return fmt.Sprintf("Invalid op %q at index %d", operation.Op, i)
+		}
+		if id(operation.Data) == "" {
+			return fmt.Sprintf("Missing id at index %d", i)
+		}
+	}
+	return ""
+}
+
+// newBatchResponse builds the per-item results. The status is 200 when every
+// operation was applied and 207 otherwise.
+func newBatchResponse[T any](c *fiber.Ctx, request model.BatchRequest[T], errs []error, id func(T) string) (int, model.BatchResponse) {
+	response := model.BatchResponse{
+		Mode:    request.Mode,
+		Results: make([]model.BatchResult, len(request.Operations)),
+	}
+	for i, operation := range request.Operations {
+		result := model.BatchResult{
+			Index: i,
+			Op:    operation.Op,
+			ID:    id(operation.Data),
+		}
+		result.Status, result.Error = batchItemStatus(c, i, operation.Op, errs[i])
+		if errs[i] == nil {
+			response.Succeeded++
+		} else {
+			response.Failed++
+		}
+		response.Results[i] = result
+	}
+
+	if response.Failed > 0 {
+		return fiber.StatusMultiStatus, response
+	}
+	return fiber.StatusOK, response
+}
+
+// batchItemStatus returns the status and error message of the operation at
+// index. Like internalError, it logs unexpected errors and keeps them, as
+// well as constraint details, out of the response.
+func batchItemStatus(c *fiber.Ctx, index int, op string, err error) (int, string) {
+	switch {
+	case err == nil && op == model.BatchOpCreate:
+		return fiber.StatusCreated, ""
+	case err == nil:
+		return fiber.StatusOK, ""
+	case errors.Is(err, repository.ErrBatchRolledBack):
+		return fiber.StatusFailedDependency, "Not applied: batch was rolled back"
+	case errors.Is(err, sql.ErrNoRows):
+		return fiber.StatusNotFound, "Not found"
+	case errors.Is(err, repository.ErrPriceChange):
+		return fiber.StatusForbidden, priceForbiddenMessage
+	case repository.IsConflict(err):
+		slog.InfoContext(c.UserContext(), "Batch operation conflicts with existing data", "method", c.Method(), "path", c.Path(), "op", op, "index", index, "error", err)
+		return fiber.StatusConflict, "Conflicts with existing data"
+	default:
+		slog.ErrorContext(c.UserContext(), "Batch operation failed", "method", c.Method(), "path", c.Path(), "op", op, "index", index, "error", err)
+		return fiber.StatusInternalServerError, "Internal error"
+	}
+}
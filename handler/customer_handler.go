@@ -1,8 +1,12 @@
 package handler
 
 import (
+	"api/dto"
 	"api/model"
 	"api/repository"
+	"api/transfer"
+	"context"
+	"io"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -19,42 +23,52 @@ func NewCustomerHandler(customerRepository *repository.CustomerRepository) *Cust
 
 // GetCustomers godoc
 // @Summary List customers
-// @Description Get all customers
+// @Description Get all customers. Responses carry an ETag and Last-Modified for conditional requests.
 // @Tags customers
 // @Accept  json
 // @Produce  json
-// @Success 200 {array} model.Customer
+// @Param fields query string false "Comma-separated fields to send, e.g. id,name"
+// @Success 200 {array} dto.CustomerResponse
+// @Success 304
+// @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /customers [get]
 func (handler *CustomerHandler) GetCustomers(c *fiber.Ctx) error {
-	customers, err := handler.customerRepository.GetCustomers()
+	p, message := parseFields(c, dto.CustomerResponse{})
+	if message != "" {
+		return invalidQuery(c, message)
+	}
+	customers, modified, err := handler.customerRepository.GetCustomersCached(c.UserContext())
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to retrieve customers",
-		})
+		return internalError(c, "Failed to retrieve customers", err)
 	}
-	return c.Status(fiber.StatusOK).JSON(customers)
+	return sendCached(c, modified, p.apply(dto.NewCustomerResponses(customers)))
 }
 
 // GetCustomerByID godoc
 // @Summary Get customer by ID
-// @Description Get a customer by its ID
+// @Description Get a customer by its ID. Responses carry an ETag and Last-Modified for conditional requests.
 // @Tags customers
 // @Accept  json
 // @Produce  json
 // @Param id path string true "Customer ID"
-// @Success 200 {object} model.Customer
+// @Param fields query string false "Comma-separated fields to send, e.g. id,name"
+// @Success 200 {object} dto.CustomerResponse
+// @Success 304
+// @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /customers/{id} [get]
 func (handler *CustomerHandler) GetCustomerByID(c *fiber.Ctx) error {
+	p, message := parseFields(c, dto.CustomerResponse{})
+	if message != "" {
+		return invalidQuery(c, message)
+	}
 	customerID := c.Params("id")
-	customer, err := handler.customerRepository.GetCustomerByID(customerID)
+	customer, modified, err := handler.customerRepository.GetCustomerByIDCached(c.UserContext(), customerID)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to retrieve customer",
-		})
+		return internalError(c, "Failed to retrieve customer", err)
 	}
-	return c.Status(fiber.StatusOK).JSON(customer)
+	return sendCached(c, modified, p.apply(dto.NewCustomerResponse(customer)))
 }
 
 // CreateCustomer godoc
@@ -63,22 +77,20 @@ func (handler *CustomerHandler) GetCustomerByID(c *fiber.Ctx) error {
 // @Tags customers
 // @Accept  json
 // @Produce  json
-// @Param customer body model.Customer true "Customer to create"
+// @Param customer body dto.CustomerRequest true "Customer to create"
 // @Success 201
 // @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /customers [post]
 func (handler *CustomerHandler) CreateCustomer(c *fiber.Ctx) error {
-	var customer model.Customer
-	if err := c.BodyParser(&customer); err != nil {
+	customer, err := decodeBody(c, decodeCustomer)
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Invalid customer data",
 		})
 	}
-	if err := handler.customerRepository.CreateCustomer(customer); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create customer",
-		})
+	if err := handler.customerRepository.CreateCustomer(c.UserContext(), customer); err != nil {
+		return internalError(c, "Failed to create customer", err)
 	}
 	return c.SendStatus(fiber.StatusCreated)
 }
@@ -90,24 +102,22 @@ func (handler *CustomerHandler) CreateCustomer(c *fiber.Ctx) error {
 // @Accept  json
 // @Produce  json
 // @Param id path string true "Customer ID"
-// @Param customer body model.Customer true "Customer to update"
+// @Param customer body dto.CustomerRequest true "Customer to update"
 // @Success 200
 // @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /customers/{id} [put]
 func (handler *CustomerHandler) UpdateCustomer(c *fiber.Ctx) error {
 	customerID := c.Params("id")
-	var customer model.Customer
-	if err := c.BodyParser(&customer); err != nil {
+	customer, err := decodeBody(c, decodeCustomer)
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Invalid customer data",
 		})
 	}
 	customer.ID = customerID
-	if err := handler.customerRepository.UpdateCustomer(customer); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update customer",
-		})
+	if err := handler.customerRepository.UpdateCustomer(c.UserContext(), customer); err != nil {
+		return internalError(c, "Failed to update customer", err)
 	}
 	return c.SendStatus(fiber.StatusOK)
 }
@@ -124,10 +134,81 @@ func (handler *CustomerHandler) UpdateCustomer(c *fiber.Ctx) error {
 // @Router /customers/{id} [delete]
 func (handler *CustomerHandler) DeleteCustomer(c *fiber.Ctx) error {
 	customerID := c.Params("id")
-	if err := handler.customerRepository.DeleteCustomer(customerID); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to delete customer",
-		})
+	if err := handler.customerRepository.DeleteCustomer(c.UserContext(), customerID); err != nil {
+		return internalError(c, "Failed to delete customer", err)
 	}
 	return c.SendStatus(fiber.StatusOK)
 }
+
+// BatchCustomers godoc
+// @Summary Batch create, update and delete customers
+// @Description Apply many customer operations in a single transaction. In all_or_nothing mode (the default) the first failure rolls back the batch; in best_effort mode each operation succeeds or fails on its own.
+// @Tags customers
+// @Accept  json
+// @Produce  json
+// @Param batch body model.BatchRequest[dto.CustomerRequest] true "Operations to apply"
+// @Success 200 {object} model.BatchResponse
+// @Success 207 {object} model.BatchResponse
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /customers:batch [post]
+func (handler *CustomerHandler) BatchCustomers(c *fiber.Ctx) error {
+	var request model.BatchRequest[model.Customer]
+	if message := decodeBatchRequest(c, &request, decodeCustomer, customerID); message != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": message,
+		})
+	}
+	errs, err := handler.customerRepository.ApplyCustomerBatch(c.UserContext(), request.Operations, repository.BatchOptions{
+		BestEffort: request.Mode == model.BatchModeBestEffort,
+	})
+	if err != nil {
+		return internalError(c, "Failed to apply customer batch", err)
+	}
+	status, response := newBatchResponse(c, request, errs, customerID)
+	return c.Status(status).JSON(response)
+}
+
+var decodeCustomer = requestDecoder(dto.CustomerRequest.Customer)
+
+func customerID(customer model.Customer) string {
+	return customer.ID
+}
+
+// ExportCustomers godoc
+// @Summary Export customers
+// @Description Stream all customers as CSV or NDJSON
+// @Tags customers
+// @Produce  text/csv
+// @Produce  application/x-ndjson
+// @Param format query string false "Export format" Enums(csv, ndjson)
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Router /customers/export [get]
+func (handler *CustomerHandler) ExportCustomers(c *fiber.Ctx) error {
+	return streamExport(c, "customers", func(ctx context.Context, w io.Writer, format transfer.Format) error {
+		return transfer.ExportCustomers(ctx, w, format, handler.customerRepository)
+	})
+}
+
+// ImportCustomers godoc
+// @Summary Import customers
+// @Description Create customers from CSV (with a header line) or NDJSON. Columns: id, name.
+// @Tags customers
+// @Accept  text/csv
+// @Accept  application/x-ndjson
+// @Produce  json
+// @Param format query string false "Import format" Enums(csv, ndjson)
+// @Param map query string false "Header mapping, e.g. Customer ID:id"
+// @Param dry_run query bool false "Validate against the database without writing"
+// @Param atomic query bool false "Import nothing unless every row succeeds"
+// @Success 200 {object} model.ImportReport
+// @Success 207 {object} model.ImportReport
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /customers/import [post]
+func (handler *CustomerHandler) ImportCustomers(c *fiber.Ctx) error {
+	return importResources(c, func(ctx context.Context, r io.Reader, options transfer.ImportOptions) (model.ImportReport, error) {
+		return transfer.ImportCustomers(ctx, r, options, handler.customerRepository)
+	})
+}
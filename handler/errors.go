@@ -0,0 +1,16 @@
+package handler
+
+import (
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// internalError logs err with the request's context and responds 500 with
+// message, keeping the underlying error out of the response.
+func internalError(c *fiber.Ctx, message string, err error) error {
+	slog.ErrorContext(c.UserContext(), message, "method", c.Method(), "path", c.Path(), "error", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"api/repository"
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type EventHandler struct {
+	outboxRepository *repository.OutboxRepository
+	done             chan struct{}
+	closeOnce        sync.Once
+
+	PollInterval      time.Duration
+	HeartbeatInterval time.Duration
+	BatchSize         int
+}
+
+func NewEventHandler(outboxRepository *repository.OutboxRepository) *EventHandler {
+	return &EventHandler{
+		outboxRepository:  outboxRepository,
+		done:              make(chan struct{}),
+		PollInterval:      500 * time.Millisecond,
+		HeartbeatInterval: 15 * time.Second,
+		BatchSize:         100,
+	}
+}
+
+// StreamEvents godoc
+// @Summary Stream change events
+// @Description Server-Sent Events feed of create/update/delete events. Each SSE id is the persisted event sequence; reconnect with Last-Event-ID to resume. Without it the stream starts at the current position.
+// @Tags events
+// @Produce  text/event-stream
+// @Param resource query string false "Comma-separated resource types to include (products, customers, orders)"
+// @Param Last-Event-ID header int false "Resume after this event ID"
+// @Success 200 {string} string "text/event-stream"
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /events [get]
+func (handler *EventHandler) StreamEvents(c *fiber.Ctx) error {
+	var resources []string
+	if resource := c.Query("resource"); resource != "" {
+		resources = strings.Split(resource, ",")
+	}
+
+	var cursor int64
+	if lastEventID := c.Get("Last-Event-ID", c.Query("last_event_id")); lastEventID != "" {
+		id, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || id < 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid Last-Event-ID",
+			})
+		}
+		cursor = id
+	} else {
+		id, err := handler.outboxRepository.GetLatestEventID(c.UserContext())
+		if err != nil {
+			return internalError(c, "Failed to open event stream", err)
+		}
+		cursor = id
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	ctx := c.UserContext()
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		handler.stream(ctx, w, cursor, resources)
+	})
+	return nil
+}
+
+// Close ends every open stream so that the server can shut down; clients
+// reconnect elsewhere with Last-Event-ID. New streams end immediately.
+func (handler *EventHandler) Close() {
+	handler.closeOnce.Do(func() {
+		close(handler.done)
+	})
+}
+
+// stream writes events after cursor until the client goes away or the handler is closed.
+func (handler *EventHandler) stream(ctx context.Context, w *bufio.Writer, cursor int64, resources []string) {
+	poll := time.NewTicker(handler.PollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(handler.HeartbeatInterval)
+	defer heartbeat.Stop()
+
+	// Tell the client how long to wait before reconnecting
+	fmt.Fprintf(w, "retry: %d\n\n", handler.PollInterval.Milliseconds()*4)
+	if w.Flush() != nil {
+		return
+	}
+
+	for {
+		events, err := handler.outboxRepository.GetEventsAfter(ctx, cursor, resources, handler.BatchSize)
+		if err != nil {
+			fmt.Fprintf(w, "event: error\ndata: %s\n\n", strconv.Quote("Failed to retrieve events"))
+			w.Flush()
+			return
+		}
+
+		for _, event := range events {
+			data, err := json.Marshal(event)
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+			cursor = event.ID
+		}
+		if len(events) > 0 {
+			if w.Flush() != nil {
+				return
+			}
+			heartbeat.Reset(handler.HeartbeatInterval)
+		}
+
+		// Drain a full batch straight away
+		if len(events) == handler.BatchSize {
+			continue
+		}
+
+		select {
+		case <-handler.done:
+			return
+		case <-poll.C:
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			if w.Flush() != nil {
+				return
+			}
+		}
+	}
+}
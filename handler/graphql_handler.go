@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"api/dto"
+	"api/graph"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type GraphQLHandler struct {
+	schema *graph.Schema
+}
+
+func NewGraphQLHandler(schema *graph.Schema) *GraphQLHandler {
+	return &GraphQLHandler{
+		schema: schema,
+	}
+}
+
+// Query godoc
+// @Summary Run a GraphQL operation
+// @Description Run a query or mutation over products, customers and orders, see graph/schema.graphql. Fields require the permissions of the matching REST routes; errors carry a code in their extensions.
+// @Tags graphql
+// @Accept  json
+// @Produce  json
+// @Param request body dto.GraphQLRequest true "GraphQL request"
+// @Success 200 {object} map[string]any
+// @Failure 400 {object} map[string]string
+// @Router /graphql [post]
+func (handler *GraphQLHandler) Query(c *fiber.Ctx) error {
+	var request dto.GraphQLRequest
+	if err := parseRequest(c, &request); err != nil || request.Query == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid GraphQL request",
+		})
+	}
+	response := handler.schema.Exec(c.UserContext(), request.Query, request.OperationName, request.Variables)
+	return c.Status(fiber.StatusOK).JSON(response)
+}
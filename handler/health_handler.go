@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"api/health"
+	"api/model"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type HealthHandler struct {
+	registry *health.Registry
+}
+
+func NewHealthHandler(registry *health.Registry) *HealthHandler {
+	return &HealthHandler{
+		registry: registry,
+	}
+}
+
+// Liveness godoc
+// @Summary Liveness probe
+// @Description Report that the process is up and serving requests. Dependencies are not checked.
+// @Tags health
+// @Produce  json
+// @Success 200 {object} model.HealthReport
+// @Router /healthz [get]
+func (handler *HealthHandler) Liveness(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(model.HealthReport{
+		Status: model.HealthStatusOK,
+		Checks: []model.HealthCheck{},
+	})
+}
+
+// Readiness godoc
+// @Summary Readiness probe
+// @Description Run every registered check (database, migrations, background workers) and report each outcome. Responds 503 when any check fails.
+// @Tags health
+// @Produce  json
+// @Success 200 {object} model.HealthReport
+// @Failure 503 {object} model.HealthReport
+// @Router /readyz [get]
+func (handler *HealthHandler) Readiness(c *fiber.Ctx) error {
+	report := handler.registry.Run(c.UserContext())
+	if report.Status != model.HealthStatusOK {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(report)
+	}
+	return c.Status(fiber.StatusOK).JSON(report)
+}
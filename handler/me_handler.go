@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"api/auth"
+	"api/dto"
+	"api/repository"
+	"database/sql"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// MeHandler serves the calling customer's own records.
+type MeHandler struct {
+	customerRepository *repository.CustomerRepository
+	orderRepository    *repository.OrderRepository
+	orders             OrderRepresentation
+}
+
+func NewMeHandler(customerRepository *repository.CustomerRepository, orderRepository *repository.OrderRepository) *MeHandler {
+	return &MeHandler{
+		customerRepository: customerRepository,
+		orderRepository:    orderRepository,
+		orders:             OrdersV1,
+	}
+}
+
+// Version returns a copy of the handler sending orders in the given
+// representation.
+func (handler *MeHandler) Version(orders OrderRepresentation) *MeHandler {
+	versioned := *handler
+	versioned.orders = orders
+	return &versioned
+}
+
+// GetMe godoc
+// @Summary Get own customer
+// @Description Get the customer the caller is authenticated as
+// @Tags me
+// @Produce  json
+// @Param fields query string false "Comma-separated fields to send, e.g. id,name"
+// @Success 200 {object} dto.CustomerResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /me [get]
+func (handler *MeHandler) GetMe(c *fiber.Ctx) error {
+	p, message := parseFields(c, dto.CustomerResponse{})
+	if message != "" {
+		return invalidQuery(c, message)
+	}
+	principal, ok := auth.PrincipalFromContext(c.UserContext())
+	if !ok {
+		return authenticationRequired(c)
+	}
+	if principal.CustomerID == "" {
+		return noCustomer(c)
+	}
+	customer, err := handler.customerRepository.GetCustomerByID(c.UserContext(), principal.CustomerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return noCustomer(c)
+	}
+	if err != nil {
+		return internalError(c, "Failed to retrieve customer", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(p.apply(dto.NewCustomerResponse(customer)))
+}
+
+// GetMyOrders godoc
+// @Summary List own orders
+// @Description Get the orders of the customer the caller is authenticated as
+// @Tags me
+// @Produce  json
+// @Param fields query string false "Comma-separated fields to send, e.g. id,order_date"
+// @Param expand query string false "Comma-separated relations to embed, all by default, e.g. customer,order_items.product"
+// @Success 200 {array} dto.OrderResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /me/orders [get]
+func (handler *MeHandler) GetMyOrders(c *fiber.Ctx) error {
+	query, message := handler.orders.parseOrderQuery(c)
+	if message != "" {
+		return invalidQuery(c, message)
+	}
+	principal, ok := auth.PrincipalFromContext(c.UserContext())
+	if !ok {
+		return authenticationRequired(c)
+	}
+	if principal.CustomerID == "" {
+		return noCustomer(c)
+	}
+	orders, err := handler.orderRepository.GetOrdersByCustomer(c.UserContext(), principal.CustomerID, query.includes)
+	if err != nil {
+		return internalError(c, "Failed to retrieve orders", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(query.projection.apply(handler.orders.encodeAll(orders)))
+}
+
+func noCustomer(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+		"error": "No customer is linked to the caller",
+	})
+}
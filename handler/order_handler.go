@@ -1,85 +1,142 @@
 package handler
 
 import (
+	"api/auth"
 	"api/model"
 	"api/repository"
+	"api/transfer"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type OrderHandler struct {
 	orderRepository *repository.OrderRepository
+	orders          OrderRepresentation
 }
 
 func NewOrderHandler(orderRepository *repository.OrderRepository) *OrderHandler {
 	return &OrderHandler{
 		orderRepository: orderRepository,
+		orders:          OrdersV1,
 	}
 }
 
+// Version returns a copy of the handler sending and receiving orders in the
+// given representation.
+func (handler *OrderHandler) Version(orders OrderRepresentation) *OrderHandler {
+	versioned := *handler
+	versioned.orders = orders
+	return &versioned
+}
+
 // GetOrders godoc
 // @Summary List orders
-// @Description Get all orders
+// @Description Get all orders. Customers only get their own orders. Under /v2 orders are dto.OrderV2Response.
 // @Tags orders
 // @Accept  json
 // @Produce  json
-// @Success 200 {array} model.Order
+// @Param fields query string false "Comma-separated fields to send, e.g. id,order_date"
+// @Param expand query string false "Comma-separated relations to embed, all by default, e.g. customer,order_items.product"
+// @Success 200 {array} dto.OrderResponse
+// @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /orders [get]
 func (handler *OrderHandler) GetOrders(c *fiber.Ctx) error {
-	orders, err := handler.orderRepository.GetOrders()
+	ctx := c.UserContext()
+	query, message := handler.orders.parseOrderQuery(c)
+	if message != "" {
+		return invalidQuery(c, message)
+	}
+	if customerID, scoped := auth.OwnCustomer(ctx, auth.PermissionOrdersRead); scoped {
+		orders, err := handler.orderRepository.GetOrdersByCustomer(ctx, customerID, query.includes)
+		if err != nil {
+			return internalError(c, "Failed to retrieve orders", err)
+		}
+		return c.Status(fiber.StatusOK).JSON(query.projection.apply(handler.orders.encodeAll(orders)))
+	}
+	orders, err := handler.orderRepository.GetOrders(ctx, query.includes)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return internalError(c, "Failed to retrieve orders", err)
 	}
-	return c.Status(fiber.StatusOK).JSON(orders)
+	return c.Status(fiber.StatusOK).JSON(query.projection.apply(handler.orders.encodeAll(orders)))
 }
 
 // GetOrderByID godoc
 // @Summary Get order by ID
-// @Description Get an order by its ID
+// @Description Get an order by its ID. Customers only find their own orders.
 // @Tags orders
 // @Accept  json
 // @Produce  json
 // @Param id path string true "Order ID"
-// @Success 200 {object} model.Order
+// @Param fields query string false "Comma-separated fields to send, e.g. id,order_date"
+// @Param expand query string false "Comma-separated relations to embed, all by default, e.g. customer,order_items.product"
+// @Success 200 {object} dto.OrderResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /orders/{id} [get]
 func (handler *OrderHandler) GetOrderByID(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	query, message := handler.orders.parseOrderQuery(c)
+	if message != "" {
+		return invalidQuery(c, message)
+	}
 	orderID := c.Params("id")
-	order, err := handler.orderRepository.GetOrderByID(orderID)
+	order, err := handler.orderRepository.GetOrderByID(ctx, orderID, query.includes)
+	customerID, scoped := auth.OwnCustomer(ctx, auth.PermissionOrdersRead)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && scoped && order.CustomerID != customerID) {
+		return orderNotFound(c)
+	}
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return internalError(c, "Failed to retrieve order", err)
 	}
-	return c.Status(fiber.StatusOK).JSON(order)
+	return c.Status(fiber.StatusOK).JSON(query.projection.apply(handler.orders.Encode(order)))
 }
 
 // CreateOrder godoc
 // @Summary Create order
-// @Description Create a new order
+// @Description Create a new order. Customers may only order for themselves; customer_id defaults to the caller and item prices are the current product prices.
 // @Tags orders
 // @Accept  json
 // @Produce  json
-// @Param order body model.Order true "Order to create"
+// @Param order body dto.OrderRequest true "Order to create"
 // @Success 201 {object} map[string]string
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /orders [post]
 func (handler *OrderHandler) CreateOrder(c *fiber.Ctx) error {
-	var order model.Order
-	if err := c.BodyParser(&order); err != nil {
+	ctx := c.UserContext()
+	order, err := handler.orders.decodeBody(c)
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
-	err := handler.orderRepository.CreateOrder(order)
+	if customerID, scoped := auth.OwnCustomer(ctx, auth.PermissionOrdersWrite); scoped {
+		if order.CustomerID != "" && order.CustomerID != customerID {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Orders can only be placed for your own customer",
+			})
+		}
+		order.CustomerID = customerID
+		order, err = handler.orderRepository.WithCatalogPrices(ctx, order)
+		if errors.Is(err, repository.ErrUnknownProduct) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if err != nil {
+			return internalError(c, "Failed to create order", err)
+		}
+	}
+	err = handler.orderRepository.CreateOrder(ctx, order)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return internalError(c, "Failed to create order", err)
 	}
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"message": "Order created",
@@ -88,30 +145,55 @@ func (handler *OrderHandler) CreateOrder(c *fiber.Ctx) error {
 
 // UpdateOrder godoc
 // @Summary Update order
-// @Description Update an existing order
+// @Description Update an existing order. Customers may only update their own orders and cannot change customer_id; their item prices are the current product prices.
 // @Tags orders
 // @Accept  json
 // @Produce  json
 // @Param id path string true "Order ID"
-// @Param order body model.Order true "Order to update"
+// @Param order body dto.OrderRequest true "Order to update"
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /orders/{id} [put]
 func (handler *OrderHandler) UpdateOrder(c *fiber.Ctx) error {
+	ctx := c.UserContext()
 	orderID := c.Params("id")
-	var order model.Order
-	if err := c.BodyParser(&order); err != nil {
+	order, err := handler.orders.decodeBody(c)
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 	order.ID = orderID
-	err := handler.orderRepository.UpdateOrder(order)
+	if customerID, scoped := auth.OwnCustomer(ctx, auth.PermissionOrdersWrite); scoped {
+		current, err := handler.orderRepository.GetOrderByID(ctx, orderID, repository.OrderIncludes{})
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && current.CustomerID != customerID) {
+			return orderNotFound(c)
+		}
+		if err != nil {
+			return internalError(c, "Failed to update order", err)
+		}
+		if order.CustomerID != "" && order.CustomerID != customerID {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "customer_id cannot be changed",
+			})
+		}
+		order.CustomerID = customerID
+		order, err = handler.orderRepository.WithCatalogPrices(ctx, order)
+		if errors.Is(err, repository.ErrUnknownProduct) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if err != nil {
+			return internalError(c, "Failed to update order", err)
+		}
+	}
+	err = handler.orderRepository.UpdateOrder(ctx, order)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return internalError(c, "Failed to update order", err)
 	}
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message": "Order updated",
@@ -130,13 +212,96 @@ func (handler *OrderHandler) UpdateOrder(c *fiber.Ctx) error {
 // @Router /orders/{id} [delete]
 func (handler *OrderHandler) DeleteOrder(c *fiber.Ctx) error {
 	orderID := c.Params("id")
-	err := handler.orderRepository.DeleteOrder(orderID)
+	err := handler.orderRepository.DeleteOrder(c.UserContext(), orderID)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return internalError(c, "Failed to delete order", err)
 	}
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message": "Order deleted",
 	})
 }
+
+// BatchOrders godoc
+// @Summary Batch create, update and delete orders
+// @Description Apply many order operations in a single transaction. In all_or_nothing mode (the default) the first failure rolls back the batch; in best_effort mode each operation succeeds or fails on its own.
+// @Tags orders
+// @Accept  json
+// @Produce  json
+// @Param batch body model.BatchRequest[dto.OrderRequest] true "Operations to apply"
+// @Success 200 {object} model.BatchResponse
+// @Success 207 {object} model.BatchResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /orders:batch [post]
+func (handler *OrderHandler) BatchOrders(c *fiber.Ctx) error {
+	var request model.BatchRequest[model.Order]
+	if message := decodeBatchRequest(c, &request, handler.orders.Decode, orderID); message != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": message,
+		})
+	}
+	for _, operation := range request.Operations {
+		if operation.Op == model.BatchOpDelete && !auth.HasPermission(c.UserContext(), auth.PermissionOrdersDelete) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Deleting orders requires the " + auth.PermissionOrdersDelete + " permission",
+			})
+		}
+	}
+	errs, err := handler.orderRepository.ApplyOrderBatch(c.UserContext(), request.Operations, repository.BatchOptions{
+		BestEffort: request.Mode == model.BatchModeBestEffort,
+	})
+	if err != nil {
+		return internalError(c, "Failed to apply order batch", err)
+	}
+	status, response := newBatchResponse(c, request, errs, orderID)
+	return c.Status(status).JSON(response)
+}
+
+func orderNotFound(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+		"error": "Order not found",
+	})
+}
+
+func orderID(order model.Order) string {
+	return order.ID
+}
+
+// ExportOrders godoc
+// @Summary Export orders
+// @Description Stream all orders as CSV or NDJSON, flattened to one row per order item
+// @Tags orders
+// @Produce  text/csv
+// @Produce  application/x-ndjson
+// @Param format query string false "Export format" Enums(csv, ndjson)
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Router /orders/export [get]
+func (handler *OrderHandler) ExportOrders(c *fiber.Ctx) error {
+	return streamExport(c, "orders", func(ctx context.Context, w io.Writer, format transfer.Format) error {
+		return transfer.ExportOrders(ctx, w, format, handler.orderRepository)
+	})
+}
+
+// ImportOrders godoc
+// @Summary Import orders
+// @Description Create orders from CSV (with a header line) or NDJSON, flattened to one row per order item; rows sharing an order_id form one order. Columns: order_id, customer_id, order_date, item_id, product_id, quantity, price, one row per item.
+// @Tags orders
+// @Accept  text/csv
+// @Accept  application/x-ndjson
+// @Produce  json
+// @Param format query string false "Import format" Enums(csv, ndjson)
+// @Param map query string false "Header mapping, e.g. Order:order_id"
+// @Param dry_run query bool false "Validate against the database without writing"
+// @Param atomic query bool false "Import nothing unless every row succeeds"
+// @Success 200 {object} model.ImportReport
+// @Success 207 {object} model.ImportReport
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /orders/import [post]
+func (handler *OrderHandler) ImportOrders(c *fiber.Ctx) error {
+	return importResources(c, func(ctx context.Context, r io.Reader, options transfer.ImportOptions) (model.ImportReport, error) {
+		return transfer.ImportOrders(ctx, r, options, handler.orderRepository)
+	})
+}
@@ -1,8 +1,14 @@
 package handler
 
 import (
+	"api/auth"
+	"api/dto"
 	"api/model"
 	"api/repository"
+	"api/transfer"
+	"context"
+	"errors"
+	"io"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -19,42 +25,52 @@ func NewProductHandler(productRepository *repository.ProductRepository) *Product
 
 // GetProducts godoc
 // @Summary List products
-// @Description Get all products
+// @Description Get all products. Responses carry an ETag and Last-Modified for conditional requests.
 // @Tags products
 // @Accept  json
 // @Produce  json
-// @Success 200 {array} model.Product
+// @Param fields query string false "Comma-separated fields to send, e.g. id,name"
+// @Success 200 {array} dto.ProductResponse
+// @Success 304
+// @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /products [get]
 func (handler *ProductHandler) GetProducts(c *fiber.Ctx) error {
-	products, err := handler.productRepository.GetProducts()
+	p, message := parseFields(c, dto.ProductResponse{})
+	if message != "" {
+		return invalidQuery(c, message)
+	}
+	products, modified, err := handler.productRepository.GetProductsCached(c.UserContext())
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to retrieve products",
-		})
+		return internalError(c, "Failed to retrieve products", err)
 	}
-	return c.Status(fiber.StatusOK).JSON(products)
+	return sendCached(c, modified, p.apply(dto.NewProductResponses(products)))
 }
 
 // GetProductByID godoc
 // @Summary Get product by ID
-// @Description Get a product by its ID
+// @Description Get a product by its ID. Responses carry an ETag and Last-Modified for conditional requests.
 // @Tags products
 // @Accept  json
 // @Produce  json
 // @Param id path string true "Product ID"
-// @Success 200 {object} model.Product
+// @Param fields query string false "Comma-separated fields to send, e.g. id,name"
+// @Success 200 {object} dto.ProductResponse
+// @Success 304
+// @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /products/{id} [get]
 func (handler *ProductHandler) GetProductByID(c *fiber.Ctx) error {
+	p, message := parseFields(c, dto.ProductResponse{})
+	if message != "" {
+		return invalidQuery(c, message)
+	}
 	productID := c.Params("id")
-	product, err := handler.productRepository.GetProductByID(productID)
+	product, modified, err := handler.productRepository.GetProductByIDCached(c.UserContext(), productID)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to retrieve product",
-		})
+		return internalError(c, "Failed to retrieve product", err)
 	}
-	return c.Status(fiber.StatusOK).JSON(product)
+	return sendCached(c, modified, p.apply(dto.NewProductResponse(product)))
 }
 
 // CreateProduct godoc
@@ -63,22 +79,24 @@ func (handler *ProductHandler) GetProductByID(c *fiber.Ctx) error {
 // @Tags products
 // @Accept  json
 // @Produce  json
-// @Param product body model.Product true "Product to create"
+// @Param product body dto.ProductRequest true "Product to create"
 // @Success 201
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /products [post]
 func (handler *ProductHandler) CreateProduct(c *fiber.Ctx) error {
-	var product model.Product
-	if err := c.BodyParser(&product); err != nil {
+	product, err := decodeBody(c, decodeProduct)
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Invalid product data",
 		})
 	}
-	if err := handler.productRepository.CreateProduct(product); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create product",
-		})
+	if !auth.HasPermission(c.UserContext(), auth.PermissionProductsPrice) {
+		return priceForbidden(c)
+	}
+	if err := handler.productRepository.CreateProduct(c.UserContext(), product); err != nil {
+		return internalError(c, "Failed to create product", err)
 	}
 	return c.SendStatus(fiber.StatusCreated)
 }
@@ -90,24 +108,31 @@ func (handler *ProductHandler) CreateProduct(c *fiber.Ctx) error {
 // @Accept  json
 // @Produce  json
 // @Param id path string true "Product ID"
-// @Param product body model.Product true "Product to update"
+// @Param product body dto.ProductRequest true "Product to update"
 // @Success 200
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /products/{id} [put]
 func (handler *ProductHandler) UpdateProduct(c *fiber.Ctx) error {
 	productID := c.Params("id")
-	var product model.Product
-	if err := c.BodyParser(&product); err != nil {
+	product, err := decodeBody(c, decodeProduct)
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Invalid product data",
 		})
 	}
 	product.ID = productID
-	if err := handler.productRepository.UpdateProduct(product); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update product",
-		})
+	update := handler.productRepository.UpdateProduct
+	if !auth.HasPermission(c.UserContext(), auth.PermissionProductsPrice) {
+		update = handler.productRepository.UpdateProductKeepingPrice
+	}
+	err = update(c.UserContext(), product)
+	if errors.Is(err, repository.ErrPriceChange) {
+		return priceForbidden(c)
+	}
+	if err != nil {
+		return internalError(c, "Failed to update product", err)
 	}
 	return c.SendStatus(fiber.StatusOK)
 }
@@ -124,10 +149,97 @@ func (handler *ProductHandler) UpdateProduct(c *fiber.Ctx) error {
 // @Router /products/{id} [delete]
 func (handler *ProductHandler) DeleteProduct(c *fiber.Ctx) error {
 	productID := c.Params("id")
-	if err := handler.productRepository.DeleteProduct(productID); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to delete product",
-		})
+	if err := handler.productRepository.DeleteProduct(c.UserContext(), productID); err != nil {
+		return internalError(c, "Failed to delete product", err)
 	}
 	return c.SendStatus(fiber.StatusOK)
 }
+
+// BatchProducts godoc
+// @Summary Batch create, update and delete products
+// @Description Apply many product operations in a single transaction. In all_or_nothing mode (the default) the first failure rolls back the batch; in best_effort mode each operation succeeds or fails on its own.
+// @Tags products
+// @Accept  json
+// @Produce  json
+// @Param batch body model.BatchRequest[dto.ProductRequest] true "Operations to apply"
+// @Success 200 {object} model.BatchResponse
+// @Success 207 {object} model.BatchResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /products:batch [post]
+func (handler *ProductHandler) BatchProducts(c *fiber.Ctx) error {
+	var request model.BatchRequest[model.Product]
+	if message := decodeBatchRequest(c, &request, decodeProduct, productID); message != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": message,
+		})
+	}
+	errs, err := handler.productRepository.ApplyProductBatch(c.UserContext(), request.Operations, repository.BatchOptions{
+		BestEffort: request.Mode == model.BatchModeBestEffort,
+		KeepPrices: !auth.HasPermission(c.UserContext(), auth.PermissionProductsPrice),
+	})
+	if err != nil {
+		return internalError(c, "Failed to apply product batch", err)
+	}
+	status, response := newBatchResponse(c, request, errs, productID)
+	return c.Status(status).JSON(response)
+}
+
+var decodeProduct = requestDecoder(dto.ProductRequest.Product)
+
+func productID(product model.Product) string {
+	return product.ID
+}
+
+// priceForbiddenMessage answers writes that set or change a price without
+// products:price.
+const priceForbiddenMessage = "Setting or changing a price requires the " + auth.PermissionProductsPrice + " permission"
+
+func priceForbidden(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+		"error": priceForbiddenMessage,
+	})
+}
+
+// ExportProducts godoc
+// @Summary Export products
+// @Description Stream all products as CSV or NDJSON
+// @Tags products
+// @Produce  text/csv
+// @Produce  application/x-ndjson
+// @Param format query string false "Export format" Enums(csv, ndjson)
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Router /products/export [get]
+func (handler *ProductHandler) ExportProducts(c *fiber.Ctx) error {
+	return streamExport(c, "products", func(ctx context.Context, w io.Writer, format transfer.Format) error {
+		return transfer.ExportProducts(ctx, w, format, handler.productRepository)
+	})
+}
+
+// ImportProducts godoc
+// @Summary Import products
+// @Description Create products from CSV (with a header line) or NDJSON. Columns: id, name, price.
+// @Tags products
+// @Accept  text/csv
+// @Accept  application/x-ndjson
+// @Produce  json
+// @Param format query string false "Import format" Enums(csv, ndjson)
+// @Param map query string false "Header mapping, e.g. SKU:id,Title:name"
+// @Param dry_run query bool false "Validate against the database without writing"
+// @Param atomic query bool false "Import nothing unless every row succeeds"
+// @Success 200 {object} model.ImportReport
+// @Success 207 {object} model.ImportReport
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /products/import [post]
+func (handler *ProductHandler) ImportProducts(c *fiber.Ctx) error {
+	if !auth.HasPermission(c.UserContext(), auth.PermissionProductsPrice) {
+		return priceForbidden(c)
+	}
+	return importResources(c, func(ctx context.Context, r io.Reader, options transfer.ImportOptions) (model.ImportReport, error) {
+		return transfer.ImportProducts(ctx, r, options, handler.productRepository)
+	})
+}
@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"api/model"
+	"api/repository"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// projection selects the top-level fields of responses: those in only,
+// unless it is nil, except those in hidden.
+type projection struct {
+	only   map[string]bool
+	hidden map[string]bool
+}
+
+func (p projection) includes(field string) bool {
+	return (p.only == nil || p.only[field]) && !p.hidden[field]
+}
+
+func (p *projection) hide(field string) {
+	if p.hidden == nil {
+		p.hidden = map[string]bool{}
+	}
+	p.hidden[field] = true
+}
+
+// apply returns response, an object or array of objects, encoding to JSON
+// with only the selected fields.
+func (p projection) apply(response any) any {
+	if p.only == nil && p.hidden == nil {
+		return response
+	}
+	return projected{projection: p, response: response}
+}
+
+type projected struct {
+	projection projection
+	response   any
+}
+
+func (p projected) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(p.response)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(string(data), "[") {
+		var objects []map[string]json.RawMessage
+		if err := json.Unmarshal(data, &objects); err != nil {
+			return nil, err
+		}
+		for _, object := range objects {
+			p.projection.filter(object)
+		}
+		return json.Marshal(objects)
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+	p.projection.filter(object)
+	return json.Marshal(object)
+}
+
+func (p projection) filter(object map[string]json.RawMessage) {
+	for field := range object {
+		if !p.includes(field) {
+			delete(object, field)
+		}
+	}
+}
+
+// parseFields parses the ?fields parameter, a comma-separated list of the
+// JSON fields of response. It returns a client error message when a field
+// is unknown.
+func parseFields(c *fiber.Ctx, response any) (projection, string) {
+	if !c.Context().QueryArgs().Has("fields") {
+		return projection{}, ""
+	}
+	known := jsonFields(reflect.TypeOf(response))
+	p := projection{only: map[string]bool{}}
+	for _, field := range queryList(c, "fields") {
+		if !known[field] {
+			return p, fmt.Sprintf("Unknown field %q", field)
+		}
+		p.only[field] = true
+	}
+	return p, ""
+}
+
+// jsonFields returns the names structs of type t are encoded with.
+func jsonFields(t reflect.Type) map[string]bool {
+	fields := map[string]bool{}
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+	return fields
+}
+
+func queryList(c *fiber.Ctx, key string) []string {
+	var values []string
+	for _, value := range strings.Split(c.Query(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// orderQuery is what a request for orders asks to be sent: the relations to
+// load and the fields to send.
+type orderQuery struct {
+	includes   repository.OrderIncludes
+	projection projection
+}
+
+// parseOrderQuery parses ?fields and ?expand, which lists the relations to
+// embed: "customer", the items field and its ".product". Without ?expand
+// every relation is embedded. Relations that are not sent are not loaded.
+func (representation OrderRepresentation) parseOrderQuery(c *fiber.Ctx) (orderQuery, string) {
+	p, message := parseFields(c, representation.Encode(model.Order{}))
+	if message != "" {
+		return orderQuery{}, message
+	}
+	query := orderQuery{includes: repository.AllOrderIncludes, projection: p}
+	if c.Context().QueryArgs().Has("expand") {
+		query.includes = repository.OrderIncludes{}
+		for _, relation := range queryList(c, "expand") {
+			switch relation {
+			case "customer":
+				query.includes.Customer = true
+			case representation.ItemsField:
+				query.includes.Items = true
+			case representation.ItemsField + ".product":
+				query.includes.Items = true
+				query.includes.Products = true
+			default:
+				return orderQuery{}, fmt.Sprintf("Unknown expansion %q", relation)
+			}
+		}
+	}
+	if !p.includes("customer") {
+		query.includes.Customer = false
+	}
+	if !query.includes.Items || !p.includes(representation.ItemsField) {
+		query.includes.Items = false
+		query.includes.Products = false
+		query.projection.hide(representation.ItemsField)
+	}
+	for _, field := range representation.ItemTotals {
+		if p.includes(field) {
+			query.includes.Items = true
+		}
+	}
+	return query, ""
+}
+
+func invalidQuery(c *fiber.Ctx, message string) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error": message,
+	})
+}
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"api/dto"
+	"api/model"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// OrderRepresentation converts orders to and from the JSON of an API
+// version, so that every version is served by the same handlers and
+// repositories.
+type OrderRepresentation struct {
+	// Decode parses an order sent in a request body or batch operation.
+	Decode func(data []byte) (model.Order, error)
+	// Encode returns the value sent for order.
+	Encode func(order model.Order) any
+	// ItemsField is the field the items of orders are sent in.
+	ItemsField string
+	// ItemTotals are the fields computed from the items, which are loaded
+	// whenever one of them is sent.
+	ItemTotals []string
+}
+
+// OrdersV1 receives dto.OrderRequest and sends dto.OrderResponse.
+var OrdersV1 = OrderRepresentation{
+	Decode: requestDecoder(dto.OrderRequest.Order),
+	Encode: func(order model.Order) any {
+		return dto.NewOrderResponse(order)
+	},
+	ItemsField: "order_items",
+}
+
+// OrdersV2 receives dto.OrderV2Request and sends dto.OrderV2Response.
+var OrdersV2 = OrderRepresentation{
+	Decode: requestDecoder(dto.OrderV2Request.Order),
+	Encode: func(order model.Order) any {
+		return dto.NewOrderV2Response(order)
+	},
+	ItemsField: "items",
+	ItemTotals: []string{"total"},
+}
+
+func (representation OrderRepresentation) encodeAll(orders []model.Order) []any {
+	encoded := make([]any, len(orders))
+	for i, order := range orders {
+		encoded[i] = representation.Encode(order)
+	}
+	return encoded
+}
+
+// decodeBody parses the request body as an order.
+func (representation OrderRepresentation) decodeBody(c *fiber.Ctx) (model.Order, error) {
+	return decodeBody(c, representation.Decode)
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// decodeJSON decodes data into the request DTO request, rejecting fields it
+// does not declare rather than silently ignoring them.
+func decodeJSON(data []byte, request any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(request); err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return errors.New("unexpected data after the JSON value")
+	}
+	return nil
+}
+
+// parseRequest decodes a JSON request body into request.
+func parseRequest(c *fiber.Ctx, request any) error {
+	if !c.Is("json") {
+		return fiber.ErrUnprocessableEntity
+	}
+	return decodeJSON(c.Body(), request)
+}
+
+// requestDecoder returns a function decoding request DTOs of type R and
+// mapping them to models with toModel.
+func requestDecoder[R any, M any](toModel func(R) M) func([]byte) (M, error) {
+	return func(data []byte) (M, error) {
+		var request R
+		if err := decodeJSON(data, &request); err != nil {
+			var zero M
+			return zero, err
+		}
+		return toModel(request), nil
+	}
+}
+
+// decodeBody parses the request body with decode.
+func decodeBody[M any](c *fiber.Ctx, decode func([]byte) (M, error)) (M, error) {
+	if !c.Is("json") {
+		var zero M
+		return zero, fiber.ErrUnprocessableEntity
+	}
+	return decode(c.Body())
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"api/model"
+	"api/repository"
+	"database/sql"
+	"errors"
+	"regexp"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	roleNamePattern   = regexp.MustCompile(`^[a-z0-9_-]{1,64}$`)
+	permissionPattern = regexp.MustCompile(`^(\*|[a-z_]+(:[a-z_]+|:\*)?)$`)
+)
+
+type RoleHandler struct {
+	roleRepository *repository.RoleRepository
+}
+
+func NewRoleHandler(roleRepository *repository.RoleRepository) *RoleHandler {
+	return &RoleHandler{
+		roleRepository: roleRepository,
+	}
+}
+
+// GetRoles godoc
+// @Summary List roles
+// @Description List every role with the permissions it grants
+// @Tags admin
+// @Produce  json
+// @Success 200 {array} model.Role
+// @Failure 500 {object} map[string]string
+// @Router /admin/roles [get]
+func (handler *RoleHandler) GetRoles(c *fiber.Ctx) error {
+	roles, err := handler.roleRepository.GetRoles(c.UserContext())
+	if err != nil {
+		return internalError(c, "Failed to retrieve roles", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(roles)
+}
+
+// SaveRole godoc
+// @Summary Create or replace role
+// @Description Create a role, or replace the description and permissions of an existing one. Users holding it are affected from their next request.
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Param name path string true "Role name"
+// @Param role body model.Role true "Role description and permissions"
+// @Success 200
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/roles/{name} [put]
+func (handler *RoleHandler) SaveRole(c *fiber.Ctx) error {
+	var role model.Role
+	if err := c.BodyParser(&role); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid role data",
+		})
+	}
+	role.Name = c.Params("name")
+	if !roleNamePattern.MatchString(role.Name) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Role names are 1 to 64 lower-case letters, digits, - or _",
+		})
+	}
+	for _, permission := range role.Permissions {
+		if !permissionPattern.MatchString(permission) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid permission " + permission,
+			})
+		}
+	}
+	if err := handler.roleRepository.SaveRole(c.UserContext(), role); err != nil {
+		return internalError(c, "Failed to save role", err)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// GetUserRoles godoc
+// @Summary Get user roles
+// @Description Get the roles assigned to a user
+// @Tags admin
+// @Produce  json
+// @Param id path string true "User ID"
+// @Success 200 {object} model.UserRoles
+// @Failure 500 {object} map[string]string
+// @Router /admin/users/{id}/roles [get]
+func (handler *RoleHandler) GetUserRoles(c *fiber.Ctx) error {
+	roles, err := handler.roleRepository.GetUserRoles(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return internalError(c, "Failed to retrieve user roles", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(model.UserRoles{Roles: roles})
+}
+
+// SetUserRoles godoc
+// @Summary Set user roles
+// @Description Replace the roles assigned to a user
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID"
+// @Param roles body model.UserRoles true "Roles to assign"
+// @Success 200
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/users/{id}/roles [put]
+func (handler *RoleHandler) SetUserRoles(c *fiber.Ctx) error {
+	var request model.UserRoles
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid roles data",
+		})
+	}
+	err := handler.roleRepository.SetUserRoles(c.UserContext(), c.Params("id"), request.Roles)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+	if errors.Is(err, repository.ErrUnknownRole) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Unknown role",
+		})
+	}
+	if err != nil {
+		return internalError(c, "Failed to set user roles", err)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"api/model"
+	"api/repository"
+	"api/tenant"
+	"database/sql"
+	"errors"
+	"os"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type TenantHandler struct {
+	manager *tenant.Manager
+}
+
+func NewTenantHandler(manager *tenant.Manager) *TenantHandler {
+	return &TenantHandler{
+		manager: manager,
+	}
+}
+
+// GetTenants godoc
+// @Summary List tenants
+// @Description List the provisioned tenants. Only available outside of a tenant.
+// @Tags admin
+// @Produce  json
+// @Success 200 {array} model.Tenant
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/tenants [get]
+func (handler *TenantHandler) GetTenants(c *fiber.Ctx) error {
+	if tenant.FromContext(c.UserContext()) != "" {
+		return tenantsForbidden(c)
+	}
+	tenants, err := handler.manager.Tenants(c.UserContext())
+	if err != nil {
+		return internalError(c, "Failed to retrieve tenants", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(tenants)
+}
+
+// CreateTenant godoc
+// @Summary Provision tenant
+// @Description Register a tenant and create its database. Requests with the tenant's host, or bearer tokens with its ID as the tenant claim, are served from it. Create its first user with "cleango user create -tenant ID".
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Param tenant body model.Tenant true "Tenant to provision"
+// @Success 201 {object} model.Tenant
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/tenants [post]
+func (handler *TenantHandler) CreateTenant(c *fiber.Ctx) error {
+	if tenant.FromContext(c.UserContext()) != "" {
+		return tenantsForbidden(c)
+	}
+	var request model.Tenant
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid tenant data",
+		})
+	}
+	created, err := handler.manager.Provision(c.UserContext(), request)
+	if errors.Is(err, tenant.ErrInvalidTenant) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if repository.IsConflict(err) || errors.Is(err, os.ErrExist) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "A tenant with this ID or host already exists",
+		})
+	}
+	if err != nil {
+		return internalError(c, "Failed to provision tenant", err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+// DeleteTenant godoc
+// @Summary Delete tenant
+// @Description Unregister a tenant and delete its database with all of its data.
+// @Tags admin
+// @Param id path string true "Tenant ID"
+// @Success 204
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/tenants/{id} [delete]
+func (handler *TenantHandler) DeleteTenant(c *fiber.Ctx) error {
+	if tenant.FromContext(c.UserContext()) != "" {
+		return tenantsForbidden(c)
+	}
+	err := handler.manager.Delete(c.UserContext(), c.Params("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Tenant not found",
+		})
+	}
+	if err != nil {
+		return internalError(c, "Failed to delete tenant", err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// tenantsForbidden rejects tenant management from within a tenant, whose
+// admins only administer their own storefront.
+func tenantsForbidden(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+		"error": "Tenants can only be managed outside of a tenant",
+	})
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"api/model"
+	"api/transfer"
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// streamExport streams a CSV or NDJSON download named after the resource.
+// export runs after the handler has returned, so it must not use c.
+func streamExport(c *fiber.Ctx, name string, export func(ctx context.Context, w io.Writer, format transfer.Format) error) error {
+	format, err := transfer.ParseFormat(c.Query("format"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, format.ContentType())
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+	ctx := c.UserContext()
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := export(ctx, w, format); err != nil {
+			slog.ErrorContext(ctx, "export failed", "resource", name, "error", err)
+			return
+		}
+		w.Flush()
+	})
+	return nil
+}
+
+// importResources creates the records read from the request body.
+//
+// Query parameters: format (csv or ndjson), map ("Source Column:field,..."),
+// dry_run to validate against the database without writing, and atomic to
+// import nothing unless every row is valid and can be written.
+func importResources(c *fiber.Ctx, importer func(context.Context, io.Reader, transfer.ImportOptions) (model.ImportReport, error)) error {
+	format, err := transfer.ParseFormat(c.Query("format"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	mapping, err := transfer.ParseMapping(c.Query("map"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	report, err := importer(c.UserContext(), bytes.NewReader(c.Body()), transfer.ImportOptions{
+		Format:  format,
+		Mapping: mapping,
+		DryRun:  c.QueryBool("dry_run"),
+		Atomic:  c.QueryBool("atomic"),
+	})
+	var inputErr *transfer.InputError
+	if errors.As(err, &inputErr) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": inputErr.Error(),
+		})
+	}
+	if err != nil {
+		return internalError(c, "Failed to import data", err)
+	}
+
+	if report.Failed > 0 {
+		return c.Status(fiber.StatusMultiStatus).JSON(report)
+	}
+	return c.Status(fiber.StatusOK).JSON(report)
+}
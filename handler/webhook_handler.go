@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"api/dto"
+	"api/model"
+	"api/repository"
+	"database/sql"
+	"errors"
+	"net/url"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type WebhookHandler struct {
+	webhookRepository *repository.WebhookRepository
+}
+
+func NewWebhookHandler(webhookRepository *repository.WebhookRepository) *WebhookHandler {
+	return &WebhookHandler{
+		webhookRepository: webhookRepository,
+	}
+}
+
+// GetWebhooks godoc
+// @Summary List webhook subscriptions
+// @Description Get all webhook subscriptions. Secrets are never returned.
+// @Tags webhooks
+// @Accept  json
+// @Produce  json
+// @Success 200 {array} dto.WebhookResponse
+// @Failure 500 {object} map[string]string
+// @Router /webhooks [get]
+func (handler *WebhookHandler) GetWebhooks(c *fiber.Ctx) error {
+	subscriptions, err := handler.webhookRepository.GetWebhookSubscriptions(c.UserContext())
+	if err != nil {
+		return internalError(c, "Failed to retrieve webhooks", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(dto.NewWebhookResponses(subscriptions))
+}
+
+// GetWebhookByID godoc
+// @Summary Get webhook subscription by ID
+// @Description Get a webhook subscription by its ID
+// @Tags webhooks
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} dto.WebhookResponse
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /webhooks/{id} [get]
+func (handler *WebhookHandler) GetWebhookByID(c *fiber.Ctx) error {
+	subscription, err := handler.webhookRepository.GetWebhookSubscriptionByID(c.UserContext(), c.Params("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		return webhookNotFound(c)
+	}
+	if err != nil {
+		return internalError(c, "Failed to retrieve webhook", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(dto.NewWebhookResponse(subscription))
+}
+
+// CreateWebhook godoc
+// @Summary Create webhook subscription
+// @Description Subscribe a URL to domain events. An empty event_types list subscribes to every event.
+// @Tags webhooks
+// @Accept  json
+// @Produce  json
+// @Param webhook body dto.WebhookRequest true "Webhook to create"
+// @Success 201
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /webhooks [post]
+func (handler *WebhookHandler) CreateWebhook(c *fiber.Ctx) error {
+	var request dto.WebhookRequest
+	err := parseRequest(c, &request)
+	subscription := request.WebhookSubscription()
+	if err != nil || !validWebhook(subscription) || subscription.Secret == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid webhook data",
+		})
+	}
+	if err := handler.webhookRepository.CreateWebhookSubscription(c.UserContext(), subscription); err != nil {
+		return internalError(c, "Failed to create webhook", err)
+	}
+	return c.SendStatus(fiber.StatusCreated)
+}
+
+// UpdateWebhook godoc
+// @Summary Update webhook subscription
+// @Description Update a webhook subscription. An empty secret and an omitted active keep the current values; setting active re-enables a disabled endpoint.
+// @Tags webhooks
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Webhook ID"
+// @Param webhook body dto.WebhookRequest true "Webhook to update"
+// @Success 200
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /webhooks/{id} [put]
+func (handler *WebhookHandler) UpdateWebhook(c *fiber.Ctx) error {
+	var request dto.WebhookRequest
+	if err := parseRequest(c, &request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid webhook data",
+		})
+	}
+	subscription := request.WebhookSubscription()
+	subscription.ID = c.Params("id")
+	if !validWebhook(subscription) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid webhook data",
+		})
+	}
+	// Omitting "active" keeps the current value
+	if request.Active == nil {
+		current, err := handler.webhookRepository.GetWebhookSubscriptionByID(c.UserContext(), subscription.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return webhookNotFound(c)
+		}
+		if err != nil {
+			return internalError(c, "Failed to update webhook", err)
+		}
+		subscription.Active = current.Active
+	}
+	err := handler.webhookRepository.UpdateWebhookSubscription(c.UserContext(), subscription)
+	if errors.Is(err, sql.ErrNoRows) {
+		return webhookNotFound(c)
+	}
+	if err != nil {
+		return internalError(c, "Failed to update webhook", err)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// DeleteWebhook godoc
+// @Summary Delete webhook subscription
+// @Description Delete a webhook subscription and its delivery log
+// @Tags webhooks
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Webhook ID"
+// @Success 200
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /webhooks/{id} [delete]
+func (handler *WebhookHandler) DeleteWebhook(c *fiber.Ctx) error {
+	err := handler.webhookRepository.DeleteWebhookSubscription(c.UserContext(), c.Params("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		return webhookNotFound(c)
+	}
+	if err != nil {
+		return internalError(c, "Failed to delete webhook", err)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// GetWebhookDeliveries godoc
+// @Summary List webhook deliveries
+// @Description Get the delivery log of a webhook subscription
+// @Tags webhooks
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Webhook ID"
+// @Success 200 {array} dto.WebhookDeliveryResponse
+// @Failure 500 {object} map[string]string
+// @Router /webhooks/{id}/deliveries [get]
+func (handler *WebhookHandler) GetWebhookDeliveries(c *fiber.Ctx) error {
+	deliveries, err := handler.webhookRepository.GetWebhookDeliveries(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return internalError(c, "Failed to retrieve webhook deliveries", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(dto.NewWebhookDeliveryResponses(deliveries))
+}
+
+// ReplayWebhookDelivery godoc
+// @Summary Replay webhook delivery
+// @Description Queue a new delivery with the same payload as an earlier one
+// @Tags webhooks
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Webhook ID"
+// @Param deliveryId path int true "Delivery ID"
+// @Success 202 {object} dto.WebhookDeliveryResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /webhooks/{id}/deliveries/{deliveryId}/replay [post]
+func (handler *WebhookHandler) ReplayWebhookDelivery(c *fiber.Ctx) error {
+	deliveryID, err := strconv.ParseInt(c.Params("deliveryId"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid delivery ID",
+		})
+	}
+	delivery, err := handler.webhookRepository.ReplayWebhookDelivery(c.UserContext(), c.Params("id"), deliveryID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Webhook delivery not found",
+		})
+	}
+	if err != nil {
+		return internalError(c, "Failed to replay webhook delivery", err)
+	}
+	return c.Status(fiber.StatusAccepted).JSON(dto.NewWebhookDeliveryResponse(delivery))
+}
+
+func validWebhook(subscription model.WebhookSubscription) bool {
+	if subscription.ID == "" {
+		return false
+	}
+	u, err := url.Parse(subscription.URL)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func webhookNotFound(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+		"error": "Webhook not found",
+	})
+}
@@ -0,0 +1,66 @@
+package health
+
+import (
+	"api/database"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	migrate "github.com/golang-migrate/migrate/v4"
+)
+
+// Database checks that the database answers queries.
+func Database(db *sql.DB) Check {
+	return func(ctx context.Context) error {
+		var count int
+		return db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master").Scan(&count)
+	}
+}
+
+// Writable checks that the SQLite file and its directory, where SQLite keeps
+// its journal, can be written. A rolled back write would not prove this, as
+// SQLite only touches the file on commit.
+func Writable(path string) Check {
+	return func(ctx context.Context) error {
+		file, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return err
+		}
+		file.Close()
+
+		probe, err := os.CreateTemp(filepath.Dir(path), ".healthz-*")
+		if err != nil {
+			return err
+		}
+		probe.Close()
+		return os.Remove(probe.Name())
+	}
+}
+
+// Migrations checks that the schema is at the latest version in migrationsDir
+// and that no migration was left half applied.
+func Migrations(db *sql.DB, migrationsDir string) Check {
+	return func(ctx context.Context) error {
+		expected, err := database.LatestVersion(migrationsDir)
+		if err != nil {
+			return err
+		}
+		version, dirty, err := database.Version(db)
+		if errors.Is(err, migrate.ErrNilVersion) {
+			return fmt.Errorf("no migrations applied, expected version %d", expected)
+		}
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("version %d is dirty", version)
+		}
+		if version != expected {
+			return fmt.Errorf("version %d, expected %d", version, expected)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,42 @@
+package health
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Heartbeat records the progress of a background worker loop.
+type Heartbeat struct {
+	mu   sync.Mutex
+	last time.Time
+	err  error
+}
+
+// Beat records a completed iteration and its outcome.
+func (heartbeat *Heartbeat) Beat(err error) {
+	heartbeat.mu.Lock()
+	defer heartbeat.mu.Unlock()
+
+	heartbeat.last = time.Now()
+	heartbeat.err = err
+}
+
+// Check fails when the worker has never run, has not completed an iteration
+// within stallAfter, or its last iteration failed.
+func (heartbeat *Heartbeat) Check(stallAfter time.Duration) error {
+	heartbeat.mu.Lock()
+	defer heartbeat.mu.Unlock()
+
+	if heartbeat.last.IsZero() {
+		return errors.New("not running")
+	}
+	if since := time.Since(heartbeat.last); since > stallAfter {
+		return fmt.Errorf("no progress for %s", since.Round(time.Second))
+	}
+	if heartbeat.err != nil {
+		return fmt.Errorf("last run failed: %w", heartbeat.err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package health
+
+import (
+	"api/model"
+	"context"
+	"sync"
+	"time"
+)
+
+// Check reports whether a dependency is usable; a nil error means healthy.
+type Check func(ctx context.Context) error
+
+// Registry holds the named checks that make up readiness. Subsystems register
+// their own checks; all of them run concurrently on every probe.
+type Registry struct {
+	mu     sync.RWMutex
+	names  []string
+	checks map[string]Check
+
+	// Timeout bounds each check.
+	Timeout time.Duration
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		checks:  map[string]Check{},
+		Timeout: 2 * time.Second,
+	}
+}
+
+// Register adds a check, replacing any check already registered under name.
+func (registry *Registry) Register(name string, check Check) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if _, ok := registry.checks[name]; !ok {
+		registry.names = append(registry.names, name)
+	}
+	registry.checks[name] = check
+}
+
+// Run executes every check and reports each outcome in registration order.
+// The overall status is ok only when every check passes.
+func (registry *Registry) Run(ctx context.Context) model.HealthReport {
+	registry.mu.RLock()
+	names := append([]string(nil), registry.names...)
+	checks := make([]Check, len(names))
+	for i, name := range names {
+		checks[i] = registry.checks[name]
+	}
+	registry.mu.RUnlock()
+
+	report := model.HealthReport{
+		Status: model.HealthStatusOK,
+		Checks: make([]model.HealthCheck, len(names)),
+	}
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = registry.run(ctx, names[i], check)
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != model.HealthStatusOK {
+			report.Status = model.HealthStatusFail
+		}
+	}
+	return report
+}
+
+func (registry *Registry) run(ctx context.Context, name string, check Check) model.HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, registry.Timeout)
+	defer cancel()
+
+	result := model.HealthCheck{Name: name, Status: model.HealthStatusOK}
+	started := time.Now()
+	err := runCheck(ctx, check)
+	result.DurationMs = float64(time.Since(started).Microseconds()) / 1000
+	if err != nil {
+		result.Status = model.HealthStatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// runCheck returns when check does or when ctx expires, whichever is first,
+// so that a check ignoring its context cannot hold up the probe.
+func runCheck(ctx context.Context, check Check) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- check(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"api/auth"
+	"api/model"
+	"api/repository"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// keyCommand runs "key create".
+func keyCommand(args []string) error {
+	if len(args) == 0 || args[0] != "create" {
+		fmt.Fprintln(os.Stderr, "Usage: cleango key create -user ID -name NAME [flags]")
+		return errors.New("key: expected create")
+	}
+
+	fs, flags := newFlagSet("key create")
+	userID := fs.String("user", "", "ID of the user the key acts as")
+	name := fs.String("name", "", "what the key is used for")
+	expiresIn := fs.Duration("expires-in", 0, "lifetime of the key, e.g. 720h (default: no expiry)")
+	dailyQuota := fs.Int("daily-quota", -1, "requests allowed per UTC day, 0 for unlimited (default: rate_limit.daily_quota)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	cfg, err := flags.Load()
+	if err != nil {
+		return err
+	}
+	if *userID == "" || *name == "" {
+		return errors.New("key create: -user and -name are required")
+	}
+	if *expiresIn < 0 {
+		return errors.New("key create: -expires-in must not be negative")
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	if _, err := repository.NewUserRepository(db).GetUserByID(ctx, *userID); errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("key create: no user with ID %q", *userID)
+	} else if err != nil {
+		return err
+	}
+
+	var expiresAt *time.Time
+	if *expiresIn > 0 {
+		expiry := time.Now().Add(*expiresIn)
+		expiresAt = &expiry
+	}
+	key, secret, hash := auth.NewAPIKey(*userID, *name, expiresAt)
+	if *dailyQuota >= 0 {
+		key.DailyQuota = dailyQuota
+	}
+	key, err = repository.NewAPIKeyRepository(db).CreateAPIKey(ctx, key, hash)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(os.Stdout).Encode(model.CreatedAPIKey{APIKey: key, Key: secret})
+}
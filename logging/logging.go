@@ -0,0 +1,95 @@
+package logging
+
+import (
+	"api/config"
+	"api/tenant"
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type requestIDContextKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the request ID added to every
+// log record written with it.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx, or "" when none is set.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// ParseLevel accepts debug, info, warn and error in any case.
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(name))
+	return level, err
+}
+
+// LevelName returns the lower-case name accepted by ParseLevel.
+func LevelName(level slog.Level) string {
+	return strings.ToLower(level.String())
+}
+
+// New returns a logger writing JSON or text records to w at the level held
+// by level, so that it can be changed while the program runs. Records logged
+// with a context carry its request ID and trace and span IDs.
+func New(w io.Writer, format string, level *slog.LevelVar) *slog.Logger {
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	if format == "text" {
+		handler = slog.NewTextHandler(w, options)
+	} else {
+		handler = slog.NewJSONHandler(w, options)
+	}
+	return slog.New(contextHandler{handler})
+}
+
+// Setup makes a logger configured by cfg the default for slog and the log
+// package, and returns the variable controlling its level.
+func Setup(w io.Writer, cfg config.LogConfig) (*slog.LevelVar, error) {
+	level, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	levelVar := new(slog.LevelVar)
+	levelVar.Set(level)
+	slog.SetDefault(New(w, cfg.Format, levelVar))
+	return levelVar, nil
+}
+
+// contextHandler adds the request, tenant and trace identifiers found in
+// the record's context.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (handler contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestIDFromContext(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	if id := tenant.FromContext(ctx); id != "" {
+		record.AddAttrs(slog.String("tenant", id))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		record.AddAttrs(
+			slog.String("trace_id", span.TraceID().String()),
+			slog.String("span_id", span.SpanID().String()),
+		)
+	}
+	return handler.Handler.Handle(ctx, record)
+}
+
+func (handler contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{handler.Handler.WithAttrs(attrs)}
+}
+
+func (handler contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{handler.Handler.WithGroup(name)}
+}
@@ -1,63 +1,83 @@
 package main
 
 import (
+	"api/config"
 	"api/database"
-	"api/handler"
-	"api/repository"
-	"api/routes"
-	"log"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
 
-	_ "api/docs"
+const usage = `Usage: cleango <command> [arguments]
 
-	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/logger"
-	fiberSwagger "github.com/swaggo/fiber-swagger"
-)
+Commands:
+  serve                          start the HTTP server (the default)
+  migrate up|down [N]|status|goto V|force V
+                                 apply, roll back or inspect schema migrations
+  seed                           insert sample products, customers and orders
+  export <resource>              write products, customers or orders as CSV or NDJSON
+  import <resource>              create products, customers or orders from CSV or NDJSON
+  user create|roles              create a user or set its roles
+  key create                     create an API key for a user
+  config print                   show the effective configuration, secrets redacted
+
+Run "cleango <command> -h" for the flags of a command.
+`
+
+var commands = map[string]func(args []string) error{
+	"serve":   serveCommand,
+	"migrate": migrateCommand,
+	"seed":    seedCommand,
+	"export":  exportCommand,
+	"import":  importCommand,
+	"user":    userCommand,
+	"key":     keyCommand,
+	"config":  configCommand,
+}
 
 func main() {
-	// Initialize the database
-	db, err := database.InitializeDB("database/database.db", "file://database/migrations")
+	err := run(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
-		log.Fatal(err)
+		fmt.Fprintln(os.Stderr, "cleango:", err)
+		os.Exit(1)
+	}
+}
+
+// run dispatches to a subcommand. Without one, or when the first argument is
+// a flag, the server is started as before the CLI existed.
+func run(args []string) error {
+	if len(args) == 0 || (args[0] != "-h" && args[0] != "--help" && len(args[0]) > 0 && args[0][0] == '-') {
+		return serveCommand(args)
 	}
-	defer db.Close()
-
-	// Create the repository instances
-	productRepository := repository.NewProductRepository(db)
-	customerRepository := repository.NewCustomerRepository(db)
-	orderRepository := repository.NewOrderRepository(db)
-
-	// Create the handler instances
-	productHandler := handler.NewProductHandler(productRepository)
-	customerHandler := handler.NewCustomerHandler(customerRepository)
-	orderHandler := handler.NewOrderHandler(orderRepository)
-
-	// Initialize Fiber app
-	app := fiber.New(fiber.Config{
-		ErrorHandler: func(c *fiber.Ctx, err error) error {
-			code := fiber.StatusInternalServerError
-			if e, ok := err.(*fiber.Error); ok {
-				code = e.Code
-			}
-			return c.Status(code).JSON(fiber.Map{
-				"error": err.Error(),
-			})
-		},
-	})
-
-	// Add middleware
-	app.Use(logger.New())
-	app.Use(cors.New())
-
-	// Define the API routes
-	routes.SetupProductRoutes(app, productHandler)
-	routes.SetupCustomerRoutes(app, customerHandler)
-	routes.SetupOrderRoutes(app, orderHandler)
-
-	// Swagger docs route
-	app.Get("/swagger/*", fiberSwagger.WrapHandler)
-
-	// Start the HTTP server
-	log.Fatal(app.Listen(":8080"))
+
+	switch args[0] {
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return nil
+	}
+
+	command, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	return command(args[1:])
+}
+
+// newFlagSet returns a flag set for the named command with the shared
+// configuration flags registered on it. Call Load on the returned flags once
+// the flag set has been parsed.
+func newFlagSet(name string) (*flag.FlagSet, *config.Flags) {
+	fs := flag.NewFlagSet("cleango "+name, flag.ContinueOnError)
+	return fs, config.RegisterFlags(fs)
+}
+
+// openDB opens the configured database, applying any pending migrations.
+func openDB(cfg *config.Config) (*sql.DB, error) {
+	return database.InitializeDB(cfg.Database.Path, cfg.MigrationsURL())
 }
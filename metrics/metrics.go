@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"api/model"
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "cleango"
+
+// Metrics owns the Prometheus registry served on /metrics and the
+// collectors the rest of the service reports into.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	queryDuration   *prometheus.HistogramVec
+	events          *prometheus.CounterVec
+	ordersCreated   prometheus.Counter
+	orderValue      prometheus.Counter
+}
+
+func New() *Metrics {
+	metrics := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method, route pattern and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "repository_call_duration_seconds",
+			Help:      "Duration of repository method calls.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"repository", "method"}),
+		events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "events_published_total",
+			Help:      "Domain events published from the outbox, by type.",
+		}, []string{"type"}),
+		ordersCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "orders_created_total",
+			Help:      "Orders created.",
+		}),
+		orderValue: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "order_value_total",
+			Help:      "Sum of the value (quantity times price of every item) of created orders.",
+		}),
+	}
+
+	metrics.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		metrics.requests,
+		metrics.requestDuration,
+		metrics.queryDuration,
+		metrics.events,
+		metrics.ordersCreated,
+		metrics.orderValue,
+	)
+	return metrics
+}
+
+// Handler serves the registry in the Prometheus text format.
+func (metrics *Metrics) Handler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(metrics.registry, promhttp.HandlerOpts{}))
+}
+
+// RegisterDB exports the connection pool statistics of db (sql.DB.Stats)
+// under the given name.
+func (metrics *Metrics) RegisterDB(db *sql.DB, name string) {
+	metrics.registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// ObserveRequest records a served request. route is the matched pattern, such
+// as /products/:id, not the raw path, to keep the number of series bounded.
+func (metrics *Metrics) ObserveRequest(method string, route string, status int, duration time.Duration) {
+	labels := prometheus.Labels{"method": method, "route": route, "status": strconv.Itoa(status)}
+	metrics.requests.With(labels).Inc()
+	metrics.requestDuration.With(labels).Observe(duration.Seconds())
+}
+
+// ObserveQuery records a repository method call; it is a repository.ObserverFunc.
+func (metrics *Metrics) ObserveQuery(repository string, method string, duration time.Duration) {
+	metrics.queryDuration.WithLabelValues(repository, method).Observe(duration.Seconds())
+}
+
+// RecordEvent counts a published domain event; it is an events.Handler for
+// the bus.
+func (metrics *Metrics) RecordEvent(ctx context.Context, event model.Event) error {
+	metrics.events.WithLabelValues(event.Type).Inc()
+	return nil
+}
+
+// RecordOrderCreated counts a committed order and its value; it is the
+// repository.OrderRepository OnOrderCreated function.
+func (metrics *Metrics) RecordOrderCreated(ctx context.Context, order model.Order) {
+	value := 0.0
+	for _, item := range order.OrderItems {
+		value += float64(item.Quantity) * item.Price
+	}
+	metrics.ordersCreated.Inc()
+	metrics.orderValue.Add(value)
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"api/repository"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ActorHeader names the request header identifying who performs a write.
+const ActorHeader = "X-Actor"
+
+// Actor stores the caller named in the X-Actor header on the request context
+// so repositories can record it in the audit log.
+func Actor() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if actor := c.Get(ActorHeader); actor != "" {
+			c.SetUserContext(repository.WithActor(c.UserContext(), actor))
+		}
+		return c.Next()
+	}
+}
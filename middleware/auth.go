@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"api/auth"
+	"api/repository"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// APIKeyHeader carries an API key; keys are also accepted as bearer tokens.
+const APIKeyHeader = "X-API-Key"
+
+// Authenticate identifies the caller from an "Authorization: Bearer" JWT or
+// API key, or an X-API-Key header, and stores the principal on the request
+// context. The principal also becomes the audit log actor, overriding
+// X-Actor. Requests without credentials are rejected with 401 when required
+// is set and proceed anonymously otherwise, with the anonymous permissions
+// of authenticator; invalid credentials are always rejected.
+func Authenticate(authenticator *auth.Authenticator, required bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		credential := c.Get(APIKeyHeader)
+		isAPIKey := credential != ""
+		if !isAPIKey {
+			credential = bearerToken(c)
+			isAPIKey = auth.IsAPIKey(credential)
+		}
+		if credential == "" {
+			if required {
+				return unauthorized(c, "Authentication required")
+			}
+			c.SetUserContext(authenticator.Anonymous(c.UserContext()))
+			return c.Next()
+		}
+
+		ctx := c.UserContext()
+		authenticate := authenticator.AuthenticateToken
+		if isAPIKey {
+			authenticate = authenticator.AuthenticateAPIKey
+		}
+		principal, err := authenticate(ctx, credential)
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			slog.InfoContext(ctx, "authentication failed", "error", err)
+			return unauthorized(c, "Invalid credentials")
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to authenticate", "error", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to authenticate",
+			})
+		}
+
+		ctx = auth.WithPrincipal(ctx, principal)
+		c.SetUserContext(repository.WithActor(ctx, auth.Actor(principal)))
+		return c.Next()
+	}
+}
+
+// bearerToken returns the credential of an "Authorization: Bearer" header.
+func bearerToken(c *fiber.Ctx) string {
+	scheme, token, ok := strings.Cut(c.Get(fiber.HeaderAuthorization), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+func unauthorized(c *fiber.Ctx, message string) error {
+	c.Set(fiber.HeaderWWWAuthenticate, "Bearer")
+	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+		"error": message,
+	})
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"api/auth"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Require lets the request through only when the caller holds permission:
+// anonymous callers lacking it get 401 and authenticated callers 403.
+func Require(permission string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		if auth.HasPermission(ctx, permission) {
+			return c.Next()
+		}
+		if _, ok := auth.PrincipalFromContext(ctx); !ok {
+			return unauthorized(c, "Authentication required")
+		}
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Missing permission " + permission,
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"api/config"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Deprecation marks the routes of a deprecated API version mounted at
+// prefix with Deprecation and Sunset headers and a Link to the same path
+// under successor, and answers 410 Gone from the sunset date on.
+func Deprecation(deprecation config.Deprecation, prefix string, successor string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		link := successor + strings.TrimPrefix(c.Path(), prefix)
+		c.Set("Deprecation", fmt.Sprintf("@%d", deprecation.Date.Unix()))
+		c.Append(fiber.HeaderLink, fmt.Sprintf(`<%s>; rel="successor-version"`, link))
+		if deprecation.Sunset.IsZero() {
+			return c.Next()
+		}
+		c.Set("Sunset", deprecation.Sunset.UTC().Format(http.TimeFormat))
+		if !time.Now().Before(deprecation.Sunset) {
+			return c.Status(fiber.StatusGone).JSON(fiber.Map{
+				"error": "This API version was retired on " + deprecation.Sunset.Format(time.DateOnly) + ", use " + link,
+			})
+		}
+		return c.Next()
+	}
+}
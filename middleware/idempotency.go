@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"api/auth"
+	"api/repository"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+	DefaultIdempotencyTTL    = 24 * time.Hour
+)
+
+// Idempotency makes POST requests carrying an Idempotency-Key header safe to
+// retry. The first response is stored for ttl and replayed for later requests
+// with the same key; reusing a key with a different body is rejected with 422.
+// Server errors are not stored, so the request can be retried, and neither
+// are responses marked "Cache-Control: no-store", such as those carrying a
+// secret, which must not be persisted in plaintext. Keys sent by
+// authenticated callers are scoped to the caller, so that one user cannot
+// replay another's response.
+func Idempotency(idempotencyRepository *repository.IdempotencyRepository, ttl time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(IdempotencyKeyHeader)
+		if c.Method() != fiber.MethodPost || key == "" {
+			return c.Next()
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Idempotency-Key is too long",
+			})
+		}
+
+		if principal, ok := auth.PrincipalFromContext(c.UserContext()); ok {
+			key = auth.Actor(principal) + ":" + key
+		}
+		method := c.Method()
+		path := c.Path()
+		hash := sha256.Sum256(c.Body())
+		requestHash := hex.EncodeToString(hash[:])
+
+		existing, err := idempotencyRepository.ReserveIdempotencyKey(c.UserContext(), key, method, path, requestHash, ttl)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to process Idempotency-Key",
+			})
+		}
+		if existing != nil {
+			if existing.RequestHash != requestHash {
+				return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+					"error": "Idempotency-Key was already used with a different request body",
+				})
+			}
+			if existing.Status == nil {
+				return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+					"error": "A request with this Idempotency-Key is still in progress",
+				})
+			}
+			c.Set(IdempotentReplayedHeader, "true")
+			if existing.ContentType != "" {
+				c.Set(fiber.HeaderContentType, existing.ContentType)
+			}
+			return c.Status(*existing.Status).Send(existing.Response)
+		}
+
+		if err := c.Next(); err != nil {
+			idempotencyRepository.ReleaseIdempotencyKey(c.UserContext(), key, method, path)
+			return err
+		}
+
+		status := c.Response().StatusCode()
+		if status >= fiber.StatusInternalServerError || noStore(c) {
+			idempotencyRepository.ReleaseIdempotencyKey(c.UserContext(), key, method, path)
+			return nil
+		}
+
+		err = idempotencyRepository.CompleteIdempotencyKey(c.UserContext(), key, method, path, status, string(c.Response().Header.ContentType()), c.Response().Body())
+		if err != nil {
+			idempotencyRepository.ReleaseIdempotencyKey(c.UserContext(), key, method, path)
+		}
+		return nil
+	}
+}
+
+// noStore reports whether the response forbids storing it.
+func noStore(c *fiber.Ctx) bool {
+	for _, directive := range strings.Split(string(c.Response().Header.Peek(fiber.HeaderCacheControl)), ",") {
+		if strings.EqualFold(strings.TrimSpace(directive), "no-store") {
+			return true
+		}
+	}
+	return false
+}
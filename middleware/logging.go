@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"api/logging"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// RequestIDHeader carries the request ID in both directions.
+	RequestIDHeader       = "X-Request-ID"
+	maxRequestIDLength    = 128
+	generatedRequestIDLen = 16
+)
+
+// RequestID keeps the caller's X-Request-ID, or generates one when it is
+// missing or unusable, echoes it in the response and stores it on the request
+// context so that every log line written for the request carries it.
+func RequestID() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		} else {
+			id = strings.Clone(id)
+		}
+		c.Set(RequestIDHeader, id)
+		c.SetUserContext(logging.WithRequestID(c.UserContext(), id))
+		return c.Next()
+	}
+}
+
+// validRequestID accepts printable ASCII without spaces, so that IDs can be
+// logged and echoed as a header safely.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	id := make([]byte, generatedRequestIDLen)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// AccessLog writes one record per request, at error level for server errors.
+func AccessLog() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		started := time.Now()
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			}
+		}
+		level := slog.LevelInfo
+		if status >= fiber.StatusInternalServerError {
+			level = slog.LevelError
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Method()),
+			slog.String("path", c.Path()),
+			slog.Int("status", status),
+			slog.Float64("duration_ms", float64(time.Since(started).Microseconds())/1000),
+			slog.String("ip", c.IP()),
+		}
+		if err != nil {
+			attrs = append(attrs, slog.String("error", err.Error()))
+		}
+		slog.LogAttrs(c.UserContext(), level, "request", attrs...)
+		return err
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"api/metrics"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Metrics records the count and latency of every request by route pattern
+// and final status, including errors turned into responses by the app's
+// error handler.
+func Metrics(metrics *metrics.Metrics) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		started := time.Now()
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			}
+		}
+		// Fiber reuses the method's backing buffer; labels must own their strings
+		metrics.ObserveRequest(strings.Clone(c.Method()), c.Route().Path, status, time.Since(started))
+		return err
+	}
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"api/auth"
+	"api/ratelimit"
+	"api/tenant"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// rateLimitLocal holds the tightest rate limit result of the request so far.
+type rateLimitLocal struct{}
+
+// RateLimitIP throttles requests per client IP. It runs ahead of
+// authentication so that floods of invalid credentials are throttled too.
+func RateLimitIP(limiter *ratelimit.Limiter) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if rateLimited(c, limiter.TakeIP(c.UserContext(), c.IP())) {
+			return tooManyRequests(c, "Rate limit exceeded")
+		}
+		return c.Next()
+	}
+}
+
+// RateLimit throttles the authenticated caller, overall and per route group,
+// and enforces the daily quota of the API key used. Anonymous callers are
+// throttled by IP. Responses carry RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset headers for the tightest bucket; rejections are 429 with
+// Retry-After.
+func RateLimit(limiter *ratelimit.Limiter) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		principal, authenticated := auth.PrincipalFromContext(ctx)
+		caller := "ip:" + c.IP()
+		if authenticated {
+			caller = "principal:" + auth.Actor(principal)
+			if principal.KeyID != "" {
+				caller = "key:" + principal.KeyID
+			}
+			if id := tenant.FromContext(ctx); id != "" {
+				caller = id + "/" + caller
+			}
+		}
+		if rateLimited(c, limiter.TakeCaller(ctx, caller, routeGroup(c.Path()))) {
+			return tooManyRequests(c, "Rate limit exceeded")
+		}
+
+		if authenticated && principal.KeyID != "" {
+			now := time.Now()
+			quota := limiter.TakeQuota(ctx, principal, now)
+			if !quota.Allowed {
+				c.Set(fiber.HeaderRetryAfter, strconv.Itoa(ceilSeconds(quota.Reset.Sub(now))))
+				return tooManyRequests(c, "Daily quota of "+strconv.Itoa(quota.Limit)+" requests exceeded")
+			}
+		}
+		return c.Next()
+	}
+}
+
+// rateLimited sets the rate limit headers from result or an earlier, tighter
+// result of the same request, and reports whether the request is rejected.
+func rateLimited(c *fiber.Ctx, result ratelimit.Result) bool {
+	if previous, ok := c.Locals(rateLimitLocal{}).(ratelimit.Result); ok {
+		result = previous.Tighter(result)
+	}
+	c.Locals(rateLimitLocal{}, result)
+	c.Set("RateLimit-Limit", strconv.Itoa(result.Limit.Burst))
+	c.Set("RateLimit-Remaining", strconv.Itoa(int(result.Remaining)))
+	c.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset())))
+	if result.Allowed {
+		return false
+	}
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(max(1, ceilSeconds(result.RetryAfter()))))
+	return true
+}
+
+func tooManyRequests(c *fiber.Ctx, message string) error {
+	return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"error": message,
+	})
+}
+
+// routeGroup returns the first segment of path after the API version, e.g.
+// "orders" for /orders/1, /v2/orders/1 and /orders:batch.
+func routeGroup(path string) string {
+	segment, rest, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if apiVersion.MatchString(segment) {
+		segment, _, _ = strings.Cut(rest, "/")
+	}
+	segment, _, _ = strings.Cut(segment, ":")
+	return segment
+}
+
+var apiVersion = regexp.MustCompile(`^v[0-9]+$`)
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"api/auth"
+	"api/tenant"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Tenant selects the tenant a request is for by its Host header or, failing
+// that, the tenant claim of its bearer JWT, and scopes the request context
+// to the tenant's database. Requests for no tenant use the main database;
+// tokens naming a tenant that does not exist are rejected with 404.
+func Tenant(manager *tenant.Manager, authenticator *auth.Authenticator) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, ok := manager.Resolve(c.Hostname())
+		if !ok {
+			if token := bearerToken(c); token != "" && !auth.IsAPIKey(token) {
+				id = authenticator.TokenTenant(token)
+			}
+		}
+		if id == "" {
+			return c.Next()
+		}
+
+		ctx, err := manager.Context(c.UserContext(), id)
+		if errors.Is(err, tenant.ErrUnknownTenant) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Unknown tenant",
+			})
+		}
+		if err != nil {
+			return err
+		}
+		c.SetUserContext(ctx)
+		return c.Next()
+	}
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing wraps every request in a server span, continuing the trace of an
+// incoming traceparent header and returning the span's own traceparent on the
+// response. Handlers that pass c.UserContext() on get child spans.
+func Tracing() fiber.Handler {
+	tracer := otel.Tracer("api/middleware")
+	return func(c *fiber.Ctx) error {
+		// Fiber reuses request buffers; span attributes must own their strings
+		method := strings.Clone(c.Method())
+		ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), requestCarrier{c})
+		ctx, span := tracer.Start(ctx, method, trace.WithSpanKind(trace.SpanKindServer))
+		defer span.End()
+		c.SetUserContext(ctx)
+		otel.GetTextMapPropagator().Inject(ctx, responseCarrier{c})
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			}
+			span.RecordError(err)
+		}
+		route := c.Route().Path
+		span.SetName(method + " " + route)
+		span.SetAttributes(
+			attribute.String("http.request.method", method),
+			attribute.String("http.route", route),
+			attribute.String("url.path", strings.Clone(c.Path())),
+			attribute.Int("http.response.status_code", status),
+		)
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, "")
+		}
+		return err
+	}
+}
+
+// requestCarrier reads propagation headers from the request.
+type requestCarrier struct {
+	c *fiber.Ctx
+}
+
+func (carrier requestCarrier) Get(key string) string {
+	return strings.Clone(carrier.c.Get(key))
+}
+
+func (carrier requestCarrier) Set(key string, value string) {
+	carrier.c.Request().Header.Set(key, value)
+}
+
+func (carrier requestCarrier) Keys() []string {
+	keys := []string{}
+	for key := range carrier.c.GetReqHeaders() {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// responseCarrier writes propagation headers to the response.
+type responseCarrier struct {
+	c *fiber.Ctx
+}
+
+func (carrier responseCarrier) Get(key string) string {
+	return string(carrier.c.Response().Header.Peek(key))
+}
+
+func (carrier responseCarrier) Set(key string, value string) {
+	carrier.c.Set(key, value)
+}
+
+func (carrier responseCarrier) Keys() []string {
+	return nil
+}
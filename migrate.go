@@ -0,0 +1,103 @@
+package main
+
+import (
+	"api/database"
+	"errors"
+	"fmt"
+	"strconv"
+
+	migrate "github.com/golang-migrate/migrate/v4"
+)
+
+// migrateCommand runs "migrate up", "migrate down [N]" (one step by default,
+// or "all"), "migrate status", "migrate goto V" and "migrate force V", which
+// sets the version without running migrations to recover from a dirty state.
+func migrateCommand(args []string) error {
+	fs, flags := newFlagSet("migrate")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: cleango migrate [flags] up|down [N|all]|status|goto V|force V")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cfg, err := flags.Load()
+	if err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("migrate: missing action")
+	}
+	action, rest := fs.Arg(0), fs.Args()[1:]
+
+	m, err := database.NewMigrate(cfg.Database.Path, cfg.MigrationsURL())
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	switch action {
+	case "up":
+		err = m.Up()
+	case "down":
+		err = migrateDown(m, rest)
+	case "goto", "force":
+		if len(rest) != 1 {
+			return fmt.Errorf("migrate %s: expected a version", action)
+		}
+		version, parseErr := strconv.ParseUint(rest[0], 10, 31)
+		if parseErr != nil {
+			return fmt.Errorf("migrate %s: invalid version %q", action, rest[0])
+		}
+		if action == "force" {
+			err = m.Force(int(version))
+		} else {
+			err = m.Migrate(uint(version))
+		}
+	case "status":
+		return migrateStatus(m)
+	default:
+		fs.Usage()
+		return fmt.Errorf("migrate: unknown action %q", action)
+	}
+
+	if errors.Is(err, migrate.ErrNoChange) {
+		fmt.Println("no change")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return migrateStatus(m)
+}
+
+func migrateDown(m *migrate.Migrate, args []string) error {
+	if len(args) == 0 {
+		return m.Steps(-1)
+	}
+	if args[0] == "all" {
+		return m.Down()
+	}
+	steps, err := strconv.Atoi(args[0])
+	if err != nil || steps < 1 {
+		return fmt.Errorf("migrate down: invalid step count %q", args[0])
+	}
+	return m.Steps(-steps)
+}
+
+func migrateStatus(m *migrate.Migrate) error {
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		fmt.Println("version: none")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("version: %d\n", version)
+	if dirty {
+		fmt.Println("dirty: true (fix the schema, then run \"migrate force V\")")
+	}
+	return nil
+}
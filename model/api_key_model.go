@@ -0,0 +1,38 @@
+package model
+
+// APIKey describes a key without its secret, which is only stored hashed.
+type APIKey struct {
+	ID     string `json:"id"`
+	UserID string `json:"user_id"`
+	Name   string `json:"name"`
+	// Prefix is the start of the key, shown to tell keys apart.
+	Prefix     string  `json:"prefix"`
+	CreatedAt  string  `json:"created_at"`
+	ExpiresAt  *string `json:"expires_at"`
+	LastUsedAt *string `json:"last_used_at"`
+	RevokedAt  *string `json:"revoked_at"`
+	// DailyQuota caps the key's requests per UTC day, 0 meaning unlimited;
+	// null applies the server's default.
+	DailyQuota *int `json:"daily_quota"`
+}
+
+// APIKeyRequest creates a key. ExpiresIn is a Go duration such as "720h";
+// empty creates a key that does not expire.
+type APIKeyRequest struct {
+	Name       string `json:"name"`
+	ExpiresIn  string `json:"expires_in"`
+	DailyQuota *int   `json:"daily_quota"`
+}
+
+// APIKeyUsage counts the requests made with a key on a UTC day (YYYY-MM-DD).
+type APIKeyUsage struct {
+	Day      string `json:"day"`
+	Requests int    `json:"requests"`
+}
+
+// CreatedAPIKey is returned once, when the key is created; the secret cannot
+// be retrieved later.
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
@@ -0,0 +1,15 @@
+package model
+
+import "encoding/json"
+
+type AuditEntry struct {
+	ID         int64           `json:"id"`
+	Actor      string          `json:"actor"`
+	Action     string          `json:"action"`
+	Resource   string          `json:"resource"`
+	ResourceID string          `json:"resource_id"`
+	Before     json.RawMessage `json:"before" swaggertype:"object"`
+	After      json.RawMessage `json:"after" swaggertype:"object"`
+	Diff       json.RawMessage `json:"diff" swaggertype:"object"`
+	CreatedAt  string          `json:"created_at"`
+}
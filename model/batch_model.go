@@ -0,0 +1,39 @@
+package model
+
+const (
+	BatchModeAllOrNothing = "all_or_nothing"
+	BatchModeBestEffort   = "best_effort"
+
+	BatchOpCreate = "create"
+	BatchOpUpdate = "update"
+	BatchOpDelete = "delete"
+)
+
+// BatchOperation is one item of a batch request. Updates and deletes target
+// the ID in Data; deletes ignore the other fields.
+type BatchOperation[T any] struct {
+	Op   string `json:"op" enums:"create,update,delete"`
+	Data T      `json:"data"`
+}
+
+type BatchRequest[T any] struct {
+	Mode       string              `json:"mode" enums:"all_or_nothing,best_effort"`
+	Operations []BatchOperation[T] `json:"operations"`
+}
+
+// BatchResult reports the outcome of one operation, with an HTTP status code
+// as if it had been sent on its own.
+type BatchResult struct {
+	Index  int    `json:"index"`
+	Op     string `json:"op"`
+	ID     string `json:"id"`
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type BatchResponse struct {
+	Mode      string        `json:"mode"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Results   []BatchResult `json:"results"`
+}
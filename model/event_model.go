@@ -0,0 +1,33 @@
+package model
+
+import "encoding/json"
+
+const (
+	EventProductCreated      = "product.created"
+	EventProductUpdated      = "product.updated"
+	EventProductPriceChanged = "product.price_changed"
+	EventProductDeleted      = "product.deleted"
+	EventCustomerCreated     = "customer.created"
+	EventCustomerUpdated     = "customer.updated"
+	EventCustomerDeleted     = "customer.deleted"
+	EventOrderCreated        = "order.created"
+	EventOrderUpdated        = "order.updated"
+	EventOrderDeleted        = "order.deleted"
+)
+
+type Event struct {
+	ID         int64           `json:"id"`
+	Type       string          `json:"type"`
+	Resource   string          `json:"resource"`
+	ResourceID string          `json:"resource_id"`
+	Payload    json.RawMessage `json:"payload" swaggertype:"object"`
+	OccurredAt string          `json:"occurred_at"`
+	Attempts   int             `json:"-"`
+}
+
+// EventPayload is the body of every domain event: the resource as it was
+// before and after the change. Before is null for creates, After for deletes.
+type EventPayload struct {
+	Before json.RawMessage `json:"before" swaggertype:"object"`
+	After  json.RawMessage `json:"after" swaggertype:"object"`
+}
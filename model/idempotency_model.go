@@ -0,0 +1,15 @@
+package model
+
+// IdempotencyRecord is the stored outcome of a request sent with an
+// Idempotency-Key header. Status is nil while the request is in progress.
+type IdempotencyRecord struct {
+	Key         string
+	Method      string
+	Path        string
+	RequestHash string
+	Status      *int
+	ContentType string
+	Response    []byte
+	CreatedAt   string
+	ExpiresAt   string
+}
@@ -0,0 +1,19 @@
+package model
+
+type ImportRowError struct {
+	Line  int    `json:"line"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error"`
+}
+
+// ImportReport summarizes an import. In a dry run, Imported counts the
+// records that would have been imported.
+type ImportReport struct {
+	Format   string           `json:"format"`
+	DryRun   bool             `json:"dry_run"`
+	Atomic   bool             `json:"atomic"`
+	Rows     int              `json:"rows"`
+	Imported int              `json:"imported"`
+	Failed   int              `json:"failed"`
+	Errors   []ImportRowError `json:"errors"`
+}
@@ -0,0 +1,6 @@
+package model
+
+// LogLevel is the body of the /admin/log-level endpoints.
+type LogLevel struct {
+	Level string `json:"level"`
+}
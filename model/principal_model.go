@@ -0,0 +1,27 @@
+package model
+
+const (
+	AuthMethodJWT    = "jwt"
+	AuthMethodAPIKey = "api_key"
+
+	PrincipalTypeUser     = "user"
+	PrincipalTypeCustomer = "customer"
+)
+
+// Principal is the authenticated caller of a request: a user (staff or an
+// integration) or a customer acting on their own behalf.
+type Principal struct {
+	// Type is user or customer.
+	Type string `json:"type"`
+	// UserID is set for users and CustomerID for customers.
+	UserID     string `json:"user_id,omitempty"`
+	CustomerID string `json:"customer_id,omitempty"`
+	Name       string `json:"name"`
+	// Method is how the caller authenticated, jwt or api_key.
+	Method string `json:"method"`
+	// KeyID is the API key used, if any, and DailyQuota its quota override.
+	KeyID      string `json:"key_id,omitempty"`
+	DailyQuota *int   `json:"daily_quota,omitempty"`
+	// Permissions are granted by the user's roles.
+	Permissions []string `json:"permissions"`
+}
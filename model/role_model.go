@@ -0,0 +1,14 @@
+package model
+
+// Role is a named set of permissions such as "products:read". The
+// permission "*" grants everything and "products:*" every products permission.
+type Role struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// UserRoles is the body of PUT /admin/users/{id}/roles.
+type UserRoles struct {
+	Roles []string `json:"roles"`
+}
@@ -0,0 +1,10 @@
+package model
+
+// Tenant is a storefront served from its own database.
+type Tenant struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Host is the Host header requests for the tenant arrive with, if any.
+	Host      string `json:"host,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
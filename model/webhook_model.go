@@ -0,0 +1,33 @@
+package model
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+type WebhookSubscription struct {
+	ID                  string   `json:"id"`
+	URL                 string   `json:"url"`
+	EventTypes          []string `json:"event_types"`
+	Secret              string   `json:"secret,omitempty"`
+	Active              bool     `json:"active"`
+	ConsecutiveFailures int      `json:"consecutive_failures"`
+	DisabledAt          *string  `json:"disabled_at"`
+	CreatedAt           string   `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID             int64   `json:"id"`
+	SubscriptionID string  `json:"subscription_id"`
+	EventID        int64   `json:"event_id"`
+	EventType      string  `json:"event_type"`
+	Status         string  `json:"status"`
+	Attempts       int     `json:"attempts"`
+	ResponseStatus *int    `json:"response_status"`
+	LastError      *string `json:"last_error"`
+	ReplayOf       *int64  `json:"replay_of"`
+	NextAttemptAt  string  `json:"next_attempt_at"`
+	CreatedAt      string  `json:"created_at"`
+	DeliveredAt    *string `json:"delivered_at"`
+}
@@ -0,0 +1,153 @@
+// Package ratelimit throttles callers with token buckets and enforces the
+// daily quotas of API keys.
+package ratelimit
+
+import (
+	"api/config"
+	"api/model"
+	"api/repository"
+	"context"
+	"log/slog"
+	"maps"
+	"math"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// pruneEvery is how many takes pass between removals of idle buckets.
+const pruneEvery = 1024
+
+// Result is the state of a bucket after a request took from it.
+type Result struct {
+	Allowed bool
+	Limit   config.RateLimit
+	// Remaining is the number of tokens left.
+	Remaining float64
+}
+
+// Reset is how long until the bucket is full again.
+func (result Result) Reset() time.Duration {
+	return seconds((float64(result.Limit.Burst) - result.Remaining) / result.Limit.Rate)
+}
+
+// RetryAfter is how long until a token is available, zero when one is.
+func (result Result) RetryAfter() time.Duration {
+	return seconds((1 - result.Remaining) / result.Limit.Rate)
+}
+
+// Tighter returns whichever of the two results leaves the caller less room:
+// a denial, or else the fewest remaining tokens.
+func (result Result) Tighter(other Result) Result {
+	if result.Allowed != other.Allowed {
+		if !result.Allowed {
+			return result
+		}
+		return other
+	}
+	if other.Remaining < result.Remaining {
+		return other
+	}
+	return result
+}
+
+// Quota is the daily usage of an API key after a request.
+type Quota struct {
+	Allowed bool
+	// Limit is the number of requests allowed per UTC day; 0 is unlimited.
+	Limit int
+	Used  int
+	// Reset is the start of the next UTC day.
+	Reset time.Time
+}
+
+// Limiter applies the configured rate limits and quotas. When the store
+// fails, requests are let through rather than rejected.
+type Limiter struct {
+	store            Store
+	apiKeyRepository *repository.APIKeyRepository
+	caller           config.RateLimit
+	ip               config.RateLimit
+	routes           map[string]config.RateLimit
+	dailyQuota       int
+	// idle is how long a bucket takes to refill completely at most; buckets
+	// idle for longer are full and can be forgotten.
+	idle  time.Duration
+	takes atomic.Uint64
+}
+
+func NewLimiter(cfg config.RateLimitConfig, store Store, apiKeyRepository *repository.APIKeyRepository) (*Limiter, error) {
+	routes, err := cfg.RouteLimits()
+	if err != nil {
+		return nil, err
+	}
+	limiter := &Limiter{
+		store:            store,
+		apiKeyRepository: apiKeyRepository,
+		caller:           config.RateLimit{Rate: cfg.CallerRate, Burst: cfg.CallerBurst},
+		ip:               config.RateLimit{Rate: cfg.IPRate, Burst: cfg.IPBurst},
+		routes:           routes,
+		dailyQuota:       cfg.DailyQuota,
+	}
+	for _, limit := range append([]config.RateLimit{limiter.caller, limiter.ip}, slices.Collect(maps.Values(routes))...) {
+		limiter.idle = max(limiter.idle, seconds(float64(limit.Burst)/limit.Rate))
+	}
+	return limiter, nil
+}
+
+// TakeIP counts a request against the client IP's bucket.
+func (limiter *Limiter) TakeIP(ctx context.Context, ip string) Result {
+	return limiter.take(ctx, "ip:"+ip, limiter.ip)
+}
+
+// TakeCaller counts a request against the caller's bucket and, when the
+// route group has a limit, the caller's bucket for the group. caller must
+// identify the caller uniquely across tenants.
+func (limiter *Limiter) TakeCaller(ctx context.Context, caller string, group string) Result {
+	result := limiter.take(ctx, "caller:"+caller, limiter.caller)
+	if !result.Allowed {
+		return result
+	}
+	if limit, ok := limiter.routes[group]; ok {
+		result = result.Tighter(limiter.take(ctx, "route:"+group+":"+caller, limit))
+	}
+	return result
+}
+
+// TakeQuota counts a request made with an API key against its daily quota.
+func (limiter *Limiter) TakeQuota(ctx context.Context, principal model.Principal, now time.Time) Quota {
+	quota := Quota{Allowed: true, Limit: limiter.dailyQuota}
+	if principal.DailyQuota != nil {
+		quota.Limit = *principal.DailyQuota
+	}
+	day := now.UTC().Truncate(24 * time.Hour)
+	quota.Reset = day.Add(24 * time.Hour)
+
+	used, allowed, err := limiter.apiKeyRepository.CountAPIKeyRequest(ctx, principal.KeyID, day.Format(time.DateOnly), quota.Limit)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to count API key request", "key_id", principal.KeyID, "error", err)
+		return quota
+	}
+	quota.Used = used
+	quota.Allowed = allowed
+	return quota
+}
+
+func (limiter *Limiter) take(ctx context.Context, key string, limit config.RateLimit) Result {
+	now := time.Now()
+	if limiter.takes.Add(1)%pruneEvery == 0 {
+		if err := limiter.store.Prune(ctx, now.Add(-limiter.idle)); err != nil {
+			slog.WarnContext(ctx, "failed to prune rate limit buckets", "error", err)
+		}
+	}
+	remaining, allowed, err := limiter.store.Take(ctx, key, limit, now)
+	if err != nil {
+		slog.ErrorContext(ctx, "rate limit store failed", "error", err)
+		return Result{Allowed: true, Limit: limit, Remaining: float64(limit.Burst)}
+	}
+	return Result{Allowed: allowed, Limit: limit, Remaining: remaining}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Max(0, s) * float64(time.Second))
+}
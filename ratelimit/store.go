@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"api/config"
+	"api/repository"
+	"context"
+	"sync"
+	"time"
+)
+
+// Store keeps token buckets by key.
+type Store interface {
+	// Take removes a token from the bucket named key if one is available and
+	// returns the tokens left. Buckets start full.
+	Take(ctx context.Context, key string, limit config.RateLimit, now time.Time) (float64, bool, error)
+	// Prune forgets the buckets last used before idleSince.
+	Prune(ctx context.Context, idleSince time.Time) error
+}
+
+// MemoryStore keeps buckets in the process.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: map[string]*bucket{},
+	}
+}
+
+func (store *MemoryStore) Take(ctx context.Context, key string, limit config.RateLimit, now time.Time) (float64, bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	b, ok := store.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updatedAt: now}
+		store.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.updatedAt); elapsed > 0 {
+		b.tokens = min(float64(limit.Burst), b.tokens+elapsed.Seconds()*limit.Rate)
+		b.updatedAt = now
+	}
+	if b.tokens < 1 {
+		return b.tokens, false, nil
+	}
+	b.tokens--
+	return b.tokens, true, nil
+}
+
+func (store *MemoryStore) Prune(ctx context.Context, idleSince time.Time) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	for key, b := range store.buckets {
+		if b.updatedAt.Before(idleSince) {
+			delete(store.buckets, key)
+		}
+	}
+	return nil
+}
+
+// SQLiteStore keeps buckets in the main database, shared by every process
+// using it.
+type SQLiteStore struct {
+	rateLimitRepository *repository.RateLimitRepository
+}
+
+func NewSQLiteStore(rateLimitRepository *repository.RateLimitRepository) *SQLiteStore {
+	return &SQLiteStore{
+		rateLimitRepository: rateLimitRepository,
+	}
+}
+
+func (store *SQLiteStore) Take(ctx context.Context, key string, limit config.RateLimit, now time.Time) (float64, bool, error) {
+	return store.rateLimitRepository.TakeToken(ctx, key, limit.Rate, limit.Burst, now)
+}
+
+func (store *SQLiteStore) Prune(ctx context.Context, idleSince time.Time) error {
+	return store.rateLimitRepository.PruneBuckets(ctx, idleSince)
+}
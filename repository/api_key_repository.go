@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"api/model"
+	"context"
+	"time"
+
+	"database/sql"
+)
+
+type APIKeyRepository struct {
+	db *sql.DB
+}
+
+func NewAPIKeyRepository(db *sql.DB) *APIKeyRepository {
+	return &APIKeyRepository{
+		db: db,
+	}
+}
+
+const apiKeyColumns = "id, user_id, name, prefix, created_at, expires_at, last_used_at, revoked_at, daily_quota"
+
+func scanAPIKey(row scanner) (model.APIKey, error) {
+	var key model.APIKey
+	err := row.Scan(
+		&key.ID, &key.UserID, &key.Name, &key.Prefix,
+		&key.CreatedAt, &key.ExpiresAt, &key.LastUsedAt, &key.RevokedAt, &key.DailyQuota,
+	)
+	return key, err
+}
+
+// GetAPIKeys returns the keys of a user, including revoked and expired ones.
+func (repository *APIKeyRepository) GetAPIKeys(ctx context.Context, userID string) ([]model.APIKey, error) {
+	ctx, end := observe(ctx, "APIKeyRepository", "GetAPIKeys")
+	defer end()
+
+	var keys []model.APIKey = []model.APIKey{}
+	rows, err := database(ctx, repository.db).QueryContext(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE user_id = ? ORDER BY created_at, id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// GetAPIKeyByHash returns the key whose secret hashes to keyHash, whether or
+// not it is still usable.
+func (repository *APIKeyRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (model.APIKey, error) {
+	ctx, end := observe(ctx, "APIKeyRepository", "GetAPIKeyByHash")
+	defer end()
+
+	row := database(ctx, repository.db).QueryRowContext(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE key_hash = ?", keyHash)
+	return scanAPIKey(row)
+}
+
+// CreateAPIKey stores the key with the hash of its secret, setting its
+// creation time, and returns it.
+func (repository *APIKeyRepository) CreateAPIKey(ctx context.Context, key model.APIKey, keyHash string) (model.APIKey, error) {
+	ctx, end := observe(ctx, "APIKeyRepository", "CreateAPIKey")
+	defer end()
+
+	key.CreatedAt = formatTimestamp(time.Now())
+	_, err := database(ctx, repository.db).ExecContext(ctx, `
+		INSERT INTO api_keys (id, user_id, name, prefix, key_hash, created_at, expires_at, daily_quota)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, key.ID, key.UserID, key.Name, key.Prefix, keyHash, key.CreatedAt, key.ExpiresAt, key.DailyQuota)
+	if err != nil {
+		return key, err
+	}
+	return key, nil
+}
+
+// TouchAPIKey records that the key was used at the given time.
+func (repository *APIKeyRepository) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	ctx, end := observe(ctx, "APIKeyRepository", "TouchAPIKey")
+	defer end()
+
+	_, err := database(ctx, repository.db).ExecContext(ctx, "UPDATE api_keys SET last_used_at = ? WHERE id = ?", formatTimestamp(usedAt), id)
+	return err
+}
+
+// RevokeAPIKey revokes one of the user's keys. It returns sql.ErrNoRows when
+// the user has no such key that is not already revoked.
+func (repository *APIKeyRepository) RevokeAPIKey(ctx context.Context, userID string, id string) error {
+	ctx, end := observe(ctx, "APIKeyRepository", "RevokeAPIKey")
+	defer end()
+
+	result, err := database(ctx, repository.db).ExecContext(ctx,
+		"UPDATE api_keys SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL",
+		formatTimestamp(time.Now()), id, userID,
+	)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CountAPIKeyRequest adds a request to the key's usage on day unless quota,
+// when positive, has been reached. It returns the requests counted that day
+// and whether this one was within the quota.
+func (repository *APIKeyRepository) CountAPIKeyRequest(ctx context.Context, id string, day string, quota int) (int, bool, error) {
+	ctx, end := observe(ctx, "APIKeyRepository", "CountAPIKeyRequest")
+	defer end()
+
+	db := database(ctx, repository.db)
+	var requests int
+	err := db.QueryRowContext(ctx, `
+		INSERT INTO api_key_usage (key_id, day, requests) VALUES (?1, ?2, 1)
+		ON CONFLICT (key_id, day) DO UPDATE SET requests = requests + 1
+		WHERE ?3 <= 0 OR requests < ?3
+		RETURNING requests
+	`, id, day, quota).Scan(&requests)
+	if err == sql.ErrNoRows {
+		// Over quota: nothing was updated
+		err = db.QueryRowContext(ctx, "SELECT requests FROM api_key_usage WHERE key_id = ? AND day = ?", id, day).Scan(&requests)
+		return requests, false, err
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return requests, true, nil
+}
+
+// GetAPIKeyUsage returns the daily usage of one of the user's keys since the
+// given day, most recent first. It returns sql.ErrNoRows when the user has
+// no such key.
+func (repository *APIKeyRepository) GetAPIKeyUsage(ctx context.Context, userID string, id string, since string) ([]model.APIKeyUsage, error) {
+	ctx, end := observe(ctx, "APIKeyRepository", "GetAPIKeyUsage")
+	defer end()
+
+	db := database(ctx, repository.db)
+	var exists int
+	if err := db.QueryRowContext(ctx, "SELECT 1 FROM api_keys WHERE id = ? AND user_id = ?", id, userID).Scan(&exists); err != nil {
+		return nil, err
+	}
+
+	var usage []model.APIKeyUsage = []model.APIKeyUsage{}
+	rows, err := db.QueryContext(ctx, "SELECT day, requests FROM api_key_usage WHERE key_id = ? AND day >= ? ORDER BY day DESC", id, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var day model.APIKeyUsage
+		if err := rows.Scan(&day.Day, &day.Requests); err != nil {
+			return nil, err
+		}
+		usage = append(usage, day)
+	}
+	return usage, rows.Err()
+}
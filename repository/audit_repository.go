@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"api/model"
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"database/sql"
+)
+
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+
+	anonymousActor = "anonymous"
+)
+
+type actorContextKey struct{}
+
+// WithActor returns a copy of ctx carrying the actor recorded in the audit log.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, actor)
+}
+
+// ActorFromContext returns the actor stored in ctx, or "anonymous" when none is set.
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorContextKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return anonymousActor
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx so reads can run inside a transaction.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type AuditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{
+		db: db,
+	}
+}
+
+func (repository *AuditRepository) GetAuditEntries(ctx context.Context, resource string, resourceID string) ([]model.AuditEntry, error) {
+	ctx, end := observe(ctx, "AuditRepository", "GetAuditEntries")
+	defer end()
+
+	var entries []model.AuditEntry = []model.AuditEntry{}
+	rows, err := database(ctx, repository.db).QueryContext(ctx, `
+		SELECT id, actor, action, resource, resource_id, before, after, diff, created_at
+		FROM audit_log
+		WHERE (? = '' OR resource = ?) AND (? = '' OR resource_id = ?)
+		ORDER BY id
+	`, resource, resource, resourceID, resourceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry model.AuditEntry
+		var before, after, diff sql.NullString
+		err := rows.Scan(
+			&entry.ID, &entry.Actor, &entry.Action, &entry.Resource, &entry.ResourceID,
+			&before, &after, &diff, &entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entry.Before = rawJSON(before)
+		entry.After = rawJSON(after)
+		entry.Diff = rawJSON(diff)
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// writeAuditEntry appends a row to audit_log within tx. An invalid before or
+// after snapshot is stored as NULL, as for creates and deletes respectively.
+func writeAuditEntry(ctx context.Context, tx *sql.Tx, action string, resource string, resourceID string, before sql.NullString, after sql.NullString) error {
+	changes, err := diffJSON(before, after)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO audit_log (actor, action, resource, resource_id, before, after, diff, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, ActorFromContext(ctx), action, resource, resourceID, before, after, changes, formatTimestamp(time.Now()))
+	return err
+}
+
+// lastChange returns when the records of resource, or only the one with
+// resourceID when it is not empty, were last written. Every write records an
+// audit entry in its transaction, deletes included, so this holds for
+// writes by other processes too. It is zero when nothing was recorded.
+func lastChange(ctx context.Context, q queryer, resource string, resourceID string) (time.Time, error) {
+	var changedAt sql.NullString
+	err := q.QueryRowContext(ctx, `
+		SELECT MAX(created_at) FROM audit_log
+		WHERE resource = ? AND (? = '' OR resource_id = ?)
+	`, resource, resourceID, resourceID).Scan(&changedAt)
+	if err != nil || !changedAt.Valid {
+		return time.Time{}, err
+	}
+	return time.Parse(timestampLayout, changedAt.String)
+}
+
+func snapshotJSON(snapshot any) (sql.NullString, error) {
+	if snapshot == nil {
+		return sql.NullString{}, nil
+	}
+	if value := reflect.ValueOf(snapshot); value.Kind() == reflect.Pointer && value.IsNil() {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// diffJSON returns the top-level fields that differ between the two snapshots
+// as {"field": {"before": ..., "after": ...}}.
+func diffJSON(before sql.NullString, after sql.NullString) (sql.NullString, error) {
+	beforeFields := map[string]any{}
+	afterFields := map[string]any{}
+	if before.Valid {
+		if err := json.Unmarshal([]byte(before.String), &beforeFields); err != nil {
+			return sql.NullString{}, err
+		}
+	}
+	if after.Valid {
+		if err := json.Unmarshal([]byte(after.String), &afterFields); err != nil {
+			return sql.NullString{}, err
+		}
+	}
+
+	diff := map[string]map[string]any{}
+	for field, value := range beforeFields {
+		if !reflect.DeepEqual(value, afterFields[field]) {
+			diff[field] = map[string]any{"before": value, "after": afterFields[field]}
+		}
+	}
+	for field, value := range afterFields {
+		if _, ok := beforeFields[field]; !ok {
+			diff[field] = map[string]any{"before": nil, "after": value}
+		}
+	}
+
+	return snapshotJSON(diff)
+}
+
+func rawJSON(value sql.NullString) json.RawMessage {
+	if !value.Valid {
+		return nil
+	}
+	return json.RawMessage(value.String)
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"database/sql"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+var (
+	// ErrBatchRolledBack is reported for operations of an all-or-nothing batch
+	// that were not applied because another operation failed.
+	ErrBatchRolledBack = errors.New("not applied: batch was rolled back")
+)
+
+// IsConflict reports whether err is a constraint violation, such as a duplicate ID.
+func IsConflict(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrConstraint
+}
+
+// BatchOptions control how a batch handles failures.
+type BatchOptions struct {
+	// BestEffort applies every operation that succeeds instead of rolling
+	// back the whole batch on the first failure.
+	BestEffort bool
+	// DryRun rolls the transaction back at the end, reporting what would happen.
+	DryRun bool
+	// KeepPrices refuses product operations that set or change a price.
+	KeepPrices bool
+}
+
+// batchApplier applies the i-th operation of a batch within the transaction it was prepared for.
+type batchApplier func(i int) error
+
+// runBatch applies count operations in a single transaction. prepare is called
+// once with the transaction to set up prepared statements and returns the
+// function applying each operation along with a cleanup function.
+//
+// In best-effort mode every operation runs in its own savepoint, so a failure
+// only undoes that operation. Otherwise the first failure rolls back the whole
+// batch and every other operation reports ErrBatchRolledBack. The returned
+// slice holds the error of each operation, nil on success. A dry run reports
+// the same errors but never commits.
+func runBatch(ctx context.Context, db *sql.DB, count int, options BatchOptions, prepare func(tx *sql.Tx) (batchApplier, func(), error)) ([]error, error) {
+	errs := make([]error, count)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	apply, cleanup, err := prepare(tx)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	defer cleanup()
+
+	for i := 0; i < count; i++ {
+		if options.BestEffort {
+			_, err = tx.ExecContext(ctx, "SAVEPOINT batch_item")
+			if err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		}
+
+		itemErr := apply(i)
+		if itemErr != nil && !options.BestEffort {
+			tx.Rollback()
+			for j := range errs {
+				errs[j] = ErrBatchRolledBack
+			}
+			errs[i] = itemErr
+			return errs, nil
+		}
+
+		if options.BestEffort {
+			if itemErr != nil {
+				errs[i] = itemErr
+				_, err = tx.ExecContext(ctx, "ROLLBACK TO batch_item")
+				if err != nil {
+					tx.Rollback()
+					return nil, err
+				}
+			}
+			_, err = tx.ExecContext(ctx, "RELEASE batch_item")
+			if err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		}
+	}
+
+	if options.DryRun {
+		tx.Rollback()
+		return errs, nil
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return errs, nil
+}
+
+// prepareAll prepares every query on tx and returns a function closing them.
+func prepareAll(ctx context.Context, tx *sql.Tx, queries ...string) ([]*sql.Stmt, func(), error) {
+	stmts := make([]*sql.Stmt, 0, len(queries))
+	closeAll := func() {
+		for _, stmt := range stmts {
+			stmt.Close()
+		}
+	}
+	for _, query := range queries {
+		stmt, err := tx.PrepareContext(ctx, query)
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		stmts = append(stmts, stmt)
+	}
+	return stmts, closeAll, nil
+}
+
+func unknownBatchOp(op string) error {
+	return fmt.Errorf("unknown batch operation %q", op)
+}
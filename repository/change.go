@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"api/model"
+	"context"
+	"time"
+
+	"database/sql"
+)
+
+// timestampLayout is fixed-width so stored timestamps sort lexicographically.
+const timestampLayout = "2006-01-02T15:04:05.000000Z07:00"
+
+func formatTimestamp(t time.Time) string {
+	return t.UTC().Format(timestampLayout)
+}
+
+var eventTypes = map[string]map[string]string{
+	productResource: {
+		AuditActionCreate: model.EventProductCreated,
+		AuditActionUpdate: model.EventProductUpdated,
+		AuditActionDelete: model.EventProductDeleted,
+	},
+	customerResource: {
+		AuditActionCreate: model.EventCustomerCreated,
+		AuditActionUpdate: model.EventCustomerUpdated,
+		AuditActionDelete: model.EventCustomerDeleted,
+	},
+	orderResource: {
+		AuditActionCreate: model.EventOrderCreated,
+		AuditActionUpdate: model.EventOrderUpdated,
+		AuditActionDelete: model.EventOrderDeleted,
+	},
+}
+
+// recordChange writes the audit entry and the domain events for a single write
+// within tx, so they commit or roll back together with the change itself.
+// extraEvents are emitted in addition to the resource's created/updated/deleted event.
+func recordChange(ctx context.Context, tx *sql.Tx, action string, resource string, resourceID string, before any, after any, extraEvents ...string) error {
+	beforeJSON, err := snapshotJSON(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := snapshotJSON(after)
+	if err != nil {
+		return err
+	}
+
+	err = writeAuditEntry(ctx, tx, action, resource, resourceID, beforeJSON, afterJSON)
+	if err != nil {
+		return err
+	}
+
+	for _, eventType := range append([]string{eventTypes[resource][action]}, extraEvents...) {
+		err = writeOutboxEvent(ctx, tx, eventType, resource, resourceID, beforeJSON, afterJSON)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -2,12 +2,17 @@ package repository
 
 import (
 	"api/model"
+	"context"
+	"time"
 
 	"database/sql"
 )
 
+const customerResource = "customers"
+
 type CustomerRepository struct {
-	db *sql.DB
+	db    *sql.DB
+	reads *readCache[model.Customer]
 }
 
 func NewCustomerRepository(db *sql.DB) *CustomerRepository {
@@ -16,9 +21,19 @@ func NewCustomerRepository(db *sql.DB) *CustomerRepository {
 	}
 }
 
-func (repository *CustomerRepository) GetCustomers() ([]model.Customer, error) {
+// EnableCache makes GetCustomersCached and GetCustomerByIDCached keep their results
+// for ttl, keeping at most maxEntries lists and maxEntries customers across all
+// databases. Writes through the repository empty the cache. Call it before use.
+func (repository *CustomerRepository) EnableCache(ttl time.Duration, maxEntries int) {
+	repository.reads = newReadCache[model.Customer](ttl, maxEntries)
+}
+
+func (repository *CustomerRepository) GetCustomers(ctx context.Context) ([]model.Customer, error) {
+	ctx, end := observe(ctx, "CustomerRepository", "GetCustomers")
+	defer end()
+
 	var customers []model.Customer = []model.Customer{}
-	rows, err := repository.db.Query("SELECT id, name FROM customers")
+	rows, err := database(ctx, repository.db).QueryContext(ctx, "SELECT id, name FROM customers")
 	if err != nil {
 		return nil, err
 	}
@@ -36,36 +51,292 @@ func (repository *CustomerRepository) GetCustomers() ([]model.Customer, error) {
 	return customers, nil
 }
 
-func (repository *CustomerRepository) GetCustomerByID(id string) (model.Customer, error) {
-	var customer model.Customer
-	row := repository.db.QueryRow("SELECT id, name FROM customers WHERE id = ?", id)
-	err := row.Scan(&customer.ID, &customer.Name)
+// CustomerFilter restricts the customers returned by ListCustomers; zero
+// fields match every customer.
+type CustomerFilter struct {
+	NameContains string
+}
+
+// ListCustomers returns a page of the customers matching filter.
+func (repository *CustomerRepository) ListCustomers(ctx context.Context, filter CustomerFilter, page Page) ([]model.Customer, error) {
+	ctx, end := observe(ctx, "CustomerRepository", "ListCustomers")
+	defer end()
+
+	var w where
+	if filter.NameContains != "" {
+		w.contains("name", filter.NameContains)
+	}
+	clause := w.paginate("id", page)
+	return queryCustomers(ctx, database(ctx, repository.db), clause, w.args...)
+}
+
+// GetCustomersByIDs returns the customers with the given IDs that exist, in
+// no particular order.
+func (repository *CustomerRepository) GetCustomersByIDs(ctx context.Context, ids []string) ([]model.Customer, error) {
+	ctx, end := observe(ctx, "CustomerRepository", "GetCustomersByIDs")
+	defer end()
+
+	if len(ids) == 0 {
+		return []model.Customer{}, nil
+	}
+	var w where
+	w.in("id", ids)
+	return queryCustomers(ctx, database(ctx, repository.db), w.String(), w.args...)
+}
+
+func queryCustomers(ctx context.Context, q queryer, clause string, args ...any) ([]model.Customer, error) {
+	var customers []model.Customer = []model.Customer{}
+	rows, err := q.QueryContext(ctx, "SELECT id, name FROM customers "+clause, args...)
 	if err != nil {
-		return customer, err
+		return nil, err
 	}
-	return customer, nil
+	defer rows.Close()
+
+	for rows.Next() {
+		var customer model.Customer
+		err := rows.Scan(&customer.ID, &customer.Name)
+		if err != nil {
+			return nil, err
+		}
+		customers = append(customers, customer)
+	}
+
+	return customers, rows.Err()
 }
 
-func (repository *CustomerRepository) CreateCustomer(customer model.Customer) error {
-	_, err := repository.db.Exec("INSERT INTO customers (id, name) VALUES (?, ?)", customer.ID, customer.Name)
+// EachCustomer calls fn for every customer as rows are read, stopping at the first error.
+func (repository *CustomerRepository) EachCustomer(ctx context.Context, fn func(model.Customer) error) error {
+	ctx, end := observe(ctx, "CustomerRepository", "EachCustomer")
+	defer end()
+
+	rows, err := database(ctx, repository.db).QueryContext(ctx, "SELECT id, name FROM customers ORDER BY id")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var customer model.Customer
+		err := rows.Scan(&customer.ID, &customer.Name)
+		if err != nil {
+			return err
+		}
+		if err := fn(customer); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func (repository *CustomerRepository) GetCustomerByID(ctx context.Context, id string) (model.Customer, error) {
+	ctx, end := observe(ctx, "CustomerRepository", "GetCustomerByID")
+	defer end()
+
+	return getCustomerByID(ctx, database(ctx, repository.db), id)
+}
+
+// GetCustomersCached is GetCustomers served from the cache when enabled, along with
+// when customers were last written, which is zero when unknown.
+func (repository *CustomerRepository) GetCustomersCached(ctx context.Context) ([]model.Customer, time.Time, error) {
+	return repository.reads.getList(ctx, repository.db, customerResource, func() ([]model.Customer, error) {
+		return repository.GetCustomers(ctx)
+	})
+}
+
+// GetCustomerByIDCached is GetCustomerByID served from the cache when enabled, along
+// with when the customer was last written, which is zero when unknown.
+func (repository *CustomerRepository) GetCustomerByIDCached(ctx context.Context, id string) (model.Customer, time.Time, error) {
+	return repository.reads.getItem(ctx, repository.db, customerResource, id, func() (model.Customer, error) {
+		return repository.GetCustomerByID(ctx, id)
+	})
+}
+
+func (repository *CustomerRepository) CreateCustomer(ctx context.Context, customer model.Customer) error {
+	ctx, end := observe(ctx, "CustomerRepository", "CreateCustomer")
+	defer end()
+	defer repository.reads.clear()
+
+	tx, err := database(ctx, repository.db).BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO customers (id, name) VALUES (?, ?)", customer.ID, customer.Name)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = recordChange(ctx, tx, AuditActionCreate, customerResource, customer.ID, nil, &customer)
 	if err != nil {
+		tx.Rollback()
 		return err
 	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
-func (repository *CustomerRepository) UpdateCustomer(customer model.Customer) error {
-	_, err := repository.db.Exec("UPDATE customers SET name = ? WHERE id = ?", customer.Name, customer.ID)
+func (repository *CustomerRepository) UpdateCustomer(ctx context.Context, customer model.Customer) error {
+	ctx, end := observe(ctx, "CustomerRepository", "UpdateCustomer")
+	defer end()
+	defer repository.reads.clear()
+
+	tx, err := database(ctx, repository.db).BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	before, err := findCustomer(ctx, tx, customer.ID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE customers SET name = ? WHERE id = ?", customer.Name, customer.ID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if before != nil {
+		err = recordChange(ctx, tx, AuditActionUpdate, customerResource, customer.ID, before, &customer)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	err = tx.Commit()
 	if err != nil {
 		return err
 	}
+
 	return nil
 }
 
-func (repository *CustomerRepository) DeleteCustomer(id string) error {
-	_, err := repository.db.Exec("DELETE FROM customers WHERE id = ?", id)
+func (repository *CustomerRepository) DeleteCustomer(ctx context.Context, id string) error {
+	ctx, end := observe(ctx, "CustomerRepository", "DeleteCustomer")
+	defer end()
+	defer repository.reads.clear()
+
+	tx, err := database(ctx, repository.db).BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	before, err := findCustomer(ctx, tx, id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM customers WHERE id = ?", id)
 	if err != nil {
+		tx.Rollback()
 		return err
 	}
+
+	if before != nil {
+		err = recordChange(ctx, tx, AuditActionDelete, customerResource, id, before, nil)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
+
+// ApplyCustomerBatch applies the operations in a single transaction. See runBatch
+// for how the options affect failures.
+func (repository *CustomerRepository) ApplyCustomerBatch(ctx context.Context, operations []model.BatchOperation[model.Customer], options BatchOptions) ([]error, error) {
+	ctx, end := observe(ctx, "CustomerRepository", "ApplyCustomerBatch")
+	defer end()
+	defer repository.reads.clear()
+
+	return runBatch(ctx, database(ctx, repository.db), len(operations), options, func(tx *sql.Tx) (batchApplier, func(), error) {
+		stmts, cleanup, err := prepareAll(ctx, tx,
+			"INSERT INTO customers (id, name) VALUES (?, ?)",
+			"UPDATE customers SET name = ? WHERE id = ?",
+			"DELETE FROM customers WHERE id = ?",
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		insertStmt, updateStmt, deleteStmt := stmts[0], stmts[1], stmts[2]
+
+		apply := func(i int) error {
+			customer := operations[i].Data
+			switch operations[i].Op {
+			case model.BatchOpCreate:
+				_, err := insertStmt.ExecContext(ctx, customer.ID, customer.Name)
+				if err != nil {
+					return err
+				}
+				return recordChange(ctx, tx, AuditActionCreate, customerResource, customer.ID, nil, &customer)
+			case model.BatchOpUpdate:
+				before, err := findCustomer(ctx, tx, customer.ID)
+				if err != nil {
+					return err
+				}
+				if before == nil {
+					return sql.ErrNoRows
+				}
+				_, err = updateStmt.ExecContext(ctx, customer.Name, customer.ID)
+				if err != nil {
+					return err
+				}
+				return recordChange(ctx, tx, AuditActionUpdate, customerResource, customer.ID, before, &customer)
+			case model.BatchOpDelete:
+				before, err := findCustomer(ctx, tx, customer.ID)
+				if err != nil {
+					return err
+				}
+				if before == nil {
+					return sql.ErrNoRows
+				}
+				_, err = deleteStmt.ExecContext(ctx, customer.ID)
+				if err != nil {
+					return err
+				}
+				return recordChange(ctx, tx, AuditActionDelete, customerResource, customer.ID, before, nil)
+			}
+			return unknownBatchOp(operations[i].Op)
+		}
+
+		return apply, cleanup, nil
+	})
+}
+
+func getCustomerByID(ctx context.Context, q queryer, id string) (model.Customer, error) {
+	var customer model.Customer
+	row := q.QueryRowContext(ctx, "SELECT id, name FROM customers WHERE id = ?", id)
+	err := row.Scan(&customer.ID, &customer.Name)
+	if err != nil {
+		return customer, err
+	}
+	return customer, nil
+}
+
+// findCustomer is like getCustomerByID but returns nil when the customer does not exist.
+func findCustomer(ctx context.Context, q queryer, id string) (*model.Customer, error) {
+	customer, err := getCustomerByID(ctx, q, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &customer, nil
+}
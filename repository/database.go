@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+type databaseContextKey struct{}
+
+// WithDatabase returns a copy of ctx in which repositories use db instead of
+// the database they were created with, such as the database of a tenant.
+func WithDatabase(ctx context.Context, db *sql.DB) context.Context {
+	return context.WithValue(ctx, databaseContextKey{}, db)
+}
+
+// database returns the database stored in ctx, or fallback when none is set.
+func database(ctx context.Context, fallback *sql.DB) *sql.DB {
+	if db, ok := ctx.Value(databaseContextKey{}).(*sql.DB); ok {
+		return db
+	}
+	return fallback
+}
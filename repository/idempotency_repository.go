@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"api/model"
+	"context"
+	"time"
+
+	"database/sql"
+)
+
+type IdempotencyRepository struct {
+	db *sql.DB
+}
+
+func NewIdempotencyRepository(db *sql.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{
+		db: db,
+	}
+}
+
+// ReserveIdempotencyKey claims the key for a new request that expires after ttl.
+// If the key is already claimed and not expired, the existing record is
+// returned instead and nothing is reserved.
+func (repository *IdempotencyRepository) ReserveIdempotencyKey(ctx context.Context, key string, method string, path string, requestHash string, ttl time.Duration) (*model.IdempotencyRecord, error) {
+	ctx, end := observe(ctx, "IdempotencyRepository", "ReserveIdempotencyKey")
+	defer end()
+
+	tx, err := database(ctx, repository.db).BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	_, err = tx.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= ?", formatTimestamp(now))
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO idempotency_keys (key, method, path, request_hash, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (key, method, path) DO NOTHING
+	`, key, method, path, requestHash, formatTimestamp(now), formatTimestamp(now.Add(ttl)))
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	var existing *model.IdempotencyRecord
+	if inserted, err := result.RowsAffected(); err != nil {
+		tx.Rollback()
+		return nil, err
+	} else if inserted == 0 {
+		record := model.IdempotencyRecord{}
+		var contentType sql.NullString
+		err = tx.QueryRowContext(ctx, `
+			SELECT key, method, path, request_hash, status, content_type, response, created_at, expires_at
+			FROM idempotency_keys
+			WHERE key = ? AND method = ? AND path = ?
+		`, key, method, path).Scan(
+			&record.Key, &record.Method, &record.Path, &record.RequestHash, &record.Status,
+			&contentType, &record.Response, &record.CreatedAt, &record.ExpiresAt,
+		)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		record.ContentType = contentType.String
+		existing = &record
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return existing, nil
+}
+
+// CompleteIdempotencyKey stores the response of a reserved request for replay.
+func (repository *IdempotencyRepository) CompleteIdempotencyKey(ctx context.Context, key string, method string, path string, status int, contentType string, response []byte) error {
+	ctx, end := observe(ctx, "IdempotencyRepository", "CompleteIdempotencyKey")
+	defer end()
+
+	_, err := database(ctx, repository.db).ExecContext(ctx,
+		"UPDATE idempotency_keys SET status = ?, content_type = ?, response = ? WHERE key = ? AND method = ? AND path = ?",
+		status, contentType, response, key, method, path,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey forgets a reserved key so the request can be retried.
+func (repository *IdempotencyRepository) ReleaseIdempotencyKey(ctx context.Context, key string, method string, path string) error {
+	ctx, end := observe(ctx, "IdempotencyRepository", "ReleaseIdempotencyKey")
+	defer end()
+
+	_, err := database(ctx, repository.db).ExecContext(ctx, "DELETE FROM idempotency_keys WHERE key = ? AND method = ? AND path = ?", key, method, path)
+	if err != nil {
+		return err
+	}
+	return nil
+}
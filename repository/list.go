@@ -0,0 +1,57 @@
+package repository
+
+import "strings"
+
+// Page selects up to Limit rows in ID order, starting after the row with ID
+// After when it is set.
+type Page struct {
+	After string
+	Limit int
+}
+
+// where builds a WHERE clause from conditions joined with AND.
+type where struct {
+	conditions []string
+	args       []any
+}
+
+func (w *where) add(condition string, args ...any) {
+	w.conditions = append(w.conditions, condition)
+	w.args = append(w.args, args...)
+}
+
+// in adds a condition that column is one of values.
+func (w *where) in(column string, values []string) {
+	args := make([]any, len(values))
+	for i, value := range values {
+		args[i] = value
+	}
+	w.add(column+" IN (?"+strings.Repeat(", ?", len(values)-1)+")", args...)
+}
+
+// contains adds a case-insensitive substring match of column against s.
+func (w *where) contains(column string, s string) {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+	w.add(column+` LIKE ? ESCAPE '\'`, "%"+escaped+"%")
+}
+
+func (w *where) String() string {
+	if len(w.conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(w.conditions, " AND ")
+}
+
+// paginate restricts w to page, ordering by the ID column idColumn, and
+// returns the clause to append after it.
+func (w *where) paginate(idColumn string, page Page) string {
+	if page.After != "" {
+		w.add(idColumn+" > ?", page.After)
+	}
+	clause := w.String() + " ORDER BY " + idColumn
+	if page.Limit > 0 {
+		clause += " LIMIT ?"
+		w.args = append(w.args, page.Limit)
+	}
+	return clause
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ObserverFunc is told how long each repository method call took, e.g. to
+// export query latency metrics.
+type ObserverFunc func(repository string, method string, duration time.Duration)
+
+var observer atomic.Pointer[ObserverFunc]
+
+// SetObserver installs fn for every repository; nil removes it.
+func SetObserver(fn ObserverFunc) {
+	if fn == nil {
+		observer.Store(nil)
+		return
+	}
+	observer.Store(&fn)
+}
+
+// tracer resolves the global tracer provider on every use, so spans are
+// exported once tracing is set up regardless of initialisation order.
+var tracer = otel.Tracer("api/repository")
+
+// observe times a method call and, when ctx is part of a trace, wraps it in a
+// span named after it that the call's queries become children of. Use it as
+//
+//	ctx, end := observe(ctx, "ProductRepository", "GetProducts")
+//	defer end()
+func observe(ctx context.Context, repository string, method string) (context.Context, func()) {
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		ctx, span = tracer.Start(ctx, repository+"."+method,
+			trace.WithSpanKind(trace.SpanKindInternal),
+			trace.WithAttributes(attribute.String("code.namespace", repository), attribute.String("code.function", method)),
+		)
+	}
+	fn := observer.Load()
+	started := time.Now()
+	return ctx, func() {
+		if fn != nil {
+			(*fn)(repository, method, time.Since(started))
+		}
+		span.End()
+	}
+}
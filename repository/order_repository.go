@@ -2,12 +2,22 @@ package repository
 
 import (
 	"api/model"
+	"context"
+	"errors"
+	"fmt"
 
 	"database/sql"
 )
 
+const orderResource = "orders"
+
+// ErrUnknownProduct is returned when pricing an order item whose product
+// does not exist.
+var ErrUnknownProduct = errors.New("unknown product")
+
 type OrderRepository struct {
-	db *sql.DB
+	db        *sql.DB
+	onCreated func(ctx context.Context, order model.Order)
 }
 
 func NewOrderRepository(db *sql.DB) *OrderRepository {
@@ -16,133 +26,380 @@ func NewOrderRepository(db *sql.DB) *OrderRepository {
 	}
 }
 
-func (repository *OrderRepository) GetOrders() ([]model.Order, error) {
+// OnOrderCreated makes the repository call fn with every order it creates,
+// once the transaction creating it has committed, e.g. to count orders.
+// Call it before use.
+func (repository *OrderRepository) OnOrderCreated(fn func(ctx context.Context, order model.Order)) {
+	repository.onCreated = fn
+}
+
+// created reports a committed order to the OnOrderCreated function.
+func (repository *OrderRepository) created(ctx context.Context, order model.Order) {
+	if repository.onCreated != nil {
+		repository.onCreated(ctx, order)
+	}
+}
+
+// OrderIncludes selects the relations loaded with orders; the others are
+// not queried at all.
+type OrderIncludes struct {
+	Customer bool
+	Items    bool
+	// Products loads the product of each item and requires Items.
+	Products bool
+}
+
+// AllOrderIncludes loads every relation of orders.
+var AllOrderIncludes = OrderIncludes{Customer: true, Items: true, Products: true}
+
+func (repository *OrderRepository) GetOrders(ctx context.Context, includes OrderIncludes) ([]model.Order, error) {
+	ctx, end := observe(ctx, "OrderRepository", "GetOrders")
+	defer end()
+
+	return getOrders(ctx, database(ctx, repository.db), includes, "")
+}
+
+// GetOrdersByCustomer returns the orders placed by one customer.
+func (repository *OrderRepository) GetOrdersByCustomer(ctx context.Context, customerID string, includes OrderIncludes) ([]model.Order, error) {
+	ctx, end := observe(ctx, "OrderRepository", "GetOrdersByCustomer")
+	defer end()
+
+	return getOrders(ctx, database(ctx, repository.db), includes, "WHERE o.customer_id = ?", customerID)
+}
+
+// OrderFilter restricts the orders returned by ListOrders; zero fields
+// match every order. From and To bound the order date, inclusive.
+type OrderFilter struct {
+	CustomerID string
+	From       string
+	To         string
+}
+
+// ListOrders returns a page of the orders matching filter.
+func (repository *OrderRepository) ListOrders(ctx context.Context, filter OrderFilter, page Page, includes OrderIncludes) ([]model.Order, error) {
+	ctx, end := observe(ctx, "OrderRepository", "ListOrders")
+	defer end()
+
+	var w where
+	if filter.CustomerID != "" {
+		w.add("o.customer_id = ?", filter.CustomerID)
+	}
+	if filter.From != "" {
+		w.add("o.order_date >= ?", filter.From)
+	}
+	if filter.To != "" {
+		w.add("o.order_date <= ?", filter.To)
+	}
+	clause := w.paginate("o.id", page)
+	return getOrders(ctx, database(ctx, repository.db), includes, clause, w.args...)
+}
+
+// GetOrdersByCustomers returns the orders placed by any of the customers,
+// most recent first.
+func (repository *OrderRepository) GetOrdersByCustomers(ctx context.Context, customerIDs []string, includes OrderIncludes) ([]model.Order, error) {
+	ctx, end := observe(ctx, "OrderRepository", "GetOrdersByCustomers")
+	defer end()
+
+	if len(customerIDs) == 0 {
+		return []model.Order{}, nil
+	}
+	var w where
+	w.in("o.customer_id", customerIDs)
+	return getOrders(ctx, database(ctx, repository.db), includes, w.String()+" ORDER BY o.order_date DESC, o.id DESC", w.args...)
+}
+
+// GetOrderItemsByOrders returns the items of the orders, without their
+// product.
+func (repository *OrderRepository) GetOrderItemsByOrders(ctx context.Context, orderIDs []string) ([]model.OrderItem, error) {
+	ctx, end := observe(ctx, "OrderRepository", "GetOrderItemsByOrders")
+	defer end()
+
+	var orderItems []model.OrderItem = []model.OrderItem{}
+	if len(orderIDs) == 0 {
+		return orderItems, nil
+	}
+	var w where
+	w.in("oi.order_id", orderIDs)
+	rows, err := database(ctx, repository.db).QueryContext(ctx, orderItemQuery(OrderIncludes{Items: true})+w.String(), w.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		orderItem, err := scanOrderItem(rows, OrderIncludes{Items: true})
+		if err != nil {
+			return nil, err
+		}
+		orderItems = append(orderItems, orderItem)
+	}
+
+	return orderItems, rows.Err()
+}
+
+// getOrders returns the orders matching filter, a WHERE clause on orders
+// aliased o that may be followed by ORDER BY and LIMIT, with the relations
+// in includes. OrderItems is nil unless items are included.
+func getOrders(ctx context.Context, q queryer, includes OrderIncludes, filter string, args ...any) ([]model.Order, error) {
 	var orders []model.Order = []model.Order{}
 
-	orderRows, err := repository.db.Query(`
-		SELECT o.id, o.customer_id, o.order_date,
-		       c.id, c.name
-		FROM orders o
-		INNER JOIN customers c ON o.customer_id = c.id
-	`)
+	orderRows, err := q.QueryContext(ctx, orderQuery(includes)+filter, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer orderRows.Close()
 
-	var customer model.Customer
-
+	index := map[string]int{}
 	for orderRows.Next() {
-		order := model.Order{}
-		err := orderRows.Scan(
-			&order.ID, &order.CustomerID, &order.OrderDate,
-			&customer.ID, &customer.Name,
-		)
+		order, err := scanOrder(orderRows, includes)
 		if err != nil {
 			return nil, err
 		}
-
-		order.Customer = customer
-		order.OrderItems = []model.OrderItem{}
+		index[order.ID] = len(orders)
 		orders = append(orders, order)
 	}
+	if err := orderRows.Err(); err != nil {
+		return nil, err
+	}
+	if !includes.Items {
+		return orders, nil
+	}
 
-	orderItemRows, err := repository.db.Query(`
-		SELECT oi.id, oi.order_id, oi.product_id, oi.quantity, oi.price,
-			   p.id, p.name, p.price
-		FROM order_items oi
-		INNER JOIN products p ON oi.product_id = p.id
-	`)
+	itemFilter := ""
+	if filter != "" {
+		itemFilter = "WHERE oi.order_id IN (SELECT o.id FROM orders o " + filter + ")"
+	}
+	orderItemRows, err := q.QueryContext(ctx, orderItemQuery(includes)+itemFilter, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer orderItemRows.Close()
 
-	var orderItem model.OrderItem
-	var product model.Product
-
 	for orderItemRows.Next() {
-		err := orderItemRows.Scan(
-			&orderItem.ID, &orderItem.OrderID, &orderItem.ProductID, &orderItem.Quantity, &orderItem.Price,
-			&product.ID, &product.Name, &product.Price,
-		)
+		orderItem, err := scanOrderItem(orderItemRows, includes)
 		if err != nil {
 			return nil, err
 		}
-
-		for i := range orders {
-			if orders[i].ID == orderItem.OrderID {
-				orderItem.Product = product
-				orders[i].OrderItems = append(orders[i].OrderItems, orderItem)
-				break
-			}
+		if i, ok := index[orderItem.OrderID]; ok {
+			orders[i].OrderItems = append(orders[i].OrderItems, orderItem)
 		}
 	}
 
-	return orders, nil
+	return orders, orderItemRows.Err()
 }
 
-func (repository *OrderRepository) GetOrderByID(orderID string) (model.Order, error) {
-	var order model.Order
-
-	orderRow := repository.db.QueryRow(`
+// orderQuery selects orders aliased o, joined to their customer when included.
+// The join never filters orders: a missing customer is left empty.
+func orderQuery(includes OrderIncludes) string {
+	if includes.Customer {
+		return `
 		SELECT o.id, o.customer_id, o.order_date,
-			   c.id, c.name
+		       COALESCE(c.id, ''), COALESCE(c.name, '')
+		FROM orders o
+		LEFT JOIN customers c ON o.customer_id = c.id
+		`
+	}
+	return `
+		SELECT o.id, o.customer_id, o.order_date
 		FROM orders o
-		INNER JOIN customers c ON o.customer_id = c.id
-		WHERE o.id = ?
-	`, orderID)
+		`
+}
 
-	customer := model.Customer{}
-	err := orderRow.Scan(
-		&order.ID, &order.CustomerID, &order.OrderDate,
-		&customer.ID, &customer.Name,
-	)
-	if err != nil {
+func scanOrder(row scanner, includes OrderIncludes) (model.Order, error) {
+	var order model.Order
+	dest := []any{&order.ID, &order.CustomerID, &order.OrderDate}
+	if includes.Customer {
+		dest = append(dest, &order.Customer.ID, &order.Customer.Name)
+	}
+	if err := row.Scan(dest...); err != nil {
 		return order, err
 	}
+	if includes.Items {
+		order.OrderItems = []model.OrderItem{}
+	}
+	return order, nil
+}
 
-	order.Customer = customer
-
-	orderItemRows, err := repository.db.Query(`
+// orderItemQuery selects order items aliased oi, joined to their product
+// when included. Like orderQuery, a missing product is left empty.
+func orderItemQuery(includes OrderIncludes) string {
+	if includes.Products {
+		return `
 		SELECT oi.id, oi.order_id, oi.product_id, oi.quantity, oi.price,
-			   p.id, p.name, p.price
+		       COALESCE(p.id, ''), COALESCE(p.name, ''), COALESCE(p.price, 0)
 		FROM order_items oi
-		INNER JOIN products p ON oi.product_id = p.id
-		WHERE oi.order_id = ?
-	`, orderID)
+		LEFT JOIN products p ON oi.product_id = p.id
+		`
+	}
+	return `
+		SELECT oi.id, oi.order_id, oi.product_id, oi.quantity, oi.price
+		FROM order_items oi
+		`
+}
+
+func scanOrderItem(row scanner, includes OrderIncludes) (model.OrderItem, error) {
+	var orderItem model.OrderItem
+	dest := []any{&orderItem.ID, &orderItem.OrderID, &orderItem.ProductID, &orderItem.Quantity, &orderItem.Price}
+	if includes.Products {
+		dest = append(dest, &orderItem.Product.ID, &orderItem.Product.Name, &orderItem.Product.Price)
+	}
+	err := row.Scan(dest...)
+	return orderItem, err
+}
+
+// EachOrder calls fn for every order with its items, without the joined
+// customer and products, as rows are read. It stops at the first error.
+func (repository *OrderRepository) EachOrder(ctx context.Context, fn func(model.Order) error) error {
+	ctx, end := observe(ctx, "OrderRepository", "EachOrder")
+	defer end()
+
+	rows, err := database(ctx, repository.db).QueryContext(ctx, `
+		SELECT o.id, o.customer_id, o.order_date,
+		       oi.id, oi.product_id, oi.quantity, oi.price
+		FROM orders o
+		LEFT JOIN order_items oi ON oi.order_id = o.id
+		ORDER BY o.id, oi.id
+	`)
 	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var current *model.Order
+	for rows.Next() {
+		var order model.Order
+		var itemID, productID sql.NullString
+		var quantity sql.NullInt64
+		var price sql.NullFloat64
+		err := rows.Scan(
+			&order.ID, &order.CustomerID, &order.OrderDate,
+			&itemID, &productID, &quantity, &price,
+		)
+		if err != nil {
+			return err
+		}
+
+		if current == nil || current.ID != order.ID {
+			if current != nil {
+				if err := fn(*current); err != nil {
+					return err
+				}
+			}
+			order.OrderItems = []model.OrderItem{}
+			current = &order
+		}
+
+		if itemID.Valid {
+			current.OrderItems = append(current.OrderItems, model.OrderItem{
+				ID:        itemID.String,
+				OrderID:   order.ID,
+				ProductID: productID.String,
+				Quantity:  int(quantity.Int64),
+				Price:     price.Float64,
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if current != nil {
+		return fn(*current)
+	}
+	return nil
+}
+
+func (repository *OrderRepository) GetOrderByID(ctx context.Context, orderID string, includes OrderIncludes) (model.Order, error) {
+	ctx, end := observe(ctx, "OrderRepository", "GetOrderByID")
+	defer end()
+
+	return getOrderByID(ctx, database(ctx, repository.db), includes, orderID)
+}
+
+func getOrderByID(ctx context.Context, q queryer, includes OrderIncludes, orderID string) (model.Order, error) {
+	order, err := scanOrder(q.QueryRowContext(ctx, orderQuery(includes)+"WHERE o.id = ?", orderID), includes)
+	if err != nil || !includes.Items {
 		return order, err
 	}
-	defer orderItemRows.Close()
 
-	var orderItem model.OrderItem
-	var product model.Product
+	orderItemRows, err := q.QueryContext(ctx, orderItemQuery(includes)+"WHERE oi.order_id = ?", orderID)
+	if err != nil {
+		return order, err
+	}
+	defer orderItemRows.Close()
 
 	for orderItemRows.Next() {
-		err := orderItemRows.Scan(
-			&orderItem.ID, &orderItem.OrderID, &orderItem.ProductID, &orderItem.Quantity, &orderItem.Price,
-			&product.ID, &product.Name, &product.Price,
-		)
+		orderItem, err := scanOrderItem(orderItemRows, includes)
 		if err != nil {
 			return order, err
 		}
-
-		orderItem.Product = product
 		order.OrderItems = append(order.OrderItems, orderItem)
 	}
 
+	return order, orderItemRows.Err()
+}
+
+// findOrder is like getOrderByID but returns nil when the order does not exist.
+func findOrder(ctx context.Context, q queryer, orderID string) (*model.Order, error) {
+	order, err := getOrderByID(ctx, q, AllOrderIncludes, orderID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// WithCatalogPrices returns order with the price of every item set to the
+// current price of its product, for callers that may not choose prices. It
+// returns ErrUnknownProduct when an item's product does not exist.
+func (repository *OrderRepository) WithCatalogPrices(ctx context.Context, order model.Order) (model.Order, error) {
+	ctx, end := observe(ctx, "OrderRepository", "WithCatalogPrices")
+	defer end()
+
+	if len(order.OrderItems) == 0 {
+		return order, nil
+	}
+	ids := make([]string, len(order.OrderItems))
+	for i, item := range order.OrderItems {
+		ids[i] = item.ProductID
+	}
+	var w where
+	w.in("id", ids)
+	products, err := queryProducts(ctx, database(ctx, repository.db), w.String(), w.args...)
+	if err != nil {
+		return model.Order{}, err
+	}
+	prices := make(map[string]float64, len(products))
+	for _, product := range products {
+		prices[product.ID] = product.Price
+	}
+
+	items := make([]model.OrderItem, len(order.OrderItems))
+	for i, item := range order.OrderItems {
+		price, ok := prices[item.ProductID]
+		if !ok {
+			return model.Order{}, fmt.Errorf("%w %q", ErrUnknownProduct, item.ProductID)
+		}
+		item.Price = price
+		items[i] = item
+	}
+	order.OrderItems = items
 	return order, nil
 }
 
-func (repository *OrderRepository) CreateOrder(order model.Order) error {
-	tx, err := repository.db.Begin()
+func (repository *OrderRepository) CreateOrder(ctx context.Context, order model.Order) error {
+	ctx, end := observe(ctx, "OrderRepository", "CreateOrder")
+	defer end()
+
+	tx, err := database(ctx, repository.db).BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 
 	// Insert order
-	_, err = tx.Exec("INSERT INTO orders (id, customer_id, order_date) VALUES (?, ?, ?)", order.ID, order.CustomerID, order.OrderDate)
+	_, err = tx.ExecContext(ctx, "INSERT INTO orders (id, customer_id, order_date) VALUES (?, ?, ?)", order.ID, order.CustomerID, order.OrderDate)
 	if err != nil {
 		tx.Rollback()
 		return err
@@ -150,36 +407,59 @@ func (repository *OrderRepository) CreateOrder(order model.Order) error {
 
 	// Insert order items
 	for _, orderItem := range order.OrderItems {
-		_, err = tx.Exec("INSERT INTO order_items (id, order_id, product_id, quantity, price) VALUES (?, ?, ?, ?, ?)", orderItem.ID, order.ID, orderItem.ProductID, orderItem.Quantity, orderItem.Price)
+		_, err = tx.ExecContext(ctx, "INSERT INTO order_items (id, order_id, product_id, quantity, price) VALUES (?, ?, ?, ?, ?)", orderItem.ID, order.ID, orderItem.ProductID, orderItem.Quantity, orderItem.Price)
 		if err != nil {
 			tx.Rollback()
 			return err
 		}
 	}
 
+	// Record audit entry, snapshotting the order as stored
+	after, err := findOrder(ctx, tx, order.ID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	err = recordChange(ctx, tx, AuditActionCreate, orderResource, order.ID, nil, after)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		return err
 	}
 
+	repository.created(ctx, order)
 	return nil
 }
 
-func (repository *OrderRepository) UpdateOrder(order model.Order) error {
-	tx, err := repository.db.Begin()
+func (repository *OrderRepository) UpdateOrder(ctx context.Context, order model.Order) error {
+	ctx, end := observe(ctx, "OrderRepository", "UpdateOrder")
+	defer end()
+
+	tx, err := database(ctx, repository.db).BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	// Snapshot order before the update
+	before, err := findOrder(ctx, tx, order.ID)
 	if err != nil {
+		tx.Rollback()
 		return err
 	}
 
 	// Update order
-	_, err = tx.Exec("UPDATE orders SET customer_id = ?, order_date = ? WHERE id = ?", order.CustomerID, order.OrderDate, order.ID)
+	_, err = tx.ExecContext(ctx, "UPDATE orders SET customer_id = ?, order_date = ? WHERE id = ?", order.CustomerID, order.OrderDate, order.ID)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
 
 	// Delete existing order items
-	_, err = tx.Exec("DELETE FROM order_items WHERE order_id = ?", order.ID)
+	_, err = tx.ExecContext(ctx, "DELETE FROM order_items WHERE order_id = ?", order.ID)
 	if err != nil {
 		tx.Rollback()
 		return err
@@ -187,7 +467,21 @@ func (repository *OrderRepository) UpdateOrder(order model.Order) error {
 
 	// Insert updated order items
 	for _, orderItem := range order.OrderItems {
-		_, err = tx.Exec("INSERT INTO order_items (id, order_id, product_id, quantity, price) VALUES (?, ?, ?, ?, ?)", orderItem.ID, order.ID, orderItem.ProductID, orderItem.Quantity, orderItem.Price)
+		_, err = tx.ExecContext(ctx, "INSERT INTO order_items (id, order_id, product_id, quantity, price) VALUES (?, ?, ?, ?, ?)", orderItem.ID, order.ID, orderItem.ProductID, orderItem.Quantity, orderItem.Price)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	// Record audit entry, snapshotting the order as stored like before
+	if before != nil {
+		after, err := findOrder(ctx, tx, order.ID)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		err = recordChange(ctx, tx, AuditActionUpdate, orderResource, order.ID, before, after)
 		if err != nil {
 			tx.Rollback()
 			return err
@@ -202,26 +496,45 @@ func (repository *OrderRepository) UpdateOrder(order model.Order) error {
 	return nil
 }
 
-func (repository *OrderRepository) DeleteOrder(orderID string) error {
-	tx, err := repository.db.Begin()
+func (repository *OrderRepository) DeleteOrder(ctx context.Context, orderID string) error {
+	ctx, end := observe(ctx, "OrderRepository", "DeleteOrder")
+	defer end()
+
+	tx, err := database(ctx, repository.db).BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	// Snapshot order before the delete
+	before, err := findOrder(ctx, tx, orderID)
 	if err != nil {
+		tx.Rollback()
 		return err
 	}
 
 	// Delete order items
-	_, err = tx.Exec("DELETE FROM order_items WHERE order_id = ?", orderID)
+	_, err = tx.ExecContext(ctx, "DELETE FROM order_items WHERE order_id = ?", orderID)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
 
 	// Delete order
-	_, err = tx.Exec("DELETE FROM orders WHERE id = ?", orderID)
+	_, err = tx.ExecContext(ctx, "DELETE FROM orders WHERE id = ?", orderID)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
 
+	// Record audit entry
+	if before != nil {
+		err = recordChange(ctx, tx, AuditActionDelete, orderResource, orderID, before, nil)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		return err
@@ -229,3 +542,108 @@ func (repository *OrderRepository) DeleteOrder(orderID string) error {
 
 	return nil
 }
+
+// ApplyOrderBatch applies the operations in a single transaction. See runBatch
+// for how the options affect failures.
+func (repository *OrderRepository) ApplyOrderBatch(ctx context.Context, operations []model.BatchOperation[model.Order], options BatchOptions) ([]error, error) {
+	ctx, end := observe(ctx, "OrderRepository", "ApplyOrderBatch")
+	defer end()
+
+	errs, err := runBatch(ctx, database(ctx, repository.db), len(operations), options, func(tx *sql.Tx) (batchApplier, func(), error) {
+		stmts, cleanup, err := prepareAll(ctx, tx,
+			"INSERT INTO orders (id, customer_id, order_date) VALUES (?, ?, ?)",
+			"UPDATE orders SET customer_id = ?, order_date = ? WHERE id = ?",
+			"DELETE FROM orders WHERE id = ?",
+			"INSERT INTO order_items (id, order_id, product_id, quantity, price) VALUES (?, ?, ?, ?, ?)",
+			"DELETE FROM order_items WHERE order_id = ?",
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		insertOrderStmt, updateOrderStmt, deleteOrderStmt := stmts[0], stmts[1], stmts[2]
+		insertItemStmt, deleteItemsStmt := stmts[3], stmts[4]
+
+		insertItems := func(order model.Order) error {
+			for _, orderItem := range order.OrderItems {
+				_, err := insertItemStmt.ExecContext(ctx, orderItem.ID, order.ID, orderItem.ProductID, orderItem.Quantity, orderItem.Price)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		apply := func(i int) error {
+			order := operations[i].Data
+			switch operations[i].Op {
+			case model.BatchOpCreate:
+				_, err := insertOrderStmt.ExecContext(ctx, order.ID, order.CustomerID, order.OrderDate)
+				if err != nil {
+					return err
+				}
+				if err := insertItems(order); err != nil {
+					return err
+				}
+				after, err := findOrder(ctx, tx, order.ID)
+				if err != nil {
+					return err
+				}
+				return recordChange(ctx, tx, AuditActionCreate, orderResource, order.ID, nil, after)
+			case model.BatchOpUpdate:
+				before, err := findOrder(ctx, tx, order.ID)
+				if err != nil {
+					return err
+				}
+				if before == nil {
+					return sql.ErrNoRows
+				}
+				_, err = updateOrderStmt.ExecContext(ctx, order.CustomerID, order.OrderDate, order.ID)
+				if err != nil {
+					return err
+				}
+				_, err = deleteItemsStmt.ExecContext(ctx, order.ID)
+				if err != nil {
+					return err
+				}
+				if err := insertItems(order); err != nil {
+					return err
+				}
+				after, err := findOrder(ctx, tx, order.ID)
+				if err != nil {
+					return err
+				}
+				return recordChange(ctx, tx, AuditActionUpdate, orderResource, order.ID, before, after)
+			case model.BatchOpDelete:
+				before, err := findOrder(ctx, tx, order.ID)
+				if err != nil {
+					return err
+				}
+				if before == nil {
+					return sql.ErrNoRows
+				}
+				_, err = deleteItemsStmt.ExecContext(ctx, order.ID)
+				if err != nil {
+					return err
+				}
+				_, err = deleteOrderStmt.ExecContext(ctx, order.ID)
+				if err != nil {
+					return err
+				}
+				return recordChange(ctx, tx, AuditActionDelete, orderResource, order.ID, before, nil)
+			}
+			return unknownBatchOp(operations[i].Op)
+		}
+
+		return apply, cleanup, nil
+	})
+	if err != nil || options.DryRun {
+		return errs, err
+	}
+
+	for i, operation := range operations {
+		if operation.Op == model.BatchOpCreate && errs[i] == nil {
+			repository.created(ctx, operation.Data)
+		}
+	}
+	return errs, nil
+}
@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"api/model"
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"database/sql"
+)
+
+type OutboxRepository struct {
+	db *sql.DB
+}
+
+func NewOutboxRepository(db *sql.DB) *OutboxRepository {
+	return &OutboxRepository{
+		db: db,
+	}
+}
+
+// GetPendingEvents returns up to limit unpublished events whose next attempt
+// is due at now, oldest first. Dead-lettered events are not pending.
+func (repository *OutboxRepository) GetPendingEvents(ctx context.Context, now time.Time, limit int) ([]model.Event, error) {
+	ctx, end := observe(ctx, "OutboxRepository", "GetPendingEvents")
+	defer end()
+
+	var events []model.Event = []model.Event{}
+	rows, err := database(ctx, repository.db).QueryContext(ctx, `
+		SELECT id, event_type, resource, resource_id, payload, occurred_at, attempts
+		FROM outbox
+		WHERE published_at IS NULL AND dead_at IS NULL AND next_attempt_at <= ?
+		ORDER BY id
+		LIMIT ?
+	`, formatTimestamp(now), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event model.Event
+		var payload string
+		err := rows.Scan(&event.ID, &event.Type, &event.Resource, &event.ResourceID, &payload, &event.OccurredAt, &event.Attempts)
+		if err != nil {
+			return nil, err
+		}
+		event.Payload = json.RawMessage(payload)
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// GetEventsAfter returns up to limit events with an ID greater than afterID,
+// published or not, optionally restricted to the given resources. Event IDs
+// are a persisted, strictly increasing sequence.
+func (repository *OutboxRepository) GetEventsAfter(ctx context.Context, afterID int64, resources []string, limit int) ([]model.Event, error) {
+	ctx, end := observe(ctx, "OutboxRepository", "GetEventsAfter")
+	defer end()
+
+	var events []model.Event = []model.Event{}
+	query := `
+		SELECT id, event_type, resource, resource_id, payload, occurred_at, attempts
+		FROM outbox
+		WHERE id > ?`
+	args := []any{afterID}
+	if len(resources) > 0 {
+		query += " AND resource IN (?" + strings.Repeat(", ?", len(resources)-1) + ")"
+		for _, resource := range resources {
+			args = append(args, resource)
+		}
+	}
+	query += " ORDER BY id LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := database(ctx, repository.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event model.Event
+		var payload string
+		err := rows.Scan(&event.ID, &event.Type, &event.Resource, &event.ResourceID, &payload, &event.OccurredAt, &event.Attempts)
+		if err != nil {
+			return nil, err
+		}
+		event.Payload = json.RawMessage(payload)
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// GetLatestEventID returns the ID of the most recent event, or 0 when there is none.
+func (repository *OutboxRepository) GetLatestEventID(ctx context.Context) (int64, error) {
+	ctx, end := observe(ctx, "OutboxRepository", "GetLatestEventID")
+	defer end()
+
+	var id int64
+	err := database(ctx, repository.db).QueryRowContext(ctx, "SELECT IFNULL(MAX(id), 0) FROM outbox").Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (repository *OutboxRepository) MarkPublished(ctx context.Context, id int64, publishedAt time.Time) error {
+	ctx, end := observe(ctx, "OutboxRepository", "MarkPublished")
+	defer end()
+
+	_, err := database(ctx, repository.db).ExecContext(ctx, "UPDATE outbox SET published_at = ?, last_error = NULL WHERE id = ?", formatTimestamp(publishedAt), id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarkFailed records a failed delivery attempt and schedules the next one.
+func (repository *OutboxRepository) MarkFailed(ctx context.Context, id int64, deliveryErr error, nextAttemptAt time.Time) error {
+	ctx, end := observe(ctx, "OutboxRepository", "MarkFailed")
+	defer end()
+
+	_, err := database(ctx, repository.db).ExecContext(ctx,
+		"UPDATE outbox SET attempts = attempts + 1, last_error = ?, next_attempt_at = ? WHERE id = ?",
+		deliveryErr.Error(), formatTimestamp(nextAttemptAt), id,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarkDead records the last failed delivery attempt of an event and
+// dead-letters it, so that it is not retried any more.
+func (repository *OutboxRepository) MarkDead(ctx context.Context, id int64, deliveryErr error, deadAt time.Time) error {
+	ctx, end := observe(ctx, "OutboxRepository", "MarkDead")
+	defer end()
+
+	_, err := database(ctx, repository.db).ExecContext(ctx,
+		"UPDATE outbox SET attempts = attempts + 1, last_error = ?, dead_at = ? WHERE id = ?",
+		deliveryErr.Error(), formatTimestamp(deadAt), id,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetDeliveredSinks returns the names of the sinks an event was delivered to.
+func (repository *OutboxRepository) GetDeliveredSinks(ctx context.Context, id int64) ([]string, error) {
+	ctx, end := observe(ctx, "OutboxRepository", "GetDeliveredSinks")
+	defer end()
+
+	rows, err := database(ctx, repository.db).QueryContext(ctx, "SELECT sink FROM outbox_deliveries WHERE event_id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sinks []string
+	for rows.Next() {
+		var sink string
+		if err := rows.Scan(&sink); err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks, rows.Err()
+}
+
+// MarkDelivered records that an event was delivered to the named sink.
+func (repository *OutboxRepository) MarkDelivered(ctx context.Context, id int64, sink string, deliveredAt time.Time) error {
+	ctx, end := observe(ctx, "OutboxRepository", "MarkDelivered")
+	defer end()
+
+	_, err := database(ctx, repository.db).ExecContext(ctx,
+		"INSERT OR IGNORE INTO outbox_deliveries (event_id, sink, delivered_at) VALUES (?, ?, ?)",
+		id, sink, formatTimestamp(deliveredAt),
+	)
+	return err
+}
+
+func writeOutboxEvent(ctx context.Context, tx *sql.Tx, eventType string, resource string, resourceID string, before sql.NullString, after sql.NullString) error {
+	payload, err := json.Marshal(model.EventPayload{
+		Before: rawJSON(before),
+		After:  rawJSON(after),
+	})
+	if err != nil {
+		return err
+	}
+
+	now := formatTimestamp(time.Now())
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO outbox (event_type, resource, resource_id, payload, occurred_at, next_attempt_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, eventType, resource, resourceID, string(payload), now, now)
+	return err
+}
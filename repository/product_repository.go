@@ -2,12 +2,22 @@ package repository
 
 import (
 	"api/model"
+	"context"
+	"errors"
+	"time"
 
 	"database/sql"
 )
 
+const productResource = "products"
+
+// ErrPriceChange is returned by writes that must keep prices when they would
+// set or change the price of a product.
+var ErrPriceChange = errors.New("price change not allowed")
+
 type ProductRepository struct {
-	db *sql.DB
+	db    *sql.DB
+	reads *readCache[model.Product]
 }
 
 func NewProductRepository(db *sql.DB) *ProductRepository {
@@ -16,9 +26,19 @@ func NewProductRepository(db *sql.DB) *ProductRepository {
 	}
 }
 
-func (repository *ProductRepository) GetProducts() ([]model.Product, error) {
+// EnableCache makes GetProductsCached and GetProductByIDCached keep their results
+// for ttl, keeping at most maxEntries lists and maxEntries products across all
+// databases. Writes through the repository empty the cache. Call it before use.
+func (repository *ProductRepository) EnableCache(ttl time.Duration, maxEntries int) {
+	repository.reads = newReadCache[model.Product](ttl, maxEntries)
+}
+
+func (repository *ProductRepository) GetProducts(ctx context.Context) ([]model.Product, error) {
+	ctx, end := observe(ctx, "ProductRepository", "GetProducts")
+	defer end()
+
 	var products []model.Product = []model.Product{}
-	rows, err := repository.db.Query("SELECT id, name, price FROM products")
+	rows, err := database(ctx, repository.db).QueryContext(ctx, "SELECT id, name, price FROM products")
 	if err != nil {
 		return nil, err
 	}
@@ -36,36 +56,336 @@ func (repository *ProductRepository) GetProducts() ([]model.Product, error) {
 	return products, nil
 }
 
-func (repository *ProductRepository) GetProductByID(id string) (model.Product, error) {
-	var product model.Product
-	row := repository.db.QueryRow("SELECT id, name, price FROM products WHERE id = ?", id)
-	err := row.Scan(&product.ID, &product.Name, &product.Price)
+// ProductFilter restricts the products returned by ListProducts; zero
+// fields match every product.
+type ProductFilter struct {
+	NameContains string
+	MinPrice     *float64
+	MaxPrice     *float64
+}
+
+// ListProducts returns a page of the products matching filter.
+func (repository *ProductRepository) ListProducts(ctx context.Context, filter ProductFilter, page Page) ([]model.Product, error) {
+	ctx, end := observe(ctx, "ProductRepository", "ListProducts")
+	defer end()
+
+	var w where
+	if filter.NameContains != "" {
+		w.contains("name", filter.NameContains)
+	}
+	if filter.MinPrice != nil {
+		w.add("price >= ?", *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		w.add("price <= ?", *filter.MaxPrice)
+	}
+	clause := w.paginate("id", page)
+	return queryProducts(ctx, database(ctx, repository.db), clause, w.args...)
+}
+
+// GetProductsByIDs returns the products with the given IDs that exist, in
+// no particular order.
+func (repository *ProductRepository) GetProductsByIDs(ctx context.Context, ids []string) ([]model.Product, error) {
+	ctx, end := observe(ctx, "ProductRepository", "GetProductsByIDs")
+	defer end()
+
+	if len(ids) == 0 {
+		return []model.Product{}, nil
+	}
+	var w where
+	w.in("id", ids)
+	return queryProducts(ctx, database(ctx, repository.db), w.String(), w.args...)
+}
+
+func queryProducts(ctx context.Context, q queryer, clause string, args ...any) ([]model.Product, error) {
+	var products []model.Product = []model.Product{}
+	rows, err := q.QueryContext(ctx, "SELECT id, name, price FROM products "+clause, args...)
 	if err != nil {
-		return product, err
+		return nil, err
 	}
-	return product, nil
+	defer rows.Close()
+
+	for rows.Next() {
+		var product model.Product
+		err := rows.Scan(&product.ID, &product.Name, &product.Price)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, product)
+	}
+
+	return products, rows.Err()
+}
+
+// EachProduct calls fn for every product as rows are read, stopping at the first error.
+func (repository *ProductRepository) EachProduct(ctx context.Context, fn func(model.Product) error) error {
+	ctx, end := observe(ctx, "ProductRepository", "EachProduct")
+	defer end()
+
+	rows, err := database(ctx, repository.db).QueryContext(ctx, "SELECT id, name, price FROM products ORDER BY id")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var product model.Product
+		err := rows.Scan(&product.ID, &product.Name, &product.Price)
+		if err != nil {
+			return err
+		}
+		if err := fn(product); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func (repository *ProductRepository) GetProductByID(ctx context.Context, id string) (model.Product, error) {
+	ctx, end := observe(ctx, "ProductRepository", "GetProductByID")
+	defer end()
+
+	return getProductByID(ctx, database(ctx, repository.db), id)
 }
 
-func (repository *ProductRepository) CreateProduct(product model.Product) error {
-	_, err := repository.db.Exec("INSERT INTO products (id, name, price) VALUES (?, ?, ?)", product.ID, product.Name, product.Price)
+// GetProductsCached is GetProducts served from the cache when enabled, along with
+// when products were last written, which is zero when unknown.
+func (repository *ProductRepository) GetProductsCached(ctx context.Context) ([]model.Product, time.Time, error) {
+	return repository.reads.getList(ctx, repository.db, productResource, func() ([]model.Product, error) {
+		return repository.GetProducts(ctx)
+	})
+}
+
+// GetProductByIDCached is GetProductByID served from the cache when enabled, along
+// with when the product was last written, which is zero when unknown.
+func (repository *ProductRepository) GetProductByIDCached(ctx context.Context, id string) (model.Product, time.Time, error) {
+	return repository.reads.getItem(ctx, repository.db, productResource, id, func() (model.Product, error) {
+		return repository.GetProductByID(ctx, id)
+	})
+}
+
+func (repository *ProductRepository) CreateProduct(ctx context.Context, product model.Product) error {
+	ctx, end := observe(ctx, "ProductRepository", "CreateProduct")
+	defer end()
+	defer repository.reads.clear()
+
+	tx, err := database(ctx, repository.db).BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO products (id, name, price) VALUES (?, ?, ?)", product.ID, product.Name, product.Price)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = recordChange(ctx, tx, AuditActionCreate, productResource, product.ID, nil, &product)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
 	if err != nil {
 		return err
 	}
+
 	return nil
 }
 
-func (repository *ProductRepository) UpdateProduct(product model.Product) error {
-	_, err := repository.db.Exec("UPDATE products SET name = ?, price = ? WHERE id = ?", product.Name, product.Price, product.ID)
+func (repository *ProductRepository) UpdateProduct(ctx context.Context, product model.Product) error {
+	ctx, end := observe(ctx, "ProductRepository", "UpdateProduct")
+	defer end()
+
+	return repository.updateProduct(ctx, product, false)
+}
+
+// UpdateProductKeepingPrice is UpdateProduct for callers that may not change
+// prices. It returns ErrPriceChange, without updating anything, when the
+// price differs from the stored one.
+func (repository *ProductRepository) UpdateProductKeepingPrice(ctx context.Context, product model.Product) error {
+	ctx, end := observe(ctx, "ProductRepository", "UpdateProductKeepingPrice")
+	defer end()
+
+	return repository.updateProduct(ctx, product, true)
+}
+
+func (repository *ProductRepository) updateProduct(ctx context.Context, product model.Product, keepPrice bool) error {
+	defer repository.reads.clear()
+
+	tx, err := database(ctx, repository.db).BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	before, err := findProduct(ctx, tx, product.ID)
 	if err != nil {
+		tx.Rollback()
 		return err
 	}
+	if keepPrice && before != nil && before.Price != product.Price {
+		tx.Rollback()
+		return ErrPriceChange
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE products SET name = ?, price = ? WHERE id = ?", product.Name, product.Price, product.ID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if before != nil {
+		err = recordChange(ctx, tx, AuditActionUpdate, productResource, product.ID, before, &product, productUpdateEvents(before, &product)...)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
-func (repository *ProductRepository) DeleteProduct(id string) error {
-	_, err := repository.db.Exec("DELETE FROM products WHERE id = ?", id)
+func (repository *ProductRepository) DeleteProduct(ctx context.Context, id string) error {
+	ctx, end := observe(ctx, "ProductRepository", "DeleteProduct")
+	defer end()
+	defer repository.reads.clear()
+
+	tx, err := database(ctx, repository.db).BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	before, err := findProduct(ctx, tx, id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM products WHERE id = ?", id)
 	if err != nil {
+		tx.Rollback()
 		return err
 	}
+
+	if before != nil {
+		err = recordChange(ctx, tx, AuditActionDelete, productResource, id, before, nil)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
+
+// ApplyProductBatch applies the operations in a single transaction. See runBatch
+// for how the options affect failures. With options.KeepPrices, creates and
+// updates changing the price of the product as stored at that point of the
+// batch fail with ErrPriceChange.
+func (repository *ProductRepository) ApplyProductBatch(ctx context.Context, operations []model.BatchOperation[model.Product], options BatchOptions) ([]error, error) {
+	ctx, end := observe(ctx, "ProductRepository", "ApplyProductBatch")
+	defer end()
+	defer repository.reads.clear()
+
+	return runBatch(ctx, database(ctx, repository.db), len(operations), options, func(tx *sql.Tx) (batchApplier, func(), error) {
+		stmts, cleanup, err := prepareAll(ctx, tx,
+			"INSERT INTO products (id, name, price) VALUES (?, ?, ?)",
+			"UPDATE products SET name = ?, price = ? WHERE id = ?",
+			"DELETE FROM products WHERE id = ?",
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		insertStmt, updateStmt, deleteStmt := stmts[0], stmts[1], stmts[2]
+
+		apply := func(i int) error {
+			product := operations[i].Data
+			switch operations[i].Op {
+			case model.BatchOpCreate:
+				if options.KeepPrices {
+					return ErrPriceChange
+				}
+				_, err := insertStmt.ExecContext(ctx, product.ID, product.Name, product.Price)
+				if err != nil {
+					return err
+				}
+				return recordChange(ctx, tx, AuditActionCreate, productResource, product.ID, nil, &product)
+			case model.BatchOpUpdate:
+				before, err := findProduct(ctx, tx, product.ID)
+				if err != nil {
+					return err
+				}
+				if before == nil {
+					return sql.ErrNoRows
+				}
+				if options.KeepPrices && before.Price != product.Price {
+					return ErrPriceChange
+				}
+				_, err = updateStmt.ExecContext(ctx, product.Name, product.Price, product.ID)
+				if err != nil {
+					return err
+				}
+				return recordChange(ctx, tx, AuditActionUpdate, productResource, product.ID, before, &product, productUpdateEvents(before, &product)...)
+			case model.BatchOpDelete:
+				before, err := findProduct(ctx, tx, product.ID)
+				if err != nil {
+					return err
+				}
+				if before == nil {
+					return sql.ErrNoRows
+				}
+				_, err = deleteStmt.ExecContext(ctx, product.ID)
+				if err != nil {
+					return err
+				}
+				return recordChange(ctx, tx, AuditActionDelete, productResource, product.ID, before, nil)
+			}
+			return unknownBatchOp(operations[i].Op)
+		}
+
+		return apply, cleanup, nil
+	})
+}
+
+// productUpdateEvents returns the events emitted by an update on top of product.updated.
+func productUpdateEvents(before *model.Product, after *model.Product) []string {
+	var extraEvents []string
+	if before.Price != after.Price {
+		extraEvents = append(extraEvents, model.EventProductPriceChanged)
+	}
+	return extraEvents
+}
+
+func getProductByID(ctx context.Context, q queryer, id string) (model.Product, error) {
+	var product model.Product
+	row := q.QueryRowContext(ctx, "SELECT id, name, price FROM products WHERE id = ?", id)
+	err := row.Scan(&product.ID, &product.Name, &product.Price)
+	if err != nil {
+		return product, err
+	}
+	return product, nil
+}
+
+// findProduct is like getProductByID but returns nil when the product does not exist.
+func findProduct(ctx context.Context, q queryer, id string) (*model.Product, error) {
+	product, err := getProductByID(ctx, q, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"database/sql"
+)
+
+// RateLimitRepository stores token buckets. Like the TenantRepository it
+// always uses the main database, so limits apply across tenants.
+type RateLimitRepository struct {
+	db *sql.DB
+}
+
+func NewRateLimitRepository(db *sql.DB) *RateLimitRepository {
+	return &RateLimitRepository{
+		db: db,
+	}
+}
+
+// TakeToken removes a token from the bucket named key if one is available,
+// after adding rate tokens per second elapsed since its last use, up to
+// burst. New buckets start full. It returns the tokens left and whether one
+// was taken, in a single statement so that concurrent callers, including
+// other processes, cannot take the same token.
+func (repository *RateLimitRepository) TakeToken(ctx context.Context, key string, rate float64, burst int, now time.Time) (float64, bool, error) {
+	ctx, end := observe(ctx, "RateLimitRepository", "TakeToken")
+	defer end()
+
+	var tokens float64
+	err := repository.db.QueryRowContext(ctx, `
+		INSERT INTO rate_limit_buckets (key, tokens, updated_at) VALUES (?1, ?2 - 1, ?3)
+		ON CONFLICT (key) DO UPDATE SET
+			tokens = MIN(?2, tokens + MAX(0, ?3 - updated_at) * ?4 / 1000.0) - 1,
+			updated_at = ?3
+		WHERE MIN(?2, tokens + MAX(0, ?3 - updated_at) * ?4 / 1000.0) >= 1
+		RETURNING tokens
+	`, key, burst, now.UnixMilli(), rate).Scan(&tokens)
+	if err == sql.ErrNoRows {
+		// Empty bucket: nothing was updated
+		err = repository.db.QueryRowContext(ctx,
+			"SELECT MIN(?, tokens + MAX(0, ? - updated_at) * ? / 1000.0) FROM rate_limit_buckets WHERE key = ?",
+			burst, now.UnixMilli(), rate, key,
+		).Scan(&tokens)
+		return tokens, false, err
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return tokens, true, nil
+}
+
+// PruneBuckets deletes the buckets last used before idleSince.
+func (repository *RateLimitRepository) PruneBuckets(ctx context.Context, idleSince time.Time) error {
+	ctx, end := observe(ctx, "RateLimitRepository", "PruneBuckets")
+	defer end()
+
+	_, err := repository.db.ExecContext(ctx, "DELETE FROM rate_limit_buckets WHERE updated_at < ?", idleSince.UnixMilli())
+	return err
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"api/cache"
+	"context"
+	"database/sql"
+	"time"
+)
+
+// readKey identifies a cached read by the database it was made on, so that
+// tenants never see each other's entries, and the ID read ("" for lists).
+type readKey struct {
+	db *sql.DB
+	id string
+}
+
+// versioned is a read along with when what it read last changed.
+type versioned[V any] struct {
+	value    V
+	modified time.Time
+}
+
+// readCache holds the list and single-row reads of one resource. Writes
+// through the owning repository clear both; writes by other processes are
+// only seen once entries expire.
+type readCache[V any] struct {
+	list  *cache.Cache[readKey, versioned[[]V]]
+	items *cache.Cache[readKey, versioned[V]]
+}
+
+func newReadCache[V any](ttl time.Duration, maxEntries int) *readCache[V] {
+	return &readCache[V]{
+		list:  cache.New[readKey, versioned[[]V]](ttl, maxEntries),
+		items: cache.New[readKey, versioned[V]](ttl, maxEntries),
+	}
+}
+
+// getList returns the cached list of resource and when it last changed, or
+// the result of load when reads are not cached.
+func (reads *readCache[V]) getList(ctx context.Context, fallback *sql.DB, resource string, load func() ([]V, error)) ([]V, time.Time, error) {
+	db := database(ctx, fallback)
+	loadVersioned := func() (versioned[[]V], error) {
+		return readVersioned(ctx, db, resource, "", load)
+	}
+	var read versioned[[]V]
+	var err error
+	if reads == nil {
+		read, err = loadVersioned()
+	} else {
+		read, err = reads.list.Get(readKey{db: db}, loadVersioned)
+	}
+	return read.value, read.modified, err
+}
+
+// getItem returns the cached row of resource with the given ID and when it
+// last changed, or the result of load when reads are not cached.
+func (reads *readCache[V]) getItem(ctx context.Context, fallback *sql.DB, resource string, id string, load func() (V, error)) (V, time.Time, error) {
+	db := database(ctx, fallback)
+	loadVersioned := func() (versioned[V], error) {
+		return readVersioned(ctx, db, resource, id, load)
+	}
+	var read versioned[V]
+	var err error
+	if reads == nil {
+		read, err = loadVersioned()
+	} else {
+		read, err = reads.items.Get(readKey{db: db, id: id}, loadVersioned)
+	}
+	return read.value, read.modified, err
+}
+
+func (reads *readCache[V]) clear() {
+	if reads != nil {
+		reads.list.Clear()
+		reads.items.Clear()
+	}
+}
+
+// readVersioned reads when resource (or its record with the given ID) last
+// changed before calling load, so that a write in between makes the time
+// older than the value rather than newer.
+func readVersioned[V any](ctx context.Context, db *sql.DB, resource string, id string, load func() (V, error)) (versioned[V], error) {
+	modified, err := lastChange(ctx, db, resource, id)
+	if err != nil {
+		return versioned[V]{}, err
+	}
+	value, err := load()
+	if err != nil {
+		return versioned[V]{}, err
+	}
+	return versioned[V]{value: value, modified: modified}, nil
+}
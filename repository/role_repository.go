@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"api/model"
+	"context"
+	"errors"
+	"slices"
+
+	"database/sql"
+)
+
+// ErrUnknownRole is returned when assigning a role that does not exist.
+var ErrUnknownRole = errors.New("unknown role")
+
+type RoleRepository struct {
+	db *sql.DB
+}
+
+func NewRoleRepository(db *sql.DB) *RoleRepository {
+	return &RoleRepository{
+		db: db,
+	}
+}
+
+func (repository *RoleRepository) GetRoles(ctx context.Context) ([]model.Role, error) {
+	ctx, end := observe(ctx, "RoleRepository", "GetRoles")
+	defer end()
+
+	var roles []model.Role = []model.Role{}
+	rows, err := database(ctx, repository.db).QueryContext(ctx, `
+		SELECT r.name, r.description, p.permission
+		FROM roles r
+		LEFT JOIN role_permissions p ON p.role = r.name
+		ORDER BY r.name, p.permission
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, description string
+		var permission sql.NullString
+		if err := rows.Scan(&name, &description, &permission); err != nil {
+			return nil, err
+		}
+		if len(roles) == 0 || roles[len(roles)-1].Name != name {
+			roles = append(roles, model.Role{Name: name, Description: description, Permissions: []string{}})
+		}
+		if permission.Valid {
+			role := &roles[len(roles)-1]
+			role.Permissions = append(role.Permissions, permission.String)
+		}
+	}
+
+	return roles, rows.Err()
+}
+
+// SaveRole creates the role or replaces its description and permissions.
+func (repository *RoleRepository) SaveRole(ctx context.Context, role model.Role) error {
+	ctx, end := observe(ctx, "RoleRepository", "SaveRole")
+	defer end()
+
+	tx, err := database(ctx, repository.db).BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO roles (name, description) VALUES (?, ?)
+		ON CONFLICT (name) DO UPDATE SET description = excluded.description
+	`, role.Name, role.Description)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM role_permissions WHERE role = ?", role.Name)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, permission := range role.Permissions {
+		_, err = tx.ExecContext(ctx, "INSERT OR IGNORE INTO role_permissions (role, permission) VALUES (?, ?)", role.Name, permission)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (repository *RoleRepository) GetUserRoles(ctx context.Context, userID string) ([]string, error) {
+	ctx, end := observe(ctx, "RoleRepository", "GetUserRoles")
+	defer end()
+
+	return queryStrings(ctx, database(ctx, repository.db), "SELECT role FROM user_roles WHERE user_id = ? ORDER BY role", userID)
+}
+
+// SetUserRoles replaces the roles of a user. It returns sql.ErrNoRows when
+// the user does not exist and ErrUnknownRole when a role does not.
+func (repository *RoleRepository) SetUserRoles(ctx context.Context, userID string, roles []string) error {
+	ctx, end := observe(ctx, "RoleRepository", "SetUserRoles")
+	defer end()
+
+	tx, err := database(ctx, repository.db).BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE id = ?)", userID).Scan(&exists)
+	if err == nil && !exists {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	known, err := queryStrings(ctx, tx, "SELECT name FROM roles")
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	for _, role := range roles {
+		if !slices.Contains(known, role) {
+			tx.Rollback()
+			return ErrUnknownRole
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM user_roles WHERE user_id = ?", userID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, role := range roles {
+		_, err = tx.ExecContext(ctx, "INSERT OR IGNORE INTO user_roles (user_id, role) VALUES (?, ?)", userID, role)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetUserPermissions returns the permissions granted to a user by all of its roles.
+func (repository *RoleRepository) GetUserPermissions(ctx context.Context, userID string) ([]string, error) {
+	ctx, end := observe(ctx, "RoleRepository", "GetUserPermissions")
+	defer end()
+
+	return queryStrings(ctx, database(ctx, repository.db), `
+		SELECT DISTINCT p.permission
+		FROM user_roles u
+		JOIN role_permissions p ON p.role = u.role
+		WHERE u.user_id = ?
+		ORDER BY p.permission
+	`, userID)
+}
+
+// queryStrings returns the single text column of every row.
+func queryStrings(ctx context.Context, q queryer, query string, args ...any) ([]string, error) {
+	var values []string = []string{}
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	return values, rows.Err()
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"api/model"
+	"context"
+	"time"
+
+	"database/sql"
+)
+
+const tenantColumns = "id, name, IFNULL(host, ''), created_at"
+
+// TenantRepository manages the tenant registry. Unlike the other
+// repositories it always uses the database it was created with, the main
+// database, whatever database the context selects.
+type TenantRepository struct {
+	db *sql.DB
+}
+
+func NewTenantRepository(db *sql.DB) *TenantRepository {
+	return &TenantRepository{
+		db: db,
+	}
+}
+
+func (repository *TenantRepository) GetTenants(ctx context.Context) ([]model.Tenant, error) {
+	ctx, end := observe(ctx, "TenantRepository", "GetTenants")
+	defer end()
+
+	var tenants []model.Tenant = []model.Tenant{}
+	rows, err := repository.db.QueryContext(ctx, "SELECT "+tenantColumns+" FROM tenants ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tenant model.Tenant
+		if err := rows.Scan(&tenant.ID, &tenant.Name, &tenant.Host, &tenant.CreatedAt); err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, tenant)
+	}
+	return tenants, rows.Err()
+}
+
+// CreateTenant registers the tenant, setting its creation time, and returns
+// it. Duplicate IDs and hosts are conflicts.
+func (repository *TenantRepository) CreateTenant(ctx context.Context, tenant model.Tenant) (model.Tenant, error) {
+	ctx, end := observe(ctx, "TenantRepository", "CreateTenant")
+	defer end()
+
+	tenant.CreatedAt = formatTimestamp(time.Now())
+	var host any
+	if tenant.Host != "" {
+		host = tenant.Host
+	}
+	_, err := repository.db.ExecContext(ctx,
+		"INSERT INTO tenants (id, name, host, created_at) VALUES (?, ?, ?, ?)",
+		tenant.ID, tenant.Name, host, tenant.CreatedAt,
+	)
+	return tenant, err
+}
+
+// DeleteTenant removes the tenant from the registry, returning
+// sql.ErrNoRows when it does not exist.
+func (repository *TenantRepository) DeleteTenant(ctx context.Context, id string) error {
+	ctx, end := observe(ctx, "TenantRepository", "DeleteTenant")
+	defer end()
+
+	result, err := repository.db.ExecContext(ctx, "DELETE FROM tenants WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
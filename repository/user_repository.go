@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"api/model"
+	"context"
+	"time"
+
+	"database/sql"
+)
+
+type UserRepository struct {
+	db *sql.DB
+}
+
+func NewUserRepository(db *sql.DB) *UserRepository {
+	return &UserRepository{
+		db: db,
+	}
+}
+
+func (repository *UserRepository) GetUsers(ctx context.Context) ([]model.User, error) {
+	ctx, end := observe(ctx, "UserRepository", "GetUsers")
+	defer end()
+
+	var users []model.User = []model.User{}
+	rows, err := database(ctx, repository.db).QueryContext(ctx, "SELECT id, name, email, created_at FROM users ORDER BY created_at, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var user model.User
+		err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+func (repository *UserRepository) GetUserByID(ctx context.Context, id string) (model.User, error) {
+	ctx, end := observe(ctx, "UserRepository", "GetUserByID")
+	defer end()
+
+	var user model.User
+	row := database(ctx, repository.db).QueryRowContext(ctx, "SELECT id, name, email, created_at FROM users WHERE id = ?", id)
+	err := row.Scan(&user.ID, &user.Name, &user.Email, &user.CreatedAt)
+	if err != nil {
+		return user, err
+	}
+	return user, nil
+}
+
+// CreateUser inserts the user, setting its creation time, and returns it.
+func (repository *UserRepository) CreateUser(ctx context.Context, user model.User) (model.User, error) {
+	ctx, end := observe(ctx, "UserRepository", "CreateUser")
+	defer end()
+
+	user.CreatedAt = formatTimestamp(time.Now())
+	_, err := database(ctx, repository.db).ExecContext(ctx,
+		"INSERT INTO users (id, name, email, created_at) VALUES (?, ?, ?, ?)",
+		user.ID, user.Name, user.Email, user.CreatedAt,
+	)
+	if err != nil {
+		return user, err
+	}
+	return user, nil
+}
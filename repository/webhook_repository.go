@@ -0,0 +1,416 @@
+package repository
+
+import (
+	"api/model"
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"database/sql"
+)
+
+// PendingWebhookDelivery is a delivery that is due, together with what is
+// needed to send it.
+type PendingWebhookDelivery struct {
+	Delivery model.WebhookDelivery
+	URL      string
+	Secret   string
+	Payload  []byte
+}
+
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{
+		db: db,
+	}
+}
+
+// scanner is satisfied by both *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+const webhookSubscriptionColumns = "id, url, event_types, active, consecutive_failures, disabled_at, created_at"
+
+func scanWebhookSubscription(row scanner) (model.WebhookSubscription, error) {
+	var subscription model.WebhookSubscription
+	var eventTypes string
+	err := row.Scan(
+		&subscription.ID, &subscription.URL, &eventTypes, &subscription.Active,
+		&subscription.ConsecutiveFailures, &subscription.DisabledAt, &subscription.CreatedAt,
+	)
+	if err != nil {
+		return subscription, err
+	}
+	err = json.Unmarshal([]byte(eventTypes), &subscription.EventTypes)
+	if err != nil {
+		return subscription, err
+	}
+	return subscription, nil
+}
+
+func (repository *WebhookRepository) GetWebhookSubscriptions(ctx context.Context) ([]model.WebhookSubscription, error) {
+	ctx, end := observe(ctx, "WebhookRepository", "GetWebhookSubscriptions")
+	defer end()
+
+	var subscriptions []model.WebhookSubscription = []model.WebhookSubscription{}
+	rows, err := database(ctx, repository.db).QueryContext(ctx, "SELECT "+webhookSubscriptionColumns+" FROM webhook_subscriptions ORDER BY created_at, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		subscription, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	return subscriptions, rows.Err()
+}
+
+func (repository *WebhookRepository) GetWebhookSubscriptionByID(ctx context.Context, id string) (model.WebhookSubscription, error) {
+	ctx, end := observe(ctx, "WebhookRepository", "GetWebhookSubscriptionByID")
+	defer end()
+
+	row := database(ctx, repository.db).QueryRowContext(ctx, "SELECT "+webhookSubscriptionColumns+" FROM webhook_subscriptions WHERE id = ?", id)
+	return scanWebhookSubscription(row)
+}
+
+func (repository *WebhookRepository) CreateWebhookSubscription(ctx context.Context, subscription model.WebhookSubscription) error {
+	ctx, end := observe(ctx, "WebhookRepository", "CreateWebhookSubscription")
+	defer end()
+
+	eventTypes, err := json.Marshal(nonNilStrings(subscription.EventTypes))
+	if err != nil {
+		return err
+	}
+	_, err = database(ctx, repository.db).ExecContext(ctx,
+		"INSERT INTO webhook_subscriptions (id, url, event_types, secret, active, created_at) VALUES (?, ?, ?, ?, 1, ?)",
+		subscription.ID, subscription.URL, string(eventTypes), subscription.Secret, formatTimestamp(time.Now()),
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpdateWebhookSubscription replaces the subscription's settings. An empty
+// secret keeps the current one. Activating a subscription clears its failure
+// count, re-enabling endpoints that were disabled automatically. It returns
+// sql.ErrNoRows when the subscription does not exist.
+func (repository *WebhookRepository) UpdateWebhookSubscription(ctx context.Context, subscription model.WebhookSubscription) error {
+	ctx, end := observe(ctx, "WebhookRepository", "UpdateWebhookSubscription")
+	defer end()
+
+	eventTypes, err := json.Marshal(nonNilStrings(subscription.EventTypes))
+	if err != nil {
+		return err
+	}
+	result, err := database(ctx, repository.db).ExecContext(ctx, `
+		UPDATE webhook_subscriptions
+		SET url = ?, event_types = ?,
+		    secret = CASE WHEN ? = '' THEN secret ELSE ? END,
+		    active = ?,
+		    consecutive_failures = CASE WHEN ? THEN 0 ELSE consecutive_failures END,
+		    disabled_at = CASE WHEN ? THEN NULL ELSE disabled_at END
+		WHERE id = ?
+	`, subscription.URL, string(eventTypes), subscription.Secret, subscription.Secret, subscription.Active,
+		subscription.Active, subscription.Active, subscription.ID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteWebhookSubscription deletes the subscription and its deliveries. It
+// returns sql.ErrNoRows when the subscription does not exist.
+func (repository *WebhookRepository) DeleteWebhookSubscription(ctx context.Context, id string) error {
+	ctx, end := observe(ctx, "WebhookRepository", "DeleteWebhookSubscription")
+	defer end()
+
+	tx, err := database(ctx, repository.db).BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM webhook_deliveries WHERE subscription_id = ?", id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM webhook_subscriptions WHERE id = ?", id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if affected == 0 {
+		tx.Rollback()
+		return sql.ErrNoRows
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// EnqueueWebhookDeliveries creates a pending delivery of event for every active
+// subscription interested in its type. Enqueuing the same event twice is a no-op.
+func (repository *WebhookRepository) EnqueueWebhookDeliveries(ctx context.Context, event model.Event) error {
+	ctx, end := observe(ctx, "WebhookRepository", "EnqueueWebhookDeliveries")
+	defer end()
+
+	subscriptions, err := repository.GetWebhookSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	now := formatTimestamp(time.Now())
+	for _, subscription := range subscriptions {
+		if !subscription.Active {
+			continue
+		}
+		if len(subscription.EventTypes) > 0 && !slices.Contains(subscription.EventTypes, event.Type) {
+			continue
+		}
+		_, err = database(ctx, repository.db).ExecContext(ctx, `
+			INSERT OR IGNORE INTO webhook_deliveries (subscription_id, event_id, event_type, payload, status, next_attempt_at, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, subscription.ID, event.ID, event.Type, string(payload), model.WebhookDeliveryPending, now, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+const webhookDeliveryColumns = `
+	d.id, d.subscription_id, d.event_id, d.event_type, d.status, d.attempts, d.response_status,
+	d.last_error, d.replay_of, d.next_attempt_at, d.created_at, d.delivered_at`
+
+func scanWebhookDelivery(row scanner, extra ...any) (model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	dest := []any{
+		&delivery.ID, &delivery.SubscriptionID, &delivery.EventID, &delivery.EventType, &delivery.Status,
+		&delivery.Attempts, &delivery.ResponseStatus, &delivery.LastError, &delivery.ReplayOf,
+		&delivery.NextAttemptAt, &delivery.CreatedAt, &delivery.DeliveredAt,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	return delivery, err
+}
+
+// GetDueWebhookDeliveries returns up to limit pending deliveries of active
+// subscriptions whose next attempt is due at now, oldest first.
+func (repository *WebhookRepository) GetDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]PendingWebhookDelivery, error) {
+	ctx, end := observe(ctx, "WebhookRepository", "GetDueWebhookDeliveries")
+	defer end()
+
+	var pending []PendingWebhookDelivery = []PendingWebhookDelivery{}
+	rows, err := database(ctx, repository.db).QueryContext(ctx, `
+		SELECT `+webhookDeliveryColumns+`, s.url, s.secret, d.payload
+		FROM webhook_deliveries d
+		INNER JOIN webhook_subscriptions s ON d.subscription_id = s.id
+		WHERE d.status = ? AND d.next_attempt_at <= ? AND s.active = 1
+		ORDER BY d.id
+		LIMIT ?
+	`, model.WebhookDeliveryPending, formatTimestamp(now), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item PendingWebhookDelivery
+		var payload string
+		item.Delivery, err = scanWebhookDelivery(rows, &item.URL, &item.Secret, &payload)
+		if err != nil {
+			return nil, err
+		}
+		item.Payload = []byte(payload)
+		pending = append(pending, item)
+	}
+
+	return pending, rows.Err()
+}
+
+func (repository *WebhookRepository) MarkWebhookDeliverySucceeded(ctx context.Context, delivery model.WebhookDelivery, responseStatus int, deliveredAt time.Time) error {
+	ctx, end := observe(ctx, "WebhookRepository", "MarkWebhookDeliverySucceeded")
+	defer end()
+
+	tx, err := database(ctx, repository.db).BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = attempts + 1, response_status = ?, last_error = NULL, delivered_at = ?
+		WHERE id = ?
+	`, model.WebhookDeliverySucceeded, responseStatus, formatTimestamp(deliveredAt), delivery.ID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE webhook_subscriptions SET consecutive_failures = 0 WHERE id = ?", delivery.SubscriptionID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// MarkWebhookDeliveryFailed records a failed attempt. A nil nextAttemptAt gives
+// up on the delivery. The subscription is disabled once it has failed
+// disableAfter times in a row; the returned bool reports whether that happened.
+func (repository *WebhookRepository) MarkWebhookDeliveryFailed(ctx context.Context, delivery model.WebhookDelivery, responseStatus *int, deliveryErr error, nextAttemptAt *time.Time, disableAfter int) (bool, error) {
+	ctx, end := observe(ctx, "WebhookRepository", "MarkWebhookDeliveryFailed")
+	defer end()
+
+	tx, err := database(ctx, repository.db).BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+
+	status := model.WebhookDeliveryFailed
+	next := delivery.NextAttemptAt
+	if nextAttemptAt != nil {
+		status = model.WebhookDeliveryPending
+		next = formatTimestamp(*nextAttemptAt)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = attempts + 1, response_status = ?, last_error = ?, next_attempt_at = ?
+		WHERE id = ?
+	`, status, responseStatus, deliveryErr.Error(), next, delivery.ID)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	var failures int
+	err = tx.QueryRowContext(ctx, `
+		UPDATE webhook_subscriptions SET consecutive_failures = consecutive_failures + 1
+		WHERE id = ?
+		RETURNING consecutive_failures
+	`, delivery.SubscriptionID).Scan(&failures)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	disabled := disableAfter > 0 && failures >= disableAfter
+	if disabled {
+		_, err = tx.ExecContext(ctx,
+			"UPDATE webhook_subscriptions SET active = 0, disabled_at = ? WHERE id = ? AND active = 1",
+			formatTimestamp(time.Now()), delivery.SubscriptionID,
+		)
+		if err != nil {
+			tx.Rollback()
+			return false, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return false, err
+	}
+
+	return disabled, nil
+}
+
+func (repository *WebhookRepository) GetWebhookDeliveries(ctx context.Context, subscriptionID string) ([]model.WebhookDelivery, error) {
+	ctx, end := observe(ctx, "WebhookRepository", "GetWebhookDeliveries")
+	defer end()
+
+	var deliveries []model.WebhookDelivery = []model.WebhookDelivery{}
+	rows, err := database(ctx, repository.db).QueryContext(ctx, `
+		SELECT `+webhookDeliveryColumns+`
+		FROM webhook_deliveries d
+		WHERE d.subscription_id = ?
+		ORDER BY d.id
+	`, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// ReplayWebhookDelivery queues a new pending delivery with the same payload
+// as an earlier one and returns it. The original stays in the log untouched.
+func (repository *WebhookRepository) ReplayWebhookDelivery(ctx context.Context, subscriptionID string, deliveryID int64) (model.WebhookDelivery, error) {
+	ctx, end := observe(ctx, "WebhookRepository", "ReplayWebhookDelivery")
+	defer end()
+
+	now := formatTimestamp(time.Now())
+	row := database(ctx, repository.db).QueryRowContext(ctx, `
+		INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload, status, replay_of, next_attempt_at, created_at)
+		SELECT subscription_id, event_id, event_type, payload, ?, id, ?, ?
+		FROM webhook_deliveries
+		WHERE id = ? AND subscription_id = ?
+		RETURNING id
+	`, model.WebhookDeliveryPending, now, now, deliveryID, subscriptionID)
+
+	var id int64
+	if err := row.Scan(&id); err != nil {
+		return model.WebhookDelivery{}, err
+	}
+
+	return scanWebhookDelivery(database(ctx, repository.db).QueryRowContext(ctx, `
+		SELECT `+webhookDeliveryColumns+`
+		FROM webhook_deliveries d
+		WHERE d.id = ?
+	`, id))
+}
+
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"api/auth"
+	"api/handler"
+	"api/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupAdminRoutes(api fiber.Router, adminHandler *handler.AdminHandler) {
+	router := api.Group("/admin")
+	router.Get("/log-level", middleware.Require(auth.PermissionAdmin), adminHandler.GetLogLevel)
+	router.Put("/log-level", middleware.Require(auth.PermissionAdmin), adminHandler.SetLogLevel)
+}
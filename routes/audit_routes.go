@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"api/auth"
+	"api/handler"
+	"api/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupAuditRoutes(api fiber.Router, auditHandler *handler.AuditHandler) {
+	router := api.Group("/audit")
+	router.Get("", middleware.Require(auth.PermissionAuditRead), auditHandler.GetAuditEntries)
+}
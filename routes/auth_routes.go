@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"api/handler"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupAuthRoutes(api fiber.Router, authHandler *handler.AuthHandler) {
+	api.Get("/auth/keys", authHandler.GetAPIKeys)
+	api.Post("/auth/keys", authHandler.CreateAPIKey)
+	api.Delete("/auth/keys/:id", authHandler.RevokeAPIKey)
+	api.Get("/auth/keys/:id/usage", authHandler.GetAPIKeyUsage)
+}
@@ -1,16 +1,21 @@
 package routes
 
 import (
+	"api/auth"
 	"api/handler"
+	"api/middleware"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-func SetupCustomerRoutes(app *fiber.App, customerHandler *handler.CustomerHandler) {
-	router := app.Group("/customers")
-	router.Get("", customerHandler.GetCustomers)
-	router.Get("/:id", customerHandler.GetCustomerByID)
-	router.Post("", customerHandler.CreateCustomer)
-	router.Put("/:id", customerHandler.UpdateCustomer)
-	router.Delete("/:id", customerHandler.DeleteCustomer)
+func SetupCustomerRoutes(api fiber.Router, customerHandler *handler.CustomerHandler) {
+	router := api.Group("/customers")
+	router.Get("", middleware.Require(auth.PermissionCustomersRead), customerHandler.GetCustomers)
+	router.Get("/export", middleware.Require(auth.PermissionCustomersRead), customerHandler.ExportCustomers)
+	router.Post("/import", middleware.Require(auth.PermissionCustomersWrite), customerHandler.ImportCustomers)
+	router.Get("/:id", middleware.Require(auth.PermissionCustomersRead), customerHandler.GetCustomerByID)
+	router.Post("", middleware.Require(auth.PermissionCustomersWrite), customerHandler.CreateCustomer)
+	router.Put("/:id", middleware.Require(auth.PermissionCustomersWrite), customerHandler.UpdateCustomer)
+	router.Delete("/:id", middleware.Require(auth.PermissionCustomersWrite), customerHandler.DeleteCustomer)
+	api.Post("/customers\\:batch", middleware.Require(auth.PermissionCustomersWrite), customerHandler.BatchCustomers)
 }
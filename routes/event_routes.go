@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"api/auth"
+	"api/handler"
+	"api/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupEventRoutes(api fiber.Router, eventHandler *handler.EventHandler) {
+	router := api.Group("/events")
+	router.Get("", middleware.Require(auth.PermissionEventsRead), eventHandler.StreamEvents)
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"api/handler"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetupGraphQLRoutes mounts the GraphQL endpoint, which checks the
+// permissions of each field itself.
+func SetupGraphQLRoutes(api fiber.Router, graphQLHandler *handler.GraphQLHandler) {
+	api.Post("/graphql", graphQLHandler.Query)
+}
@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"api/handler"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupHealthRoutes(app *fiber.App, healthHandler *handler.HealthHandler) {
+	app.Get("/healthz", healthHandler.Liveness)
+	app.Get("/readyz", healthHandler.Readiness)
+}
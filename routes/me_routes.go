@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"api/auth"
+	"api/handler"
+	"api/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupMeRoutes(api fiber.Router, meHandler *handler.MeHandler) {
+	api.Get("/me", middleware.Require(auth.PermissionOwnCustomerRead), meHandler.GetMe)
+	api.Get("/me/orders", middleware.Require(auth.PermissionOwnOrdersRead), meHandler.GetMyOrders)
+}
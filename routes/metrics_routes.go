@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"api/metrics"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupMetricsRoutes(app *fiber.App, metrics *metrics.Metrics) {
+	app.Get("/metrics", metrics.Handler())
+}
@@ -1,16 +1,21 @@
 package routes
 
 import (
+	"api/auth"
 	"api/handler"
+	"api/middleware"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-func SetupOrderRoutes(app *fiber.App, orderHandler *handler.OrderHandler) {
-	router := app.Group("/orders")
-	router.Get("", orderHandler.GetOrders)
-	router.Get("/:id", orderHandler.GetOrderByID)
-	router.Post("", orderHandler.CreateOrder)
-	router.Put("/:id", orderHandler.UpdateOrder)
-	router.Delete("/:id", orderHandler.DeleteOrder)
+func SetupOrderRoutes(api fiber.Router, orderHandler *handler.OrderHandler) {
+	router := api.Group("/orders")
+	router.Get("", middleware.Require(auth.PermissionOwnOrdersRead), orderHandler.GetOrders)
+	router.Get("/export", middleware.Require(auth.PermissionOrdersRead), orderHandler.ExportOrders)
+	router.Post("/import", middleware.Require(auth.PermissionOrdersWrite), orderHandler.ImportOrders)
+	router.Get("/:id", middleware.Require(auth.PermissionOwnOrdersRead), orderHandler.GetOrderByID)
+	router.Post("", middleware.Require(auth.PermissionOwnOrdersWrite), orderHandler.CreateOrder)
+	router.Put("/:id", middleware.Require(auth.PermissionOwnOrdersWrite), orderHandler.UpdateOrder)
+	router.Delete("/:id", middleware.Require(auth.PermissionOrdersDelete), orderHandler.DeleteOrder)
+	api.Post("/orders\\:batch", middleware.Require(auth.PermissionOrdersWrite), orderHandler.BatchOrders)
 }
@@ -1,16 +1,21 @@
 package routes
 
 import (
+	"api/auth"
 	"api/handler"
+	"api/middleware"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-func SetupProductRoutes(app *fiber.App, productHandler *handler.ProductHandler) {
-	router := app.Group("/products")
-	router.Get("", productHandler.GetProducts)
-	router.Get("/:id", productHandler.GetProductByID)
-	router.Post("", productHandler.CreateProduct)
-	router.Put("/:id", productHandler.UpdateProduct)
-	router.Delete("/:id", productHandler.DeleteProduct)
+func SetupProductRoutes(api fiber.Router, productHandler *handler.ProductHandler) {
+	router := api.Group("/products")
+	router.Get("", middleware.Require(auth.PermissionProductsRead), productHandler.GetProducts)
+	router.Get("/export", middleware.Require(auth.PermissionProductsRead), productHandler.ExportProducts)
+	router.Post("/import", middleware.Require(auth.PermissionProductsWrite), productHandler.ImportProducts)
+	router.Get("/:id", middleware.Require(auth.PermissionProductsRead), productHandler.GetProductByID)
+	router.Post("", middleware.Require(auth.PermissionProductsWrite), productHandler.CreateProduct)
+	router.Put("/:id", middleware.Require(auth.PermissionProductsWrite), productHandler.UpdateProduct)
+	router.Delete("/:id", middleware.Require(auth.PermissionProductsWrite), productHandler.DeleteProduct)
+	api.Post("/products\\:batch", middleware.Require(auth.PermissionProductsWrite), productHandler.BatchProducts)
 }
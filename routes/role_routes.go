@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"api/auth"
+	"api/handler"
+	"api/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupRoleRoutes(api fiber.Router, roleHandler *handler.RoleHandler) {
+	router := api.Group("/admin")
+	router.Get("/roles", middleware.Require(auth.PermissionAdmin), roleHandler.GetRoles)
+	router.Put("/roles/:name", middleware.Require(auth.PermissionAdmin), roleHandler.SaveRole)
+	router.Get("/users/:id/roles", middleware.Require(auth.PermissionAdmin), roleHandler.GetUserRoles)
+	router.Put("/users/:id/roles", middleware.Require(auth.PermissionAdmin), roleHandler.SetUserRoles)
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"api/auth"
+	"api/handler"
+	"api/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupTenantRoutes(api fiber.Router, tenantHandler *handler.TenantHandler) {
+	router := api.Group("/admin")
+	router.Get("/tenants", middleware.Require(auth.PermissionAdmin), tenantHandler.GetTenants)
+	router.Post("/tenants", middleware.Require(auth.PermissionAdmin), tenantHandler.CreateTenant)
+	router.Delete("/tenants/:id", middleware.Require(auth.PermissionAdmin), tenantHandler.DeleteTenant)
+}
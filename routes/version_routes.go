@@ -0,0 +1,78 @@
+package routes
+
+import (
+	"api/config"
+	"api/handler"
+	"api/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// LatestVersion is the version deprecated routes link to as their successor.
+const LatestVersion = "v2"
+
+// Handlers serve the routes of every API version.
+type Handlers struct {
+	Product  *handler.ProductHandler
+	Customer *handler.CustomerHandler
+	Order    *handler.OrderHandler
+	Audit    *handler.AuditHandler
+	Webhook  *handler.WebhookHandler
+	Event    *handler.EventHandler
+	Admin    *handler.AdminHandler
+	Auth     *handler.AuthHandler
+	Role     *handler.RoleHandler
+	Me       *handler.MeHandler
+	// Tenant is nil when tenancy is disabled.
+	Tenant *handler.TenantHandler
+	// GraphQL is served outside of the versions, nil to leave it out.
+	GraphQL *handler.GraphQLHandler
+}
+
+// SetupVersionRoutes mounts v1 and v2 under their prefix and v1 again at
+// the root for clients predating versioning, marking the versions in
+// schedule as deprecated. GraphQL, which evolves its schema instead, is
+// mounted at the root without deprecation.
+func SetupVersionRoutes(app *fiber.App, handlers Handlers, schedule map[string]config.Deprecation) {
+	SetupV1Routes(app.Group("/v1", deprecated(schedule, "v1", "/v1")...), handlers)
+	SetupV2Routes(app.Group("/v2", deprecated(schedule, "v2", "/v2")...), handlers)
+	if handlers.GraphQL != nil {
+		SetupGraphQLRoutes(app, handlers.GraphQL)
+	}
+	// Registered last since the middleware of a group without a prefix runs
+	// for every route registered after it
+	SetupV1Routes(app.Group("", deprecated(schedule, "unversioned", "")...), handlers)
+}
+
+// SetupV1Routes mounts the v1 API on api.
+func SetupV1Routes(api fiber.Router, handlers Handlers) {
+	SetupProductRoutes(api, handlers.Product)
+	SetupCustomerRoutes(api, handlers.Customer)
+	SetupOrderRoutes(api, handlers.Order)
+	SetupAuditRoutes(api, handlers.Audit)
+	SetupWebhookRoutes(api, handlers.Webhook)
+	SetupEventRoutes(api, handlers.Event)
+	SetupAdminRoutes(api, handlers.Admin)
+	SetupAuthRoutes(api, handlers.Auth)
+	SetupRoleRoutes(api, handlers.Role)
+	SetupMeRoutes(api, handlers.Me)
+	if handlers.Tenant != nil {
+		SetupTenantRoutes(api, handlers.Tenant)
+	}
+}
+
+// SetupV2Routes mounts the v2 API on api. It differs from v1 in the
+// representation of orders, see dto.OrderV2.
+func SetupV2Routes(api fiber.Router, handlers Handlers) {
+	handlers.Order = handlers.Order.Version(handler.OrdersV2)
+	handlers.Me = handlers.Me.Version(handler.OrdersV2)
+	SetupV1Routes(api, handlers)
+}
+
+func deprecated(schedule map[string]config.Deprecation, version string, prefix string) []fiber.Handler {
+	deprecation, ok := schedule[version]
+	if !ok {
+		return nil
+	}
+	return []fiber.Handler{middleware.Deprecation(deprecation, prefix, "/"+LatestVersion)}
+}
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"api/auth"
+	"api/handler"
+	"api/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupWebhookRoutes(api fiber.Router, webhookHandler *handler.WebhookHandler) {
+	router := api.Group("/webhooks", middleware.Require(auth.PermissionWebhooksManage))
+	router.Get("", webhookHandler.GetWebhooks)
+	router.Get("/:id", webhookHandler.GetWebhookByID)
+	router.Post("", webhookHandler.CreateWebhook)
+	router.Put("/:id", webhookHandler.UpdateWebhook)
+	router.Delete("/:id", webhookHandler.DeleteWebhook)
+	router.Get("/:id/deliveries", webhookHandler.GetWebhookDeliveries)
+	router.Post("/:id/deliveries/:deliveryId/replay", webhookHandler.ReplayWebhookDelivery)
+}
@@ -0,0 +1,99 @@
+package rpc
+
+import (
+	"api/model"
+	storev1 "api/proto/store/v1"
+	"api/repository"
+	"context"
+	"database/sql"
+	"errors"
+)
+
+type customerService struct {
+	storev1.UnimplementedCustomerServiceServer
+	customerRepository *repository.CustomerRepository
+}
+
+func customerMessage(customer model.Customer) *storev1.Customer {
+	return &storev1.Customer{Id: customer.ID, Name: customer.Name}
+}
+
+// customerModel returns the customer of a create or update request.
+func customerModel(customer *storev1.Customer) (model.Customer, error) {
+	if customer.GetId() == "" {
+		return model.Customer{}, invalidArgument("customer.id is required")
+	}
+	return model.Customer{ID: customer.GetId(), Name: customer.GetName()}, nil
+}
+
+func (s *customerService) ListCustomers(ctx context.Context, req *storev1.ListCustomersRequest) (*storev1.ListCustomersResponse, error) {
+	page, err := newPage(req.GetPageSize(), req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	filter := repository.CustomerFilter{NameContains: req.GetNameContains()}
+	customers, err := s.customerRepository.ListCustomers(ctx, filter, page)
+	if err != nil {
+		return nil, internalError(ctx, "Failed to retrieve customers", err)
+	}
+	customers, token := nextPage(customers, page, func(customer model.Customer) string { return customer.ID })
+	response := &storev1.ListCustomersResponse{NextPageToken: token}
+	for _, customer := range customers {
+		response.Customers = append(response.Customers, customerMessage(customer))
+	}
+	return response, nil
+}
+
+func (s *customerService) GetCustomer(ctx context.Context, req *storev1.GetCustomerRequest) (*storev1.Customer, error) {
+	customer, err := s.customerRepository.GetCustomerByID(ctx, req.GetId())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound("Customer not found")
+	}
+	if err != nil {
+		return nil, internalError(ctx, "Failed to retrieve customer", err)
+	}
+	return customerMessage(customer), nil
+}
+
+func (s *customerService) CreateCustomer(ctx context.Context, req *storev1.CreateCustomerRequest) (*storev1.Customer, error) {
+	customer, err := customerModel(req.GetCustomer())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.customerRepository.CreateCustomer(ctx, customer); err != nil {
+		return nil, writeError(ctx, "Failed to create customer", err)
+	}
+	return customerMessage(customer), nil
+}
+
+func (s *customerService) UpdateCustomer(ctx context.Context, req *storev1.UpdateCustomerRequest) (*storev1.Customer, error) {
+	customer, err := customerModel(req.GetCustomer())
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.customerRepository.GetCustomerByID(ctx, customer.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound("Customer not found")
+	}
+	if err == nil {
+		err = s.customerRepository.UpdateCustomer(ctx, customer)
+	}
+	if err != nil {
+		return nil, writeError(ctx, "Failed to update customer", err)
+	}
+	return customerMessage(customer), nil
+}
+
+func (s *customerService) DeleteCustomer(ctx context.Context, req *storev1.DeleteCustomerRequest) (*storev1.DeleteCustomerResponse, error) {
+	_, err := s.customerRepository.GetCustomerByID(ctx, req.GetId())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound("Customer not found")
+	}
+	if err == nil {
+		err = s.customerRepository.DeleteCustomer(ctx, req.GetId())
+	}
+	if err != nil {
+		return nil, writeError(ctx, "Failed to delete customer", err)
+	}
+	return &storev1.DeleteCustomerResponse{}, nil
+}
@@ -0,0 +1,222 @@
+package rpc
+
+import (
+	"api/auth"
+	"api/middleware"
+	storev1 "api/proto/store/v1"
+	"api/ratelimit"
+	"api/repository"
+	"api/tenant"
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// method declares what a call of a method needs: the permission of the
+// matching REST route, and the route group it is throttled in.
+type method struct {
+	permission string
+	group      string
+}
+
+var methods = map[string]method{
+	storev1.ProductService_ListProducts_FullMethodName:  {auth.PermissionProductsRead, "products"},
+	storev1.ProductService_GetProduct_FullMethodName:    {auth.PermissionProductsRead, "products"},
+	storev1.ProductService_CreateProduct_FullMethodName: {auth.PermissionProductsWrite, "products"},
+	storev1.ProductService_UpdateProduct_FullMethodName: {auth.PermissionProductsWrite, "products"},
+	storev1.ProductService_DeleteProduct_FullMethodName: {auth.PermissionProductsWrite, "products"},
+
+	storev1.CustomerService_ListCustomers_FullMethodName:  {auth.PermissionCustomersRead, "customers"},
+	storev1.CustomerService_GetCustomer_FullMethodName:    {auth.PermissionCustomersRead, "customers"},
+	storev1.CustomerService_CreateCustomer_FullMethodName: {auth.PermissionCustomersWrite, "customers"},
+	storev1.CustomerService_UpdateCustomer_FullMethodName: {auth.PermissionCustomersWrite, "customers"},
+	storev1.CustomerService_DeleteCustomer_FullMethodName: {auth.PermissionCustomersWrite, "customers"},
+
+	storev1.OrderService_ListOrders_FullMethodName:  {auth.PermissionOwnOrdersRead, "orders"},
+	storev1.OrderService_GetOrder_FullMethodName:    {auth.PermissionOwnOrdersRead, "orders"},
+	storev1.OrderService_CreateOrder_FullMethodName: {auth.PermissionOwnOrdersWrite, "orders"},
+	storev1.OrderService_UpdateOrder_FullMethodName: {auth.PermissionOwnOrdersWrite, "orders"},
+	storev1.OrderService_DeleteOrder_FullMethodName: {auth.PermissionOrdersDelete, "orders"},
+}
+
+// header returns the first value of the metadata key name, which is
+// lowercase like every gRPC metadata key.
+func header(ctx context.Context, name string) string {
+	if values := metadata.ValueFromIncomingContext(ctx, name); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// bearerToken returns the credential of an "authorization: Bearer" entry.
+func bearerToken(ctx context.Context) string {
+	scheme, token, ok := strings.Cut(header(ctx, "authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// tenant scopes the call to the tenant served on its :authority or named by
+// its bearer JWT, like middleware.Tenant.
+func (options Options) tenant(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if options.Tenants == nil {
+		return handler(ctx, req)
+	}
+	id, ok := options.Tenants.Resolve(header(ctx, ":authority"))
+	if !ok {
+		if token := bearerToken(ctx); token != "" && !auth.IsAPIKey(token) {
+			id = options.Authenticator.TokenTenant(token)
+		}
+	}
+	if id == "" {
+		return handler(ctx, req)
+	}
+	ctx, err := options.Tenants.Context(ctx, id)
+	if errors.Is(err, tenant.ErrUnknownTenant) {
+		return nil, notFound("Unknown tenant")
+	}
+	if err != nil {
+		return nil, internalError(ctx, "Failed to resolve tenant", err)
+	}
+	return handler(ctx, req)
+}
+
+// actor stores the caller named in the x-actor entry on the context, like
+// middleware.Actor.
+func actor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if actor := header(ctx, strings.ToLower(middleware.ActorHeader)); actor != "" {
+		ctx = repository.WithActor(ctx, actor)
+	}
+	return handler(ctx, req)
+}
+
+// authenticate identifies the caller from an "authorization: Bearer" JWT or
+// API key, or an x-api-key entry, like middleware.Authenticate.
+func (options Options) authenticate(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	credential := header(ctx, strings.ToLower(middleware.APIKeyHeader))
+	isAPIKey := credential != ""
+	if !isAPIKey {
+		credential = bearerToken(ctx)
+		isAPIKey = auth.IsAPIKey(credential)
+	}
+	if credential == "" {
+		if options.AuthRequired {
+			return nil, statusError(http.StatusUnauthorized, "Authentication required")
+		}
+		return handler(options.Authenticator.Anonymous(ctx), req)
+	}
+
+	authenticate := options.Authenticator.AuthenticateToken
+	if isAPIKey {
+		authenticate = options.Authenticator.AuthenticateAPIKey
+	}
+	principal, err := authenticate(ctx, credential)
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		slog.InfoContext(ctx, "authentication failed", "error", err)
+		return nil, statusError(http.StatusUnauthorized, "Invalid credentials")
+	}
+	if err != nil {
+		return nil, internalError(ctx, "Failed to authenticate", err)
+	}
+
+	ctx = auth.WithPrincipal(ctx, principal)
+	return handler(repository.WithActor(ctx, auth.Actor(principal)), req)
+}
+
+// rateLimitIP throttles calls per client IP ahead of authentication, like
+// middleware.RateLimitIP.
+func (options Options) rateLimitIP(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if options.Limiter == nil {
+		return handler(ctx, req)
+	}
+	if err := rateLimited(ctx, options.Limiter.TakeIP(ctx, clientIP(ctx))); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// rateLimit throttles the authenticated caller, overall and per route
+// group, and enforces the daily quota of the API key used, like
+// middleware.RateLimit. Rejections carry a retry-after entry in seconds.
+func (options Options) rateLimit(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if options.Limiter == nil {
+		return handler(ctx, req)
+	}
+	principal, authenticated := auth.PrincipalFromContext(ctx)
+	caller := "ip:" + clientIP(ctx)
+	if authenticated {
+		caller = "principal:" + auth.Actor(principal)
+		if principal.KeyID != "" {
+			caller = "key:" + principal.KeyID
+		}
+		if id := tenant.FromContext(ctx); id != "" {
+			caller = id + "/" + caller
+		}
+	}
+	if err := rateLimited(ctx, options.Limiter.TakeCaller(ctx, caller, methods[info.FullMethod].group)); err != nil {
+		return nil, err
+	}
+
+	if authenticated && principal.KeyID != "" {
+		now := time.Now()
+		quota := options.Limiter.TakeQuota(ctx, principal, now)
+		if !quota.Allowed {
+			setRetryAfter(ctx, quota.Reset.Sub(now))
+			return nil, statusError(http.StatusTooManyRequests, "Daily quota of "+strconv.Itoa(quota.Limit)+" requests exceeded")
+		}
+	}
+	return handler(ctx, req)
+}
+
+func rateLimited(ctx context.Context, result ratelimit.Result) error {
+	if result.Allowed {
+		return nil
+	}
+	setRetryAfter(ctx, result.RetryAfter())
+	return statusError(http.StatusTooManyRequests, "Rate limit exceeded")
+}
+
+func setRetryAfter(ctx context.Context, d time.Duration) {
+	seconds := max(1, int(math.Ceil(d.Seconds())))
+	grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(seconds)))
+}
+
+// clientIP returns the IP address of the peer of the call.
+func clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// authorize rejects calls of callers missing the permission of the method,
+// like middleware.Require. Methods declaring no permission are refused.
+func authorize(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	m, ok := methods[info.FullMethod]
+	if !ok {
+		return nil, permissionDenied("No permission is declared for " + info.FullMethod)
+	}
+	if auth.HasPermission(ctx, m.permission) {
+		return handler(ctx, req)
+	}
+	if _, ok := auth.PrincipalFromContext(ctx); !ok {
+		return nil, statusError(http.StatusUnauthorized, "Authentication required")
+	}
+	return nil, permissionDenied("Missing permission " + m.permission)
+}
@@ -0,0 +1,199 @@
+package rpc
+
+import (
+	"api/auth"
+	"api/model"
+	storev1 "api/proto/store/v1"
+	"api/repository"
+	"context"
+	"database/sql"
+	"errors"
+)
+
+type orderService struct {
+	storev1.UnimplementedOrderServiceServer
+	orderRepository *repository.OrderRepository
+}
+
+// orderMessage returns order with its customer and the products of its
+// items, when loaded.
+func orderMessage(order model.Order) *storev1.Order {
+	message := &storev1.Order{
+		Id:         order.ID,
+		OrderDate:  order.OrderDate,
+		CustomerId: order.CustomerID,
+		Items:      make([]*storev1.OrderItem, len(order.OrderItems)),
+	}
+	if order.Customer.ID != "" {
+		message.Customer = customerMessage(order.Customer)
+	}
+	for i, item := range order.OrderItems {
+		message.Items[i] = &storev1.OrderItem{
+			Id:        item.ID,
+			ProductId: item.ProductID,
+			Quantity:  int32(item.Quantity),
+			Price:     item.Price,
+		}
+		if item.Product.ID != "" {
+			message.Items[i].Product = productMessage(item.Product)
+		}
+	}
+	return message
+}
+
+// orderModel returns the order of a create or update request.
+func orderModel(order *storev1.Order) (model.Order, error) {
+	if order.GetId() == "" {
+		return model.Order{}, invalidArgument("order.id is required")
+	}
+	result := model.Order{
+		ID:         order.GetId(),
+		OrderDate:  order.GetOrderDate(),
+		CustomerID: order.GetCustomerId(),
+		OrderItems: make([]model.OrderItem, len(order.GetItems())),
+	}
+	for i, item := range order.GetItems() {
+		result.OrderItems[i] = model.OrderItem{
+			ID:        item.GetId(),
+			OrderID:   result.ID,
+			ProductID: item.GetProductId(),
+			Quantity:  int(item.GetQuantity()),
+			Price:     item.GetPrice(),
+		}
+	}
+	return result, nil
+}
+
+func (s *orderService) ListOrders(ctx context.Context, req *storev1.ListOrdersRequest) (*storev1.ListOrdersResponse, error) {
+	page, err := newPage(req.GetPageSize(), req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	filter := repository.OrderFilter{
+		CustomerID: req.GetCustomerId(),
+		From:       req.GetFrom(),
+		To:         req.GetTo(),
+	}
+	if customerID, scoped := auth.OwnCustomer(ctx, auth.PermissionOrdersRead); scoped {
+		// Other customers' orders do not exist for customers
+		if filter.CustomerID != "" && filter.CustomerID != customerID {
+			return &storev1.ListOrdersResponse{}, nil
+		}
+		filter.CustomerID = customerID
+	}
+	orders, err := s.orderRepository.ListOrders(ctx, filter, page, repository.AllOrderIncludes)
+	if err != nil {
+		return nil, internalError(ctx, "Failed to retrieve orders", err)
+	}
+	orders, token := nextPage(orders, page, func(order model.Order) string { return order.ID })
+	response := &storev1.ListOrdersResponse{NextPageToken: token}
+	for _, order := range orders {
+		response.Orders = append(response.Orders, orderMessage(order))
+	}
+	return response, nil
+}
+
+func (s *orderService) GetOrder(ctx context.Context, req *storev1.GetOrderRequest) (*storev1.Order, error) {
+	order, err := s.ownOrder(ctx, req.GetId(), auth.PermissionOrdersRead)
+	if err != nil {
+		return nil, err
+	}
+	return orderMessage(order), nil
+}
+
+func (s *orderService) CreateOrder(ctx context.Context, req *storev1.CreateOrderRequest) (*storev1.Order, error) {
+	order, err := orderModel(req.GetOrder())
+	if err != nil {
+		return nil, err
+	}
+	if customerID, scoped := auth.OwnCustomer(ctx, auth.PermissionOrdersWrite); scoped {
+		if order.CustomerID != "" && order.CustomerID != customerID {
+			return nil, permissionDenied("Orders can only be placed for your own customer")
+		}
+		order.CustomerID = customerID
+		if order, err = s.catalogPrices(ctx, order, "Failed to create order"); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.orderRepository.CreateOrder(ctx, order); err != nil {
+		return nil, writeError(ctx, "Failed to create order", err)
+	}
+	return s.written(ctx, order)
+}
+
+func (s *orderService) UpdateOrder(ctx context.Context, req *storev1.UpdateOrderRequest) (*storev1.Order, error) {
+	order, err := orderModel(req.GetOrder())
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.ownOrder(ctx, order.ID, auth.PermissionOrdersWrite)
+	if err != nil {
+		return nil, err
+	}
+	customerID, scoped := auth.OwnCustomer(ctx, auth.PermissionOrdersWrite)
+	if scoped && order.CustomerID != "" && order.CustomerID != customerID {
+		return nil, permissionDenied("customer_id cannot be changed")
+	}
+	if order.CustomerID == "" {
+		order.CustomerID = current.CustomerID
+	}
+	if scoped {
+		if order, err = s.catalogPrices(ctx, order, "Failed to update order"); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.orderRepository.UpdateOrder(ctx, order); err != nil {
+		return nil, writeError(ctx, "Failed to update order", err)
+	}
+	return s.written(ctx, order)
+}
+
+func (s *orderService) DeleteOrder(ctx context.Context, req *storev1.DeleteOrderRequest) (*storev1.DeleteOrderResponse, error) {
+	_, err := s.orderRepository.GetOrderByID(ctx, req.GetId(), repository.OrderIncludes{})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound("Order not found")
+	}
+	if err == nil {
+		err = s.orderRepository.DeleteOrder(ctx, req.GetId())
+	}
+	if err != nil {
+		return nil, writeError(ctx, "Failed to delete order", err)
+	}
+	return &storev1.DeleteOrderResponse{}, nil
+}
+
+// ownOrder returns the order with its relations. Callers holding only the
+// own variant of permission get NotFound for the orders of other customers.
+func (s *orderService) ownOrder(ctx context.Context, id string, permission string) (model.Order, error) {
+	order, err := s.orderRepository.GetOrderByID(ctx, id, repository.AllOrderIncludes)
+	customerID, scoped := auth.OwnCustomer(ctx, permission)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && scoped && order.CustomerID != customerID) {
+		return model.Order{}, notFound("Order not found")
+	}
+	if err != nil {
+		return model.Order{}, internalError(ctx, "Failed to retrieve order", err)
+	}
+	return order, nil
+}
+
+// catalogPrices returns order with the current product prices, for callers
+// that may only write their own orders.
+func (s *orderService) catalogPrices(ctx context.Context, order model.Order, message string) (model.Order, error) {
+	priced, err := s.orderRepository.WithCatalogPrices(ctx, order)
+	if errors.Is(err, repository.ErrUnknownProduct) {
+		return model.Order{}, invalidArgument(err.Error())
+	}
+	if err != nil {
+		return model.Order{}, internalError(ctx, message, err)
+	}
+	return priced, nil
+}
+
+// written returns order as stored by a create or update, with its relations.
+func (s *orderService) written(ctx context.Context, order model.Order) (*storev1.Order, error) {
+	stored, err := s.orderRepository.GetOrderByID(ctx, order.ID, repository.AllOrderIncludes)
+	if err != nil {
+		return nil, internalError(ctx, "Failed to retrieve order", err)
+	}
+	return orderMessage(stored), nil
+}
@@ -0,0 +1,34 @@
+package rpc
+
+import (
+	"api/repository"
+	"fmt"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// newPage returns the page of size rows after the row with ID token, asking
+// for one more row to tell whether there is a next page. A size of zero
+// means the default.
+func newPage(size int32, token string) (repository.Page, error) {
+	if size == 0 {
+		size = defaultPageSize
+	}
+	if size < 1 || size > maxPageSize {
+		return repository.Page{}, invalidArgument(fmt.Sprintf("page_size must be between 1 and %d", maxPageSize))
+	}
+	return repository.Page{After: token, Limit: int(size) + 1}, nil
+}
+
+// nextPage drops the extra row read for page and returns the token of the
+// next page, which is empty on the last page.
+func nextPage[M any](rows []M, page repository.Page, id func(M) string) ([]M, string) {
+	if len(rows) < page.Limit {
+		return rows, ""
+	}
+	rows = rows[:len(rows)-1]
+	return rows, id(rows[len(rows)-1])
+}
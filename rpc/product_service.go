@@ -0,0 +1,120 @@
+package rpc
+
+import (
+	"api/auth"
+	"api/model"
+	storev1 "api/proto/store/v1"
+	"api/repository"
+	"context"
+	"database/sql"
+	"errors"
+)
+
+type productService struct {
+	storev1.UnimplementedProductServiceServer
+	productRepository *repository.ProductRepository
+}
+
+func productMessage(product model.Product) *storev1.Product {
+	return &storev1.Product{Id: product.ID, Name: product.Name, Price: product.Price}
+}
+
+// productModel returns the product of a create or update request.
+func productModel(product *storev1.Product) (model.Product, error) {
+	if product.GetId() == "" {
+		return model.Product{}, invalidArgument("product.id is required")
+	}
+	return model.Product{ID: product.GetId(), Name: product.GetName(), Price: product.GetPrice()}, nil
+}
+
+func (s *productService) ListProducts(ctx context.Context, req *storev1.ListProductsRequest) (*storev1.ListProductsResponse, error) {
+	page, err := newPage(req.GetPageSize(), req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	filter := repository.ProductFilter{
+		NameContains: req.GetNameContains(),
+		MinPrice:     req.MinPrice,
+		MaxPrice:     req.MaxPrice,
+	}
+	products, err := s.productRepository.ListProducts(ctx, filter, page)
+	if err != nil {
+		return nil, internalError(ctx, "Failed to retrieve products", err)
+	}
+	products, token := nextPage(products, page, func(product model.Product) string { return product.ID })
+	response := &storev1.ListProductsResponse{NextPageToken: token}
+	for _, product := range products {
+		response.Products = append(response.Products, productMessage(product))
+	}
+	return response, nil
+}
+
+func (s *productService) GetProduct(ctx context.Context, req *storev1.GetProductRequest) (*storev1.Product, error) {
+	product, err := s.productRepository.GetProductByID(ctx, req.GetId())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound("Product not found")
+	}
+	if err != nil {
+		return nil, internalError(ctx, "Failed to retrieve product", err)
+	}
+	return productMessage(product), nil
+}
+
+func (s *productService) CreateProduct(ctx context.Context, req *storev1.CreateProductRequest) (*storev1.Product, error) {
+	product, err := productModel(req.GetProduct())
+	if err != nil {
+		return nil, err
+	}
+	if !auth.HasPermission(ctx, auth.PermissionProductsPrice) {
+		return nil, priceForbidden()
+	}
+	if err := s.productRepository.CreateProduct(ctx, product); err != nil {
+		return nil, writeError(ctx, "Failed to create product", err)
+	}
+	return productMessage(product), nil
+}
+
+func (s *productService) UpdateProduct(ctx context.Context, req *storev1.UpdateProductRequest) (*storev1.Product, error) {
+	product, err := productModel(req.GetProduct())
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.productRepository.GetProductByID(ctx, product.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound("Product not found")
+	}
+	if err != nil {
+		return nil, internalError(ctx, "Failed to update product", err)
+	}
+	// Without products:price updates must keep the current price
+	update := s.productRepository.UpdateProduct
+	if !auth.HasPermission(ctx, auth.PermissionProductsPrice) {
+		update = s.productRepository.UpdateProductKeepingPrice
+	}
+	err = update(ctx, product)
+	if errors.Is(err, repository.ErrPriceChange) {
+		return nil, priceForbidden()
+	}
+	if err != nil {
+		return nil, writeError(ctx, "Failed to update product", err)
+	}
+	return productMessage(product), nil
+}
+
+func (s *productService) DeleteProduct(ctx context.Context, req *storev1.DeleteProductRequest) (*storev1.DeleteProductResponse, error) {
+	_, err := s.productRepository.GetProductByID(ctx, req.GetId())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound("Product not found")
+	}
+	if err == nil {
+		err = s.productRepository.DeleteProduct(ctx, req.GetId())
+	}
+	if err != nil {
+		return nil, writeError(ctx, "Failed to delete product", err)
+	}
+	return &storev1.DeleteProductResponse{}, nil
+}
+
+func priceForbidden() error {
+	return permissionDenied("Setting or changing a price requires the " + auth.PermissionProductsPrice + " permission")
+}
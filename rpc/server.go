@@ -0,0 +1,47 @@
+// Package rpc serves products, customers and orders over gRPC. Calls are
+// authenticated, throttled and authorized like REST requests and delegate to
+// the same repositories; errors carry the codes matching the HTTP status
+// codes of the REST API, see Code.
+package rpc
+
+import (
+	"api/auth"
+	storev1 "api/proto/store/v1"
+	"api/ratelimit"
+	"api/repository"
+	"api/tenant"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+// Options configure the request handling shared with the REST API.
+type Options struct {
+	Authenticator *auth.Authenticator
+	// AuthRequired rejects calls without credentials.
+	AuthRequired bool
+	// Limiter throttles callers unless nil.
+	Limiter *ratelimit.Limiter
+	// Tenants serves the tenant named by the :authority of a call or the
+	// tenant claim of its bearer JWT, unless nil.
+	Tenants *tenant.Manager
+}
+
+// NewServer returns a server of the product, customer and order services
+// and of server reflection. Reflection is open to unauthenticated callers,
+// like the API docs of the REST API.
+func NewServer(options Options, productRepository *repository.ProductRepository, customerRepository *repository.CustomerRepository, orderRepository *repository.OrderRepository) *grpc.Server {
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		options.rateLimitIP,
+		options.tenant,
+		actor,
+		options.authenticate,
+		options.rateLimit,
+		authorize,
+	))
+	storev1.RegisterProductServiceServer(server, &productService{productRepository: productRepository})
+	storev1.RegisterCustomerServiceServer(server, &customerService{customerRepository: customerRepository})
+	storev1.RegisterOrderServiceServer(server, &orderService{orderRepository: orderRepository})
+	reflection.Register(server)
+	return server
+}
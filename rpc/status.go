@@ -0,0 +1,65 @@
+package rpc
+
+import (
+	"api/repository"
+	"context"
+	"log/slog"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// httpCodes maps the HTTP status codes of the REST API to gRPC codes.
+var httpCodes = map[int]codes.Code{
+	http.StatusBadRequest:          codes.InvalidArgument,
+	http.StatusUnauthorized:        codes.Unauthenticated,
+	http.StatusForbidden:           codes.PermissionDenied,
+	http.StatusNotFound:            codes.NotFound,
+	http.StatusConflict:            codes.AlreadyExists,
+	http.StatusTooManyRequests:     codes.ResourceExhausted,
+	http.StatusInternalServerError: codes.Internal,
+}
+
+// Code returns the gRPC code of errors the REST API answers with
+// httpStatus. Statuses the REST API does not use for errors are Unknown.
+func Code(httpStatus int) codes.Code {
+	if code, ok := httpCodes[httpStatus]; ok {
+		return code
+	}
+	return codes.Unknown
+}
+
+// statusError returns an error with the code of httpStatus and message, the
+// error message the REST API answers with.
+func statusError(httpStatus int, message string) error {
+	return status.Error(Code(httpStatus), message)
+}
+
+func invalidArgument(message string) error {
+	return statusError(http.StatusBadRequest, message)
+}
+
+func notFound(message string) error {
+	return statusError(http.StatusNotFound, message)
+}
+
+func permissionDenied(message string) error {
+	return statusError(http.StatusForbidden, message)
+}
+
+// internalError logs err and returns an error with message only, keeping
+// the underlying error from the caller.
+func internalError(ctx context.Context, message string, err error) error {
+	slog.ErrorContext(ctx, message, "error", err)
+	return statusError(http.StatusInternalServerError, message)
+}
+
+// writeError reports constraint violations, such as duplicate IDs or
+// references to missing records, as conflicts and other errors as internal.
+func writeError(ctx context.Context, message string, err error) error {
+	if repository.IsConflict(err) {
+		return statusError(http.StatusConflict, message+": conflicts with existing data")
+	}
+	return internalError(ctx, message, err)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"api/model"
+	"api/repository"
+	"context"
+	"fmt"
+)
+
+var (
+	seedProducts = []model.Product{
+		{ID: "p-keyboard", Name: "Mechanical Keyboard", Price: 89.9},
+		{ID: "p-mouse", Name: "Wireless Mouse", Price: 24.5},
+		{ID: "p-monitor", Name: "27\" Monitor", Price: 229},
+		{ID: "p-cable", Name: "USB-C Cable", Price: 9.99},
+	}
+	seedCustomers = []model.Customer{
+		{ID: "c-ada", Name: "Ada Lovelace"},
+		{ID: "c-alan", Name: "Alan Turing"},
+		{ID: "c-grace", Name: "Grace Hopper"},
+	}
+	seedOrders = []model.Order{
+		{ID: "o-1001", CustomerID: "c-ada", OrderDate: "2024-01-15", OrderItems: []model.OrderItem{
+			{ID: "oi-1001-1", ProductID: "p-keyboard", Quantity: 1, Price: 89.9},
+			{ID: "oi-1001-2", ProductID: "p-cable", Quantity: 2, Price: 9.99},
+		}},
+		{ID: "o-1002", CustomerID: "c-grace", OrderDate: "2024-02-03", OrderItems: []model.OrderItem{
+			{ID: "oi-1002-1", ProductID: "p-monitor", Quantity: 2, Price: 229},
+		}},
+		{ID: "o-1003", CustomerID: "c-alan", OrderDate: "2024-02-20", OrderItems: []model.OrderItem{
+			{ID: "oi-1003-1", ProductID: "p-mouse", Quantity: 1, Price: 24.5},
+		}},
+	}
+)
+
+// seedCommand inserts sample data. Records that already exist are skipped,
+// so seeding twice is harmless.
+func seedCommand(args []string) error {
+	fs, flags := newFlagSet("seed")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cfg, err := flags.Load()
+	if err != nil {
+		return err
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := repository.WithActor(context.Background(), "seed")
+	options := repository.BatchOptions{BestEffort: true}
+
+	errs, err := repository.NewProductRepository(db).ApplyProductBatch(ctx, createOperations(seedProducts), options)
+	if err := reportSeeded("products", errs, err); err != nil {
+		return err
+	}
+	errs, err = repository.NewCustomerRepository(db).ApplyCustomerBatch(ctx, createOperations(seedCustomers), options)
+	if err := reportSeeded("customers", errs, err); err != nil {
+		return err
+	}
+	errs, err = repository.NewOrderRepository(db).ApplyOrderBatch(ctx, createOperations(seedOrders), options)
+	return reportSeeded("orders", errs, err)
+}
+
+func createOperations[T any](values []T) []model.BatchOperation[T] {
+	operations := make([]model.BatchOperation[T], len(values))
+	for i, value := range values {
+		operations[i] = model.BatchOperation[T]{Op: model.BatchOpCreate, Data: value}
+	}
+	return operations
+}
+
+// reportSeeded prints how many records were created, treating records that
+// already exist as skipped and any other item error as a failure.
+func reportSeeded(resource string, errs []error, err error) error {
+	if err != nil {
+		return fmt.Errorf("seed %s: %w", resource, err)
+	}
+	created, skipped := 0, 0
+	for _, itemErr := range errs {
+		switch {
+		case itemErr == nil:
+			created++
+		case repository.IsConflict(itemErr):
+			skipped++
+		default:
+			return fmt.Errorf("seed %s: %w", resource, itemErr)
+		}
+	}
+	fmt.Printf("%s: %d created, %d already present\n", resource, created, skipped)
+	return nil
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"api/auth"
+	"api/config"
+	"api/events"
+	"api/graph"
+	"api/handler"
+	"api/health"
+	"api/logging"
+	"api/metrics"
+	"api/middleware"
+	"api/model"
+	"api/ratelimit"
+	"api/repository"
+	"api/routes"
+	"api/rpc"
+	"api/tenant"
+	"api/tracing"
+	"api/webhooks"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	_ "api/docs"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+	fiberSwagger "github.com/swaggo/fiber-swagger"
+	"google.golang.org/grpc"
+)
+
+func serveCommand(args []string) error {
+	fs, flags := newFlagSet("serve")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cfg, err := flags.Load()
+	if err != nil {
+		return err
+	}
+	return serve(cfg)
+}
+
+func serve(cfg *config.Config) error {
+	// Write structured logs
+	logLevel, err := logging.Setup(os.Stdout, cfg.Log)
+	if err != nil {
+		return err
+	}
+
+	// Export traces
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Error("tracing shutdown failed", "error", err)
+		}
+	}()
+
+	// Initialize the database
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Create the repository instances
+	productRepository := repository.NewProductRepository(db)
+	customerRepository := repository.NewCustomerRepository(db)
+	orderRepository := repository.NewOrderRepository(db)
+	auditRepository := repository.NewAuditRepository(db)
+	outboxRepository := repository.NewOutboxRepository(db)
+	webhookRepository := repository.NewWebhookRepository(db)
+	idempotencyRepository := repository.NewIdempotencyRepository(db)
+	userRepository := repository.NewUserRepository(db)
+	apiKeyRepository := repository.NewAPIKeyRepository(db)
+	roleRepository := repository.NewRoleRepository(db)
+	if cfg.Cache.Enabled {
+		productRepository.EnableCache(cfg.Cache.TTL, cfg.Cache.MaxEntries)
+		customerRepository.EnableCache(cfg.Cache.TTL, cfg.Cache.MaxEntries)
+	}
+
+	// Identify callers by JWT or API key, with the permissions of their roles
+	authenticator, err := auth.NewAuthenticator(cfg.Auth, apiKeyRepository, userRepository, roleRepository, customerRepository)
+	if err != nil {
+		return err
+	}
+
+	// Throttle callers and enforce the daily quotas of API keys
+	var limiter *ratelimit.Limiter
+	if cfg.RateLimit.Enabled {
+		var store ratelimit.Store = ratelimit.NewMemoryStore()
+		if cfg.RateLimit.Store == "sqlite" {
+			store = ratelimit.NewSQLiteStore(repository.NewRateLimitRepository(db))
+		}
+		limiter, err = ratelimit.NewLimiter(cfg.RateLimit, store, apiKeyRepository)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Export Prometheus metrics
+	appMetrics := metrics.New()
+	appMetrics.RegisterDB(db, "main")
+	repository.SetObserver(appMetrics.ObserveQuery)
+	defer repository.SetObserver(nil)
+	orderRepository.OnOrderCreated(appMetrics.RecordOrderCreated)
+
+	// Publish domain events from the outbox in the background
+	bus := events.NewBus()
+	bus.Subscribe(appMetrics.RecordEvent)
+	sinks := []events.Sink{bus, webhooks.NewSink(webhookRepository)}
+	if cfg.Outbox.WebhookURL != "" {
+		sinks = append(sinks, events.NewWebhookSink(cfg.Outbox.WebhookURL))
+	}
+	if cfg.Outbox.NATSAddr != "" {
+		sinks = append(sinks, events.NewNATSSink(cfg.Outbox.NATSAddr, cfg.Outbox.NATSSubject))
+	}
+	workers, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	var wg sync.WaitGroup
+	dispatcher := events.NewDispatcher(outboxRepository, sinks...)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		dispatcher.Run(workers)
+	}()
+
+	// Deliver webhook subscriptions in the background
+	deliverer := webhooks.NewDeliverer(webhookRepository)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		deliverer.Run(workers)
+	}()
+
+	// Serve each tenant from its own database, with its own workers
+	var tenants *tenant.Manager
+	if cfg.Tenancy.Enabled {
+		tenants = tenant.NewManager(cfg, repository.NewTenantRepository(db))
+		tenants.OnOpen(func(ctx context.Context, t model.Tenant) {
+			tenantDispatcher := events.NewDispatcher(outboxRepository, bus, webhooks.NewSink(webhookRepository))
+			tenantDeliverer := webhooks.NewDeliverer(webhookRepository)
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				tenantDispatcher.Run(ctx)
+			}()
+			go func() {
+				defer wg.Done()
+				tenantDeliverer.Run(ctx)
+			}()
+		})
+		if err := tenants.Start(workers); err != nil {
+			return err
+		}
+		defer tenants.Close()
+	}
+
+	// Register the readiness checks
+	checks := health.NewRegistry()
+	checks.Register("database", health.Database(db))
+	checks.Register("database_writable", health.Writable(cfg.Database.Path))
+	checks.Register("migrations", health.Migrations(db, cfg.Database.MigrationsDir))
+	checks.Register("outbox_dispatcher", dispatcher.Check)
+	checks.Register("webhook_deliverer", deliverer.Check)
+
+	// Create the handler instances
+	productHandler := handler.NewProductHandler(productRepository)
+	customerHandler := handler.NewCustomerHandler(customerRepository)
+	orderHandler := handler.NewOrderHandler(orderRepository)
+	auditHandler := handler.NewAuditHandler(auditRepository)
+	webhookHandler := handler.NewWebhookHandler(webhookRepository)
+	eventHandler := handler.NewEventHandler(outboxRepository)
+	healthHandler := handler.NewHealthHandler(checks)
+	adminHandler := handler.NewAdminHandler(logLevel)
+	authHandler := handler.NewAuthHandler(apiKeyRepository)
+	roleHandler := handler.NewRoleHandler(roleRepository)
+	meHandler := handler.NewMeHandler(customerRepository, orderRepository)
+	graphQLHandler := handler.NewGraphQLHandler(graph.NewSchema(productRepository, customerRepository, orderRepository))
+	var tenantHandler *handler.TenantHandler
+	if tenants != nil {
+		tenantHandler = handler.NewTenantHandler(tenants)
+	}
+
+	// Initialize Fiber app
+	app := fiber.New(fiber.Config{
+		DisableStartupMessage: true,
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			code := fiber.StatusInternalServerError
+			if e, ok := err.(*fiber.Error); ok {
+				code = e.Code
+			}
+			return c.Status(code).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		},
+	})
+
+	// Probes, metrics and the API docs are registered ahead of the middleware
+	// to keep them out of the access log and open to unauthenticated callers
+	routes.SetupHealthRoutes(app, healthHandler)
+	routes.SetupMetricsRoutes(app, appMetrics)
+	app.Get("/swagger/*", fiberSwagger.WrapHandler)
+
+	// Add middleware
+	app.Use(middleware.RequestID())
+	app.Use(middleware.Tracing())
+	app.Use(middleware.Metrics(appMetrics))
+	if cfg.Log.Requests {
+		app.Use(middleware.AccessLog())
+	}
+	app.Use(cors.New(cors.Config{
+		AllowOrigins:     strings.Join(cfg.CORS.AllowOrigins, ","),
+		AllowCredentials: cfg.CORS.AllowCredentials,
+	}))
+	if limiter != nil {
+		app.Use(middleware.RateLimitIP(limiter))
+	}
+	if tenants != nil {
+		app.Use(middleware.Tenant(tenants, authenticator))
+	}
+	app.Use(middleware.Actor())
+	app.Use(middleware.Authenticate(authenticator, cfg.Auth.Required))
+	if limiter != nil {
+		app.Use(middleware.RateLimit(limiter))
+	}
+	app.Use(middleware.Idempotency(idempotencyRepository, cfg.Idempotency.TTL))
+
+	// Define the API routes of every version
+	deprecations, err := cfg.API.DeprecationSchedule()
+	if err != nil {
+		return err
+	}
+	routes.SetupVersionRoutes(app, routes.Handlers{
+		Product:  productHandler,
+		Customer: customerHandler,
+		Order:    orderHandler,
+		Audit:    auditHandler,
+		Webhook:  webhookHandler,
+		Event:    eventHandler,
+		Admin:    adminHandler,
+		Auth:     authHandler,
+		Role:     roleHandler,
+		Me:       meHandler,
+		Tenant:   tenantHandler,
+		GraphQL:  graphQLHandler,
+	}, deprecations)
+
+	// Start the HTTP server and, alongside it, the gRPC server
+	var grpcServer *grpc.Server
+	var grpcListener net.Listener
+	if cfg.GRPC.Enabled {
+		grpcListener, err = net.Listen("tcp", cfg.GRPC.ListenAddr)
+		if err != nil {
+			return fmt.Errorf("grpc: %w", err)
+		}
+		grpcServer = rpc.NewServer(rpc.Options{
+			Authenticator: authenticator,
+			AuthRequired:  cfg.Auth.Required,
+			Limiter:       limiter,
+			Tenants:       tenants,
+		}, productRepository, customerRepository, orderRepository)
+	}
+	signals, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	listenErr := make(chan error, 2)
+	go func() {
+		listenErr <- app.Listen(cfg.Server.ListenAddr)
+	}()
+	slog.Info("listening", "addr", cfg.Server.ListenAddr)
+	if grpcServer != nil {
+		go func() {
+			listenErr <- grpcServer.Serve(grpcListener)
+		}()
+		slog.Info("listening for gRPC", "addr", cfg.GRPC.ListenAddr)
+	}
+
+	select {
+	case err := <-listenErr:
+		if grpcServer != nil {
+			grpcServer.Stop()
+		}
+		stopWorkers()
+		wg.Wait()
+		return err
+	case <-signals.Done():
+	}
+	// A second signal terminates immediately
+	stop()
+
+	slog.Info("shutting down, draining requests", "timeout", cfg.Server.ShutdownTimeout.String())
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	// Stop accepting connections and wait for in-flight requests; event
+	// streams never finish on their own so they are ended first.
+	eventHandler.Close()
+	if grpcServer != nil {
+		stopGRPC(ctx, grpcServer)
+	}
+	shutdownErr := app.ShutdownWithContext(ctx)
+
+	// No request can write to the outbox any more: stop the workers after
+	// their current batch and publish what the drained requests produced.
+	stopWorkers()
+	wg.Wait()
+	if _, err := dispatcher.DispatchPending(ctx); err != nil {
+		slog.Error("outbox dispatcher failed", "error", err)
+	}
+
+	if shutdownErr != nil {
+		return fmt.Errorf("shutdown: %w", shutdownErr)
+	}
+	slog.Info("shutdown complete")
+	return nil
+}
+
+// stopGRPC waits for in-flight calls to finish, cancelling those still
+// running when ctx is done.
+func stopGRPC(ctx context.Context, server *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		server.Stop()
+	}
+}
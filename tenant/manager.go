@@ -0,0 +1,219 @@
+package tenant
+
+import (
+	"api/config"
+	"api/database"
+	"api/model"
+	"api/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrUnknownTenant is returned for tenant IDs that are not provisioned.
+	ErrUnknownTenant = errors.New("unknown tenant")
+	// ErrInvalidTenant is returned by Provision for an invalid ID or a missing name.
+	ErrInvalidTenant = errors.New("invalid tenant")
+)
+
+// Manager keeps the database of every provisioned tenant open.
+type Manager struct {
+	cfg              *config.Config
+	tenantRepository *repository.TenantRepository
+
+	mu      sync.RWMutex
+	parent  context.Context
+	tenants map[string]*openTenant
+	onOpen  []func(ctx context.Context, tenant model.Tenant)
+}
+
+type openTenant struct {
+	tenant model.Tenant
+	db     *sql.DB
+	cancel context.CancelFunc
+}
+
+func NewManager(cfg *config.Config, tenantRepository *repository.TenantRepository) *Manager {
+	return &Manager{
+		cfg:              cfg,
+		tenantRepository: tenantRepository,
+		parent:           context.Background(),
+		tenants:          map[string]*openTenant{},
+	}
+}
+
+// OnOpen registers fn to be called whenever a tenant's database is opened,
+// by Start or Provision, e.g. to run background workers for it. fn is given
+// a context scoped to the tenant which is cancelled once the tenant is
+// deleted, the manager closed or the context given to Start is done.
+func (manager *Manager) OnOpen(fn func(ctx context.Context, tenant model.Tenant)) {
+	manager.onOpen = append(manager.onOpen, fn)
+}
+
+// Start opens the database of every provisioned tenant, applying pending
+// migrations. The contexts given to OnOpen functions derive from ctx.
+func (manager *Manager) Start(ctx context.Context) error {
+	manager.mu.Lock()
+	manager.parent = ctx
+	manager.mu.Unlock()
+	tenants, err := manager.tenantRepository.GetTenants(ctx)
+	if err != nil {
+		return err
+	}
+	for _, tenant := range tenants {
+		if err := manager.open(tenant); err != nil {
+			return fmt.Errorf("tenant %s: %w", tenant.ID, err)
+		}
+	}
+	return nil
+}
+
+// Tenants returns the provisioned tenants.
+func (manager *Manager) Tenants(ctx context.Context) ([]model.Tenant, error) {
+	return manager.tenantRepository.GetTenants(ctx)
+}
+
+// Resolve returns the ID of the tenant served on host, ignoring any port.
+func (manager *Manager) Resolve(host string) (string, bool) {
+	host = normalizeHost(host)
+	if host == "" {
+		return "", false
+	}
+	manager.mu.RLock()
+	defer manager.mu.RUnlock()
+	for id, open := range manager.tenants {
+		if open.tenant.Host == host {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// Context returns a copy of ctx serving the tenant: repositories called with
+// it use the tenant's database.
+func (manager *Manager) Context(ctx context.Context, id string) (context.Context, error) {
+	manager.mu.RLock()
+	open, ok := manager.tenants[id]
+	manager.mu.RUnlock()
+	if !ok {
+		return ctx, fmt.Errorf("%w %q", ErrUnknownTenant, id)
+	}
+	return repository.WithDatabase(WithTenant(ctx, id), open.db), nil
+}
+
+// Provision registers a tenant and creates its database. It fails with
+// ErrInvalidTenant for an invalid ID or a missing name, with
+// os.ErrExist when a database file is left over from an earlier tenant with
+// the same ID, and with a conflict when the ID or host is taken.
+func (manager *Manager) Provision(ctx context.Context, tenant model.Tenant) (model.Tenant, error) {
+	path, err := manager.cfg.TenantDatabasePath(tenant.ID)
+	if err != nil {
+		return tenant, fmt.Errorf("%w: %v", ErrInvalidTenant, err)
+	}
+	if strings.TrimSpace(tenant.Name) == "" {
+		return tenant, fmt.Errorf("%w: name is required", ErrInvalidTenant)
+	}
+	if _, err := os.Stat(path); err == nil {
+		return tenant, fmt.Errorf("database of tenant %q: %w", tenant.ID, os.ErrExist)
+	}
+	tenant.Host = normalizeHost(tenant.Host)
+
+	tenant, err = manager.tenantRepository.CreateTenant(ctx, tenant)
+	if err != nil {
+		return tenant, err
+	}
+	if err := manager.open(tenant); err != nil {
+		if err := manager.tenantRepository.DeleteTenant(context.WithoutCancel(ctx), tenant.ID); err != nil {
+			slog.ErrorContext(ctx, "failed to unregister tenant", "tenant", tenant.ID, "error", err)
+		}
+		removeDatabase(path)
+		return tenant, err
+	}
+	return tenant, nil
+}
+
+// Delete unregisters a tenant, closes its database and removes the file,
+// returning sql.ErrNoRows when the tenant does not exist.
+func (manager *Manager) Delete(ctx context.Context, id string) error {
+	if err := manager.tenantRepository.DeleteTenant(ctx, id); err != nil {
+		return err
+	}
+	manager.mu.Lock()
+	open, ok := manager.tenants[id]
+	delete(manager.tenants, id)
+	manager.mu.Unlock()
+	if ok {
+		open.cancel()
+		if err := open.db.Close(); err != nil {
+			return err
+		}
+	}
+	path, err := manager.cfg.TenantDatabasePath(id)
+	if err != nil {
+		return err
+	}
+	return removeDatabase(path)
+}
+
+// Close stops every tenant's workers and closes the databases.
+func (manager *Manager) Close() error {
+	manager.mu.Lock()
+	tenants := manager.tenants
+	manager.tenants = map[string]*openTenant{}
+	manager.mu.Unlock()
+
+	var errs []error
+	for _, open := range tenants {
+		open.cancel()
+		errs = append(errs, open.db.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func (manager *Manager) open(tenant model.Tenant) error {
+	path, err := manager.cfg.TenantDatabasePath(tenant.ID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(manager.cfg.Tenancy.Dir, 0o755); err != nil {
+		return err
+	}
+	db, err := database.InitializeDB(path, manager.cfg.MigrationsURL())
+	if err != nil {
+		return err
+	}
+
+	manager.mu.Lock()
+	ctx, cancel := context.WithCancel(repository.WithDatabase(WithTenant(manager.parent, tenant.ID), db))
+	manager.tenants[tenant.ID] = &openTenant{tenant: tenant, db: db, cancel: cancel}
+	manager.mu.Unlock()
+	for _, fn := range manager.onOpen {
+		fn(ctx, tenant)
+	}
+	return nil
+}
+
+func normalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if i := strings.LastIndexByte(host, ':'); i >= 0 && !strings.Contains(host[i:], "]") {
+		host = host[:i]
+	}
+	return host
+}
+
+// removeDatabase deletes an SQLite database file along with its journals.
+func removeDatabase(path string) error {
+	var errs []error
+	for _, suffix := range []string{"", "-journal", "-wal", "-shm"} {
+		if err := os.Remove(path + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
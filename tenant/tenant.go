@@ -0,0 +1,19 @@
+// Package tenant serves several storefronts from one process, each from its
+// own SQLite database.
+package tenant
+
+import "context"
+
+type tenantContextKey struct{}
+
+// WithTenant returns a copy of ctx carrying the ID of the tenant being served.
+func WithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, id)
+}
+
+// FromContext returns the ID of the tenant stored in ctx, or "" when ctx
+// uses the main database.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(tenantContextKey{}).(string)
+	return id
+}
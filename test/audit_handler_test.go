@@ -0,0 +1,162 @@
+package handler_test
+
+import (
+	"api/handler"
+	"api/middleware"
+	"api/model"
+	"api/repository"
+	"api/routes"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"api/database"
+
+	"github.com/gofiber/fiber/v2"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+var testAuditDB *sql.DB
+
+func setupAuditTestApp() *fiber.App {
+	dbFile := "test_audit_integration.db"
+	os.Remove(dbFile)
+	migrationDir := "file://../database/migrations"
+	db, err := database.InitializeDB(dbFile, migrationDir)
+	if err != nil {
+		panic(err)
+	}
+	testAuditDB = db
+	productRepo := repository.NewProductRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	productHandler := handler.NewProductHandler(productRepo)
+	auditHandler := handler.NewAuditHandler(auditRepo)
+	app := fiber.New()
+	app.Use(asPrincipal("*"))
+	app.Use(middleware.Actor())
+	routes.SetupProductRoutes(app, productHandler)
+	routes.SetupAuditRoutes(app, auditHandler)
+	return app
+}
+
+func teardownAuditTestDB() {
+	if testAuditDB != nil {
+		testAuditDB.Close()
+		os.Remove("test_audit_integration.db")
+	}
+}
+
+func TestAuditIntegration(t *testing.T) {
+	app := setupAuditTestApp()
+	defer teardownAuditTestDB()
+
+	// Create, update and delete a product as "alice"
+	product := model.Product{ID: "1", Name: "Test Product", Price: 9.99}
+	body, _ := json.Marshal(product)
+	req := httptest.NewRequest(http.MethodPost, "/products", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.ActorHeader, "alice")
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	updated := model.Product{ID: "1", Name: "Test Product", Price: 19.99}
+	body, _ = json.Marshal(updated)
+	req = httptest.NewRequest(http.MethodPut, "/products/1", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.ActorHeader, "alice")
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	req = httptest.NewRequest(http.MethodDelete, "/products/1", nil)
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	// Test GET /audit
+	req = httptest.NewRequest(http.MethodGet, "/audit?resource=products&id=1", nil)
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var entries []model.AuditEntry
+	json.NewDecoder(resp.Body).Decode(&entries)
+	assert.Len(t, entries, 3)
+	assert.Equal(t, repository.AuditActionCreate, entries[0].Action)
+	assert.Equal(t, "alice", entries[0].Actor)
+	assert.JSONEq(t, "null", string(entries[0].Before))
+	assert.Equal(t, repository.AuditActionUpdate, entries[1].Action)
+	assert.JSONEq(t, `{"price": {"before": 9.99, "after": 19.99}}`, string(entries[1].Diff))
+	assert.Equal(t, repository.AuditActionDelete, entries[2].Action)
+	assert.Equal(t, "anonymous", entries[2].Actor)
+	assert.JSONEq(t, "null", string(entries[2].After))
+
+	// The audit log cannot be rewritten
+	_, err = testAuditDB.Exec("DELETE FROM audit_log")
+	assert.Error(t, err)
+
+	// Other resources are filtered out
+	req = httptest.NewRequest(http.MethodGet, "/audit?resource=orders", nil)
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	entries = nil
+	json.NewDecoder(resp.Body).Decode(&entries)
+	assert.Len(t, entries, 0)
+}
+
+func TestAuditOrderSnapshots(t *testing.T) {
+	app := setupAuditTestApp()
+	defer teardownAuditTestDB()
+
+	ctx := context.Background()
+	assert.NoError(t, repository.NewCustomerRepository(testAuditDB).CreateCustomer(ctx, model.Customer{ID: "c1", Name: "Customer"}))
+	assert.NoError(t, repository.NewProductRepository(testAuditDB).CreateProduct(ctx, model.Product{ID: "p1", Name: "Widget", Price: 2.5}))
+	orderRepo := repository.NewOrderRepository(testAuditDB)
+	order := model.Order{ID: "o1", CustomerID: "c1", OrderDate: "2024-01-01", OrderItems: []model.OrderItem{
+		{ID: "i1", ProductID: "p1", Quantity: 1, Price: 2.5},
+	}}
+	assert.NoError(t, orderRepo.CreateOrder(ctx, order))
+	order.OrderDate = "2024-02-01"
+	assert.NoError(t, orderRepo.UpdateOrder(ctx, order))
+	order.OrderDate = "2024-03-01"
+	errs, err := orderRepo.ApplyOrderBatch(ctx, []model.BatchOperation[model.Order]{{Op: model.BatchOpUpdate, Data: order}}, repository.BatchOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []error{nil}, errs)
+	errs, err = orderRepo.ApplyOrderBatch(ctx, []model.BatchOperation[model.Order]{{Op: model.BatchOpCreate, Data: model.Order{ID: "o2", CustomerID: "c1", OrderDate: "2024-01-01"}}}, repository.BatchOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []error{nil}, errs)
+
+	// The after snapshot is the stored order, so only what changed is diffed
+	req := httptest.NewRequest(http.MethodGet, "/audit?resource=orders&id=o1", nil)
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	var entries []model.AuditEntry
+	json.NewDecoder(resp.Body).Decode(&entries)
+	if assert.Len(t, entries, 3) {
+		assert.JSONEq(t, string(entries[0].After), string(entries[1].Before))
+		assert.JSONEq(t, `{"order_date": {"before": "2024-01-01", "after": "2024-02-01"}}`, string(entries[1].Diff))
+		assert.JSONEq(t, `{"order_date": {"before": "2024-02-01", "after": "2024-03-01"}}`, string(entries[2].Diff))
+		var after model.Order
+		assert.NoError(t, json.Unmarshal(entries[2].After, &after))
+		assert.Equal(t, "Customer", after.Customer.Name)
+		assert.Equal(t, "Widget", after.OrderItems[0].Product.Name)
+	}
+
+	// Orders created in a batch are snapshotted the same way
+	req = httptest.NewRequest(http.MethodGet, "/audit?resource=orders&id=o2", nil)
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	json.NewDecoder(resp.Body).Decode(&entries)
+	if assert.Len(t, entries, 1) {
+		var after model.Order
+		assert.NoError(t, json.Unmarshal(entries[0].After, &after))
+		assert.Equal(t, "Customer", after.Customer.Name)
+	}
+}
@@ -0,0 +1,256 @@
+package handler_test
+
+import (
+	"api/auth"
+	"api/config"
+	"api/handler"
+	"api/middleware"
+	"api/model"
+	"api/repository"
+	"api/routes"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"api/database"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+const testJWTSecret = "0123456789abcdef0123456789abcdef"
+
+var testAuthDB *sql.DB
+
+// setupAuthTestApp requires authentication, accepting HS256 tokens and RS256
+// tokens signed with rsaKey, and creates the admin user "u1".
+func setupAuthTestApp(t *testing.T) (*fiber.App, *rsa.PrivateKey) {
+	dbFile := "test_auth_integration.db"
+	os.Remove(dbFile)
+	migrationDir := "file://../database/migrations"
+	db, err := database.InitializeDB(dbFile, migrationDir)
+	if err != nil {
+		panic(err)
+	}
+	testAuthDB = db
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks, _ := json.Marshal(map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": "k1",
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+	}}})
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(jwksFile, jwks, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	roleRepo := repository.NewRoleRepository(db)
+	if _, err := userRepo.CreateUser(context.Background(), model.User{ID: "u1", Name: "Alice", Email: "alice@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := roleRepo.SetUserRoles(context.Background(), "u1", []string{"admin"}); err != nil {
+		t.Fatal(err)
+	}
+	authenticator, err := auth.NewAuthenticator(config.AuthConfig{
+		Required:  true,
+		JWTSecret: testJWTSecret,
+		JWKSFile:  jwksFile,
+		Issuer:    "https://issuer.example",
+	}, apiKeyRepo, userRepo, roleRepo, repository.NewCustomerRepository(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	app.Use(middleware.Actor())
+	app.Use(middleware.Authenticate(authenticator, true))
+	routes.SetupProductRoutes(app, handler.NewProductHandler(repository.NewProductRepository(db)))
+	routes.SetupAuditRoutes(app, handler.NewAuditHandler(repository.NewAuditRepository(db)))
+	routes.SetupAuthRoutes(app, handler.NewAuthHandler(apiKeyRepo))
+	return app, rsaKey
+}
+
+func teardownAuthTestDB() {
+	if testAuthDB != nil {
+		testAuthDB.Close()
+		os.Remove("test_auth_integration.db")
+	}
+}
+
+func testToken(t *testing.T, method jwt.SigningMethod, key any, kid string, claims jwt.RegisteredClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func authStatus(t *testing.T, app *fiber.App, header string, value string) int {
+	req := httptest.NewRequest(http.MethodGet, "/products", nil)
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	return resp.StatusCode
+}
+
+func TestAuthJWT(t *testing.T) {
+	app, rsaKey := setupAuthTestApp(t)
+	defer teardownAuthTestDB()
+
+	valid := jwt.RegisteredClaims{
+		Subject:   "u1",
+		Issuer:    "https://issuer.example",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}
+
+	// No credentials
+	req := httptest.NewRequest(http.MethodGet, "/products", nil)
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, "Bearer", resp.Header.Get("WWW-Authenticate"))
+
+	// HS256 and RS256 tokens
+	assert.Equal(t, fiber.StatusOK, authStatus(t, app, "Authorization", "Bearer "+testToken(t, jwt.SigningMethodHS256, []byte(testJWTSecret), "", valid)))
+	assert.Equal(t, fiber.StatusOK, authStatus(t, app, "Authorization", "Bearer "+testToken(t, jwt.SigningMethodRS256, rsaKey, "k1", valid)))
+
+	// Rejected tokens
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	expired := valid
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	wrongIssuer := valid
+	wrongIssuer.Issuer = "https://other.example"
+	unknownUser := valid
+	unknownUser.Subject = "u2"
+	noExpiry := valid
+	noExpiry.ExpiresAt = nil
+	for name, token := range map[string]string{
+		"wrong secret":  testToken(t, jwt.SigningMethodHS256, []byte("another secret of thirty-two bytes"), "", valid),
+		"wrong rsa key": testToken(t, jwt.SigningMethodRS256, otherKey, "k1", valid),
+		"unknown kid":   testToken(t, jwt.SigningMethodRS256, rsaKey, "k2", valid),
+		"HS384":         testToken(t, jwt.SigningMethodHS384, []byte(testJWTSecret), "", valid),
+		"expired":       testToken(t, jwt.SigningMethodHS256, []byte(testJWTSecret), "", expired),
+		"no expiry":     testToken(t, jwt.SigningMethodHS256, []byte(testJWTSecret), "", noExpiry),
+		"wrong issuer":  testToken(t, jwt.SigningMethodHS256, []byte(testJWTSecret), "", wrongIssuer),
+		"unknown user":  testToken(t, jwt.SigningMethodHS256, []byte(testJWTSecret), "", unknownUser),
+		"malformed":     "not-a-token",
+	} {
+		assert.Equal(t, fiber.StatusUnauthorized, authStatus(t, app, "Authorization", "Bearer "+token), name)
+	}
+
+	// The principal, not X-Actor, is recorded in the audit log
+	body, _ := json.Marshal(model.Product{ID: "p1", Name: "Widget", Price: 2.5})
+	req = httptest.NewRequest(http.MethodPost, "/products", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+testToken(t, jwt.SigningMethodHS256, []byte(testJWTSecret), "", valid))
+	req.Header.Set("X-Actor", "mallory")
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	var entries []model.AuditEntry
+	entries, err = repository.NewAuditRepository(testAuthDB).GetAuditEntries(context.Background(), "products", "p1")
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "u1", entries[0].Actor)
+	}
+}
+
+func TestAuthAPIKeys(t *testing.T) {
+	app, _ := setupAuthTestApp(t)
+	defer teardownAuthTestDB()
+
+	// Bootstrap a key as the CLI does
+	key, secret, hash := auth.NewAPIKey("u1", "bootstrap", nil)
+	_, err := repository.NewAPIKeyRepository(testAuthDB).CreateAPIKey(context.Background(), key, hash)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, authStatus(t, app, "Authorization", "Bearer "+secret))
+	assert.Equal(t, fiber.StatusOK, authStatus(t, app, "X-API-Key", secret))
+	assert.Equal(t, fiber.StatusUnauthorized, authStatus(t, app, "X-API-Key", secret+"x"))
+
+	// Create a key through the API
+	req := httptest.NewRequest(http.MethodPost, "/auth/keys", bytes.NewReader([]byte(`{"name":"ci","expires_in":"1h"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", secret)
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	var created model.CreatedAPIKey
+	json.NewDecoder(resp.Body).Decode(&created)
+	assert.Equal(t, "u1", created.UserID)
+	assert.Equal(t, "ci", created.Name)
+	assert.NotNil(t, created.ExpiresAt)
+	assert.Equal(t, created.Key[:len(created.Prefix)], created.Prefix)
+	assert.Equal(t, fiber.StatusOK, authStatus(t, app, "X-API-Key", created.Key))
+
+	// Keys are listed without their secret
+	req = httptest.NewRequest(http.MethodGet, "/auth/keys", nil)
+	req.Header.Set("X-API-Key", created.Key)
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var listed []map[string]any
+	json.NewDecoder(resp.Body).Decode(&listed)
+	if assert.Len(t, listed, 2) {
+		assert.NotContains(t, listed[1], "key")
+		assert.NotNil(t, listed[0]["last_used_at"])
+	}
+
+	// Revoke the created key
+	req = httptest.NewRequest(http.MethodDelete, "/auth/keys/"+created.ID, nil)
+	req.Header.Set("X-API-Key", secret)
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, fiber.StatusUnauthorized, authStatus(t, app, "X-API-Key", created.Key))
+
+	req = httptest.NewRequest(http.MethodDelete, "/auth/keys/"+created.ID, nil)
+	req.Header.Set("X-API-Key", secret)
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+	// Expired keys are rejected
+	past := time.Now().Add(-time.Minute)
+	expiredKey, expiredSecret, expiredHash := auth.NewAPIKey("u1", "old", &past)
+	_, err = repository.NewAPIKeyRepository(testAuthDB).CreateAPIKey(context.Background(), expiredKey, expiredHash)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusUnauthorized, authStatus(t, app, "X-API-Key", expiredSecret))
+
+	// Invalid key requests
+	for _, body := range []string{`{}`, `{"name":"x","expires_in":"soon"}`, `{"name":"x","expires_in":"-1h"}`} {
+		req = httptest.NewRequest(http.MethodPost, "/auth/keys", bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", secret)
+		resp, err = app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode, body)
+	}
+}
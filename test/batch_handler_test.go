@@ -0,0 +1,160 @@
+package handler_test
+
+import (
+	"api/dto"
+	"api/handler"
+	"api/model"
+	"api/repository"
+	"api/routes"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"api/database"
+
+	"github.com/gofiber/fiber/v2"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+var testBatchDB *sql.DB
+
+func setupBatchTestApp() *fiber.App {
+	dbFile := "test_batch_integration.db"
+	os.Remove(dbFile)
+	migrationDir := "file://../database/migrations"
+	db, err := database.InitializeDB(dbFile, migrationDir)
+	if err != nil {
+		panic(err)
+	}
+	testBatchDB = db
+	app := fiber.New()
+	app.Use(asPrincipal("*"))
+	routes.SetupProductRoutes(app, handler.NewProductHandler(repository.NewProductRepository(db)))
+	routes.SetupCustomerRoutes(app, handler.NewCustomerHandler(repository.NewCustomerRepository(db)))
+	routes.SetupOrderRoutes(app, handler.NewOrderHandler(repository.NewOrderRepository(db)))
+	return app
+}
+
+func teardownBatchTestDB() {
+	if testBatchDB != nil {
+		testBatchDB.Close()
+		os.Remove("test_batch_integration.db")
+	}
+}
+
+func postBatch(t *testing.T, app *fiber.App, path string, payload any) (int, model.BatchResponse) {
+	body, _ := json.Marshal(payload)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	var response model.BatchResponse
+	json.NewDecoder(resp.Body).Decode(&response)
+	return resp.StatusCode, response
+}
+
+func countRows(table string) int {
+	var count int
+	testBatchDB.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&count)
+	return count
+}
+
+func TestBatchIntegration(t *testing.T) {
+	app := setupBatchTestApp()
+	defer teardownBatchTestDB()
+
+	// Test POST /products:batch
+	status, response := postBatch(t, app, "/products:batch", model.BatchRequest[model.Product]{
+		Operations: []model.BatchOperation[model.Product]{
+			{Op: model.BatchOpCreate, Data: model.Product{ID: "p1", Name: "One", Price: 1}},
+			{Op: model.BatchOpCreate, Data: model.Product{ID: "p2", Name: "Two", Price: 2}},
+			{Op: model.BatchOpCreate, Data: model.Product{ID: "p3", Name: "Three", Price: 3}},
+		},
+	})
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, model.BatchModeAllOrNothing, response.Mode)
+	assert.Equal(t, 3, response.Succeeded)
+	assert.Equal(t, fiber.StatusCreated, response.Results[2].Status)
+	assert.Equal(t, 3, countRows("products"))
+
+	// All-or-nothing: a duplicate rolls back the whole batch
+	status, response = postBatch(t, app, "/products:batch", model.BatchRequest[model.Product]{
+		Mode: model.BatchModeAllOrNothing,
+		Operations: []model.BatchOperation[model.Product]{
+			{Op: model.BatchOpUpdate, Data: model.Product{ID: "p1", Name: "One", Price: 10}},
+			{Op: model.BatchOpCreate, Data: model.Product{ID: "p2", Name: "Duplicate", Price: 2}},
+			{Op: model.BatchOpDelete, Data: model.Product{ID: "p3"}},
+		},
+	})
+	assert.Equal(t, fiber.StatusMultiStatus, status)
+	assert.Equal(t, 0, response.Succeeded)
+	assert.Equal(t, 3, response.Failed)
+	assert.Equal(t, fiber.StatusFailedDependency, response.Results[0].Status)
+	assert.Equal(t, fiber.StatusConflict, response.Results[1].Status)
+	assert.Equal(t, "Conflicts with existing data", response.Results[1].Error)
+	assert.Equal(t, fiber.StatusFailedDependency, response.Results[2].Status)
+	assert.Equal(t, 3, countRows("products"))
+	var price float64
+	testBatchDB.QueryRow("SELECT price FROM products WHERE id = 'p1'").Scan(&price)
+	assert.Equal(t, 1.0, price)
+
+	// Best-effort: only the failing operation is skipped
+	status, response = postBatch(t, app, "/products:batch", model.BatchRequest[model.Product]{
+		Mode: model.BatchModeBestEffort,
+		Operations: []model.BatchOperation[model.Product]{
+			{Op: model.BatchOpUpdate, Data: model.Product{ID: "p1", Name: "One", Price: 10}},
+			{Op: model.BatchOpCreate, Data: model.Product{ID: "p2", Name: "Duplicate", Price: 2}},
+			{Op: model.BatchOpDelete, Data: model.Product{ID: "missing"}},
+			{Op: model.BatchOpDelete, Data: model.Product{ID: "p3"}},
+		},
+	})
+	assert.Equal(t, fiber.StatusMultiStatus, status)
+	assert.Equal(t, 2, response.Succeeded)
+	assert.Equal(t, fiber.StatusOK, response.Results[0].Status)
+	assert.Equal(t, fiber.StatusConflict, response.Results[1].Status)
+	assert.Equal(t, fiber.StatusNotFound, response.Results[2].Status)
+	assert.Equal(t, fiber.StatusOK, response.Results[3].Status)
+	assert.Equal(t, 2, countRows("products"))
+	testBatchDB.QueryRow("SELECT price FROM products WHERE id = 'p1'").Scan(&price)
+	assert.Equal(t, 10.0, price)
+
+	// Invalid operations are rejected up front
+	status, _ = postBatch(t, app, "/products:batch", model.BatchRequest[model.Product]{
+		Operations: []model.BatchOperation[model.Product]{{Op: "upsert", Data: model.Product{ID: "p9"}}},
+	})
+	assert.Equal(t, fiber.StatusBadRequest, status)
+
+	// Test POST /customers:batch and /orders:batch
+	status, _ = postBatch(t, app, "/customers:batch", model.BatchRequest[model.Customer]{
+		Operations: []model.BatchOperation[model.Customer]{
+			{Op: model.BatchOpCreate, Data: model.Customer{ID: "c1", Name: "Customer"}},
+		},
+	})
+	assert.Equal(t, fiber.StatusOK, status)
+
+	status, response = postBatch(t, app, "/orders:batch", model.BatchRequest[dto.OrderRequest]{
+		Operations: []model.BatchOperation[dto.OrderRequest]{
+			{Op: model.BatchOpCreate, Data: dto.OrderRequest{
+				ID: "o1", CustomerID: "c1", OrderDate: "2024-01-01",
+				OrderItems: []dto.OrderItemRequest{{ID: "oi1", ProductID: "p1", Quantity: 1, Price: 10}},
+			}},
+			{Op: model.BatchOpCreate, Data: dto.OrderRequest{
+				ID: "o2", CustomerID: "c1", OrderDate: "2024-01-02",
+				OrderItems: []dto.OrderItemRequest{{ID: "oi2", ProductID: "p2", Quantity: 2, Price: 2}},
+			}},
+			{Op: model.BatchOpUpdate, Data: dto.OrderRequest{
+				ID: "o1", CustomerID: "c1", OrderDate: "2024-01-03",
+				OrderItems: []dto.OrderItemRequest{{ID: "oi3", ProductID: "p2", Quantity: 3, Price: 2}},
+			}},
+		},
+	})
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, 3, response.Succeeded)
+	assert.Equal(t, 2, countRows("orders"))
+	assert.Equal(t, 2, countRows("order_items"))
+}
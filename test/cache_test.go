@@ -0,0 +1,253 @@
+package handler_test
+
+import (
+	"api/cache"
+	"api/handler"
+	"api/model"
+	"api/repository"
+	"api/routes"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"api/database"
+
+	"github.com/gofiber/fiber/v2"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+var testCacheDB *sql.DB
+
+func setupCacheTestApp() *fiber.App {
+	dbFile := "test_cache_integration.db"
+	os.Remove(dbFile)
+	migrationDir := "file://../database/migrations"
+	db, err := database.InitializeDB(dbFile, migrationDir)
+	if err != nil {
+		panic(err)
+	}
+	testCacheDB = db
+	productRepo := repository.NewProductRepository(db)
+	productRepo.EnableCache(time.Minute, 100)
+	customerRepo := repository.NewCustomerRepository(db)
+	customerRepo.EnableCache(time.Minute, 100)
+	app := fiber.New()
+	app.Use(asPrincipal("*"))
+	routes.SetupProductRoutes(app, handler.NewProductHandler(productRepo))
+	routes.SetupCustomerRoutes(app, handler.NewCustomerHandler(customerRepo))
+	return app
+}
+
+func teardownCacheTestDB() {
+	if testCacheDB != nil {
+		testCacheDB.Close()
+		os.Remove("test_cache_integration.db")
+	}
+}
+
+func cacheRequest(t *testing.T, app *fiber.App, method string, path string, body any, headers map[string]string) (*http.Response, string) {
+	var reader io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	data, _ := io.ReadAll(resp.Body)
+	return resp, string(data)
+}
+
+func TestCachedReads(t *testing.T) {
+	app := setupCacheTestApp()
+	defer teardownCacheTestDB()
+
+	resp, _ := cacheRequest(t, app, http.MethodPost, "/products", model.Product{ID: "p1", Name: "Widget", Price: 2.5}, nil)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	// Reads carry validators and must be revalidated
+	resp, body := cacheRequest(t, app, http.MethodGet, "/products", nil, nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, `[{"id":"p1","name":"Widget","price":2.5}]`, body)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "private, no-cache", resp.Header.Get("Cache-Control"))
+	etag := resp.Header.Get("ETag")
+	assert.NotEmpty(t, etag)
+	lastModified, err := http.ParseTime(resp.Header.Get("Last-Modified"))
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), lastModified, time.Minute)
+
+	// Matching validators get 304 without a body
+	resp, body = cacheRequest(t, app, http.MethodGet, "/products", nil, map[string]string{"If-None-Match": `"other", W/` + etag})
+	assert.Equal(t, fiber.StatusNotModified, resp.StatusCode)
+	assert.Empty(t, body)
+	assert.Equal(t, etag, resp.Header.Get("ETag"))
+	resp, _ = cacheRequest(t, app, http.MethodGet, "/products", nil, map[string]string{"If-None-Match": `"other"`})
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp, _ = cacheRequest(t, app, http.MethodGet, "/products", nil, map[string]string{"If-Modified-Since": lastModified.Format(http.TimeFormat)})
+	assert.Equal(t, fiber.StatusNotModified, resp.StatusCode)
+	resp, _ = cacheRequest(t, app, http.MethodGet, "/products", nil, map[string]string{"If-Modified-Since": lastModified.Add(-time.Second).Format(http.TimeFormat)})
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	// Writes by other processes are not seen while cached
+	_, err = testCacheDB.Exec("UPDATE products SET name = 'Gadget' WHERE id = 'p1'")
+	assert.NoError(t, err)
+	resp, body = cacheRequest(t, app, http.MethodGet, "/products", nil, nil)
+	assert.Equal(t, etag, resp.Header.Get("ETag"))
+	assert.Contains(t, body, "Widget")
+
+	// Writes through the API invalidate lists and items
+	resp, body = cacheRequest(t, app, http.MethodGet, "/products/p1", nil, nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, "Gadget")
+	itemTag := resp.Header.Get("ETag")
+	resp, _ = cacheRequest(t, app, http.MethodPut, "/products/p1", model.Product{Name: "Gizmo", Price: 2.5}, nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp, body = cacheRequest(t, app, http.MethodGet, "/products/p1", nil, map[string]string{"If-None-Match": itemTag})
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, "Gizmo")
+	resp, body = cacheRequest(t, app, http.MethodGet, "/products", nil, map[string]string{"If-None-Match": etag})
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, "Gizmo")
+
+	resp, _ = cacheRequest(t, app, http.MethodPost, "/products:batch", model.BatchRequest[model.Product]{Operations: []model.BatchOperation[model.Product]{
+		{Op: model.BatchOpDelete, Data: model.Product{ID: "p1"}},
+	}}, nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	_, body = cacheRequest(t, app, http.MethodGet, "/products", nil, nil)
+	assert.JSONEq(t, `[]`, body)
+
+	// Customers are cached the same way
+	resp, _ = cacheRequest(t, app, http.MethodPost, "/customers", model.Customer{ID: "c1", Name: "Alice"}, nil)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	resp, _ = cacheRequest(t, app, http.MethodGet, "/customers/c1", nil, nil)
+	customerTag := resp.Header.Get("ETag")
+	resp, _ = cacheRequest(t, app, http.MethodGet, "/customers/c1", nil, map[string]string{"If-None-Match": customerTag})
+	assert.Equal(t, fiber.StatusNotModified, resp.StatusCode)
+	resp, _ = cacheRequest(t, app, http.MethodDelete, "/customers/c1", nil, nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp, _ = cacheRequest(t, app, http.MethodGet, "/customers/c1", nil, map[string]string{"If-None-Match": customerTag})
+	assert.NotEqual(t, fiber.StatusNotModified, resp.StatusCode)
+}
+
+func TestLastModified(t *testing.T) {
+	app := setupCacheTestApp()
+	defer teardownCacheTestDB()
+
+	// Rows last written long ago, as recorded in the audit log
+	for _, id := range []string{"p1", "p2"} {
+		_, err := testCacheDB.Exec("INSERT INTO products (id, name, price) VALUES (?, 'Widget', 1)", id)
+		assert.NoError(t, err)
+		_, err = testCacheDB.Exec("INSERT INTO audit_log (actor, action, resource, resource_id, created_at) VALUES ('seed', 'create', 'products', ?, '2024-01-01T00:00:00.000000Z')", id)
+		assert.NoError(t, err)
+	}
+	since := map[string]string{"If-Modified-Since": "Mon, 01 Jan 2024 00:00:00 GMT"}
+	for _, path := range []string{"/products", "/products/p1", "/products/p2"} {
+		resp, _ := cacheRequest(t, app, http.MethodGet, path, nil, nil)
+		assert.Equal(t, "Mon, 01 Jan 2024 00:00:00 GMT", resp.Header.Get("Last-Modified"), path)
+		resp, body := cacheRequest(t, app, http.MethodGet, path, nil, since)
+		assert.Equal(t, fiber.StatusNotModified, resp.StatusCode, path)
+		assert.Empty(t, body)
+	}
+
+	// Deletes move Last-Modified of the list, even though no remaining row changed
+	resp, _ := cacheRequest(t, app, http.MethodDelete, "/products/p2", nil, nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp, _ = cacheRequest(t, app, http.MethodGet, "/products", nil, since)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp, _ = cacheRequest(t, app, http.MethodGet, "/products/p1", nil, since)
+	assert.Equal(t, fiber.StatusNotModified, resp.StatusCode)
+
+	// Updates move Last-Modified of the record written
+	resp, _ = cacheRequest(t, app, http.MethodPut, "/products/p1", model.Product{Name: "Gadget", Price: 1}, nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp, body := cacheRequest(t, app, http.MethodGet, "/products/p1", nil, since)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, "Gadget")
+
+	// If-None-Match takes precedence
+	later := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	resp, _ = cacheRequest(t, app, http.MethodGet, "/products/p1", nil, map[string]string{"If-Modified-Since": later})
+	assert.Equal(t, fiber.StatusNotModified, resp.StatusCode)
+	resp, _ = cacheRequest(t, app, http.MethodGet, "/products/p1", nil, map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": later})
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+}
+
+func TestCachedReadsPerDatabase(t *testing.T) {
+	setupCacheTestApp()
+	defer teardownCacheTestDB()
+
+	other, err := database.InitializeDB(filepath.Join(t.TempDir(), "other.db"), "file://../database/migrations")
+	assert.NoError(t, err)
+	defer other.Close()
+	_, err = other.Exec("INSERT INTO products (id, name, price) VALUES ('p2', 'Other', 1)")
+	assert.NoError(t, err)
+
+	products := repository.NewProductRepository(testCacheDB)
+	products.EnableCache(time.Minute, 100)
+	ctx := context.Background()
+	main, _, err := products.GetProductsCached(ctx)
+	assert.NoError(t, err)
+	tenant, _, err := products.GetProductsCached(repository.WithDatabase(ctx, other))
+	assert.NoError(t, err)
+	assert.Empty(t, main)
+	if assert.Len(t, tenant, 1) {
+		assert.Equal(t, "p2", tenant[0].ID)
+	}
+}
+
+func TestCacheBounds(t *testing.T) {
+	c := cache.New[string, int](50*time.Millisecond, 2)
+	loads := 0
+	load := func(value int) func() (int, error) {
+		return func() (int, error) {
+			loads++
+			return value, nil
+		}
+	}
+
+	c.Get("a", load(1))
+	c.Get("b", load(2))
+	value, _ := c.Get("a", load(1))
+	assert.Equal(t, 1, value)
+	assert.Equal(t, 2, loads)
+
+	// The least recently used entry is evicted
+	c.Get("c", load(3))
+	assert.Equal(t, 2, c.Len())
+	c.Get("a", load(1))
+	assert.Equal(t, 3, loads)
+	c.Get("b", load(2))
+	assert.Equal(t, 4, loads)
+
+	// Entries expire and errors are not cached
+	time.Sleep(60 * time.Millisecond)
+	value, _ = c.Get("b", load(20))
+	assert.Equal(t, 20, value)
+	_, err := c.Get("d", func() (int, error) { return 0, errors.New("boom") })
+	assert.Error(t, err)
+	value, _ = c.Get("d", load(4))
+	assert.Equal(t, 4, value)
+
+	// Loads racing a Clear are returned but not stored
+	value, _ = c.Get("e", func() (int, error) {
+		c.Clear()
+		return 5, nil
+	})
+	assert.Equal(t, 5, value)
+	assert.Equal(t, 0, c.Len())
+}
@@ -34,6 +34,7 @@ func setupCustomerTestApp() *fiber.App {
 	customerRepo := repository.NewCustomerRepository(db)
 	customerHandler := handler.NewCustomerHandler(customerRepo)
 	app := fiber.New()
+	app.Use(asPrincipal("*"))
 	routes.SetupCustomerRoutes(app, customerHandler)
 	return app
 }
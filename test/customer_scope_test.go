@@ -0,0 +1,217 @@
+package handler_test
+
+import (
+	"api/auth"
+	"api/config"
+	"api/dto"
+	"api/handler"
+	"api/middleware"
+	"api/model"
+	"api/repository"
+	"api/routes"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"api/database"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+var testCustomerScopeDB *sql.DB
+
+// setupCustomerScopeTestApp creates customers c1 and c2 with an order each,
+// o1 and o2, and returns an admin API key.
+func setupCustomerScopeTestApp(t *testing.T) (*fiber.App, string) {
+	dbFile := "test_customer_scope_integration.db"
+	os.Remove(dbFile)
+	migrationDir := "file://../database/migrations"
+	db, err := database.InitializeDB(dbFile, migrationDir)
+	if err != nil {
+		panic(err)
+	}
+	testCustomerScopeDB = db
+
+	ctx := context.Background()
+	userRepo := repository.NewUserRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	roleRepo := repository.NewRoleRepository(db)
+	customerRepo := repository.NewCustomerRepository(db)
+	orderRepo := repository.NewOrderRepository(db)
+	if _, err := userRepo.CreateUser(ctx, model.User{ID: "admin", Name: "Admin", Email: "admin@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := roleRepo.SetUserRoles(ctx, "admin", []string{"admin"}); err != nil {
+		t.Fatal(err)
+	}
+	key, secret, hash := auth.NewAPIKey("admin", "test", nil)
+	if _, err := apiKeyRepo.CreateAPIKey(ctx, key, hash); err != nil {
+		t.Fatal(err)
+	}
+	if err := repository.NewProductRepository(db).CreateProduct(ctx, model.Product{ID: "p1", Name: "Widget", Price: 2.5}); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"1", "2"} {
+		if err := customerRepo.CreateCustomer(ctx, model.Customer{ID: "c" + id, Name: "Customer " + id}); err != nil {
+			t.Fatal(err)
+		}
+		order := model.Order{ID: "o" + id, CustomerID: "c" + id, OrderDate: "2024-01-01", OrderItems: []model.OrderItem{
+			{ID: "i" + id, ProductID: "p1", Quantity: 1, Price: 2.5},
+		}}
+		if err := orderRepo.CreateOrder(ctx, order); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	authenticator, err := auth.NewAuthenticator(config.AuthConfig{Required: true, JWTSecret: testJWTSecret}, apiKeyRepo, userRepo, roleRepo, customerRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := fiber.New()
+	app.Use(middleware.Authenticate(authenticator, true))
+	routes.SetupCustomerRoutes(app, handler.NewCustomerHandler(customerRepo))
+	routes.SetupOrderRoutes(app, handler.NewOrderHandler(orderRepo))
+	routes.SetupAuthRoutes(app, handler.NewAuthHandler(apiKeyRepo))
+	routes.SetupMeRoutes(app, handler.NewMeHandler(customerRepo, orderRepo))
+	return app, secret
+}
+
+func teardownCustomerScopeTestDB() {
+	if testCustomerScopeDB != nil {
+		testCustomerScopeDB.Close()
+		os.Remove("test_customer_scope_integration.db")
+	}
+}
+
+func customerToken(t *testing.T, customerID string) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   customerID,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Kind: auth.TokenKindCustomer,
+	})
+	signed, err := token.SignedString([]byte(testJWTSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func scopeRequest(t *testing.T, app *fiber.App, token string, method string, path string, body any) *http.Response {
+	var reader *bytes.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	return resp
+}
+
+func orderIDs(t *testing.T, resp *http.Response) []string {
+	var orders []model.Order
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&orders))
+	ids := []string{}
+	for _, order := range orders {
+		ids = append(ids, order.ID)
+	}
+	return ids
+}
+
+func TestCustomerScopeOrders(t *testing.T) {
+	app, adminKey := setupCustomerScopeTestApp(t)
+	defer teardownCustomerScopeTestDB()
+
+	c1 := customerToken(t, "c1")
+
+	// Customers only see their own orders; other orders do not exist for them
+	resp := scopeRequest(t, app, c1, http.MethodGet, "/orders", nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"o1"}, orderIDs(t, resp))
+	assert.Equal(t, fiber.StatusOK, scopeRequest(t, app, c1, http.MethodGet, "/orders/o1", nil).StatusCode)
+	assert.Equal(t, fiber.StatusNotFound, scopeRequest(t, app, c1, http.MethodGet, "/orders/o2", nil).StatusCode)
+
+	// Orders are placed for the caller, at the current product prices
+	order := dto.OrderRequest{ID: "o3", OrderDate: "2024-02-01", OrderItems: []dto.OrderItemRequest{
+		{ID: "i3", ProductID: "p1", Quantity: 2, Price: 0.01},
+	}}
+	assert.Equal(t, fiber.StatusCreated, scopeRequest(t, app, c1, http.MethodPost, "/orders", order).StatusCode)
+	stored, err := repository.NewOrderRepository(testCustomerScopeDB).GetOrderByID(context.Background(), "o3", repository.AllOrderIncludes)
+	assert.NoError(t, err)
+	assert.Equal(t, "c1", stored.CustomerID)
+	assert.Equal(t, 2.5, stored.OrderItems[0].Price)
+	order = dto.OrderRequest{ID: "o5", OrderDate: "2024-02-01", OrderItems: []dto.OrderItemRequest{
+		{ID: "i5", ProductID: "p9", Quantity: 1, Price: 0.01},
+	}}
+	assert.Equal(t, fiber.StatusBadRequest, scopeRequest(t, app, c1, http.MethodPost, "/orders", order).StatusCode)
+	order = dto.OrderRequest{ID: "o4", CustomerID: "c2", OrderDate: "2024-02-01", OrderItems: []dto.OrderItemRequest{}}
+	assert.Equal(t, fiber.StatusForbidden, scopeRequest(t, app, c1, http.MethodPost, "/orders", order).StatusCode)
+
+	// Updates cannot reach other customers' orders or move an order to another customer
+	order = dto.OrderRequest{OrderDate: "2024-03-01", OrderItems: []dto.OrderItemRequest{
+		{ID: "i3", ProductID: "p1", Quantity: 1, Price: 0.01},
+	}}
+	assert.Equal(t, fiber.StatusNotFound, scopeRequest(t, app, c1, http.MethodPut, "/orders/o2", order).StatusCode)
+	order.CustomerID = "c2"
+	resp = scopeRequest(t, app, c1, http.MethodPut, "/orders/o3", order)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	var body map[string]string
+	json.NewDecoder(resp.Body).Decode(&body)
+	assert.Equal(t, "customer_id cannot be changed", body["error"])
+	order.CustomerID = ""
+	assert.Equal(t, fiber.StatusOK, scopeRequest(t, app, c1, http.MethodPut, "/orders/o3", order).StatusCode)
+	stored, err = repository.NewOrderRepository(testCustomerScopeDB).GetOrderByID(context.Background(), "o3", repository.AllOrderIncludes)
+	assert.NoError(t, err)
+	assert.Equal(t, "c1", stored.CustomerID)
+	assert.Equal(t, "2024-03-01", stored.OrderDate)
+	assert.Equal(t, 2.5, stored.OrderItems[0].Price)
+
+	// Everything else stays with staff
+	for _, path := range []string{"/customers", "/customers/c1", "/orders/export", "/auth/keys"} {
+		assert.Equal(t, fiber.StatusForbidden, scopeRequest(t, app, c1, http.MethodGet, path, nil).StatusCode, path)
+	}
+	assert.Equal(t, fiber.StatusForbidden, scopeRequest(t, app, c1, http.MethodDelete, "/orders/o1", nil).StatusCode)
+
+	// Staff still see every order
+	resp = scopeRequest(t, app, adminKey, http.MethodGet, "/orders", nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.ElementsMatch(t, []string{"o1", "o2", "o3"}, orderIDs(t, resp))
+	assert.Equal(t, fiber.StatusNotFound, scopeRequest(t, app, adminKey, http.MethodGet, "/orders/o9", nil).StatusCode)
+
+	// Tokens for unknown customers are rejected
+	assert.Equal(t, fiber.StatusUnauthorized, scopeRequest(t, app, customerToken(t, "c9"), http.MethodGet, "/me", nil).StatusCode)
+}
+
+func TestCustomerScopeMe(t *testing.T) {
+	app, adminKey := setupCustomerScopeTestApp(t)
+	defer teardownCustomerScopeTestDB()
+
+	resp := scopeRequest(t, app, customerToken(t, "c2"), http.MethodGet, "/me", nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var customer model.Customer
+	json.NewDecoder(resp.Body).Decode(&customer)
+	assert.Equal(t, "c2", customer.ID)
+
+	resp = scopeRequest(t, app, customerToken(t, "c2"), http.MethodGet, "/me/orders", nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"o2"}, orderIDs(t, resp))
+
+	// Users are not customers
+	assert.Equal(t, fiber.StatusNotFound, scopeRequest(t, app, adminKey, http.MethodGet, "/me", nil).StatusCode)
+	assert.Equal(t, fiber.StatusNotFound, scopeRequest(t, app, adminKey, http.MethodGet, "/me/orders", nil).StatusCode)
+}
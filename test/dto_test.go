@@ -0,0 +1,79 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func rawRequest(t *testing.T, app *fiber.App, method string, path string, body string) (int, map[string]any) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	var decoded map[string]any
+	json.NewDecoder(resp.Body).Decode(&decoded)
+	return resp.StatusCode, decoded
+}
+
+func TestRequestDTOs(t *testing.T) {
+	app := setupVersionTestApp(nil)
+	defer teardownVersionTestDB()
+
+	status, _ := rawRequest(t, app, http.MethodPost, "/v1/customers", `{"id":"c1","name":"Alice"}`)
+	assert.Equal(t, fiber.StatusCreated, status)
+	status, _ = rawRequest(t, app, http.MethodPost, "/v1/products", `{"id":"p1","name":"Widget","price":2.5}`)
+	assert.Equal(t, fiber.StatusCreated, status)
+
+	// Fields that are only part of responses, or not part of the API, are rejected
+	for path, body := range map[string]string{
+		"/v1/orders":          `{"id":"o1","customer_id":"c1","customer":{"id":"c2","name":"Mallory"},"order_items":[]}`,
+		"/v2/orders":          `{"id":"o1","customer_id":"c1","order_items":[]}`,
+		"/v1/products":        `{"id":"p2","name":"Gadget","price":1,"stock":3}`,
+		"/v1/customers":       `{"id":"c2","name":"Bob"} {"id":"c3"}`,
+		"/v1/webhooks":        `{"id":"w1","url":"https://example.com","secret":"s","consecutive_failures":0}`,
+		"/v1/products:batch":  `{"operations":[{"op":"create","data":{"id":"p3","name":"Gizmo","price":1,"cost":0.5}}]}`,
+		"/v1/customers:batch": `{"operations":[{"op":"create","data":{"id":"c4","name":"Carol"}}],"dry_run":true}`,
+	} {
+		status, _ := rawRequest(t, app, http.MethodPost, path, body)
+		assert.Equal(t, fiber.StatusBadRequest, status, path)
+	}
+	status, response := rawRequest(t, app, http.MethodPost, "/v1/orders", `{"id":"o1","customer_id":"c1","order_items":[{"id":"i1","product_id":"p1","product":{"id":"p1","price":0},"quantity":1,"price":2.5}]}`)
+	assert.Equal(t, fiber.StatusBadRequest, status)
+	assert.Contains(t, response["error"], `unknown field "product"`)
+	status, response = rawRequest(t, app, http.MethodPost, "/v1/orders:batch", `{"operations":[{"op":"create","data":{"id":"o1","customer":{}}}]}`)
+	assert.Equal(t, fiber.StatusBadRequest, status)
+	assert.Contains(t, response["error"], "Invalid data at index 0")
+
+	// Responses are built from the response DTOs
+	status, _ = rawRequest(t, app, http.MethodPost, "/v1/orders", `{"id":"o1","order_date":"2026-10-19","customer_id":"c1","order_items":[{"id":"i1","product_id":"p1","quantity":2,"price":2.5}]}`)
+	assert.Equal(t, fiber.StatusCreated, status)
+	status, response = rawRequest(t, app, http.MethodGet, "/v1/orders/o1", "")
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, []string{"customer", "customer_id", "id", "order_date", "order_items"}, keys(response))
+	item := response["order_items"].([]any)[0].(map[string]any)
+	assert.Equal(t, []string{"id", "order_id", "price", "product", "product_id", "quantity"}, keys(item))
+	assert.Equal(t, "Widget", item["product"].(map[string]any)["name"])
+
+	status, _ = rawRequest(t, app, http.MethodPost, "/v1/webhooks", `{"id":"w1","url":"https://example.com/hook","secret":"s3cret"}`)
+	assert.Equal(t, fiber.StatusCreated, status)
+	status, response = rawRequest(t, app, http.MethodGet, "/v1/webhooks/w1", "")
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.NotContains(t, response, "secret")
+	assert.Equal(t, true, response["active"])
+}
+
+func keys(object map[string]any) []string {
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,180 @@
+package handler_test
+
+import (
+	"api/handler"
+	"api/model"
+	"api/repository"
+	"api/routes"
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"api/database"
+
+	"github.com/gofiber/fiber/v2"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+var testEventDB *sql.DB
+var testEventHandler *handler.EventHandler
+
+func setupEventTestApp() *fiber.App {
+	dbFile := "test_event_integration.db"
+	os.Remove(dbFile)
+	migrationDir := "file://../database/migrations"
+	db, err := database.InitializeDB(dbFile, migrationDir)
+	if err != nil {
+		panic(err)
+	}
+	testEventDB = db
+	productRepo := repository.NewProductRepository(db)
+	customerRepo := repository.NewCustomerRepository(db)
+	productHandler := handler.NewProductHandler(productRepo)
+	customerHandler := handler.NewCustomerHandler(customerRepo)
+	eventHandler := handler.NewEventHandler(repository.NewOutboxRepository(db))
+	eventHandler.PollInterval = 10 * time.Millisecond
+	eventHandler.HeartbeatInterval = 50 * time.Millisecond
+	testEventHandler = eventHandler
+	app := fiber.New()
+	app.Use(asPrincipal("*"))
+	routes.SetupProductRoutes(app, productHandler)
+	routes.SetupCustomerRoutes(app, customerHandler)
+	routes.SetupEventRoutes(app, eventHandler)
+	return app
+}
+
+func teardownEventTestDB() {
+	if testEventDB != nil {
+		testEventDB.Close()
+		os.Remove("test_event_integration.db")
+	}
+}
+
+// readSSE collects frames from an event stream until n non-comment frames are read.
+func readSSE(t *testing.T, reader *bufio.Reader, n int) (frames []map[string]string, heartbeats int) {
+	frame := map[string]string{}
+	for len(frames) < n {
+		line, err := reader.ReadString('\n')
+		if !assert.NoError(t, err) {
+			return
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			if len(frame) > 0 {
+				frames = append(frames, frame)
+				frame = map[string]string{}
+			}
+		case line == ": heartbeat":
+			heartbeats++
+		case !strings.HasPrefix(line, "retry:"):
+			field, value, _ := strings.Cut(line, ": ")
+			frame[field] = value
+		}
+	}
+	return frames, heartbeats
+}
+
+func TestEventIntegration(t *testing.T) {
+	app := setupEventTestApp()
+	defer teardownEventTestDB()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go app.Listener(listener)
+	defer app.Shutdown()
+	baseURL := "http://" + listener.Addr().String()
+
+	// Produce product and customer events
+	product := model.Product{ID: "p1", Name: "Test Product", Price: 9.99}
+	body, _ := json.Marshal(product)
+	req := httptest.NewRequest(http.MethodPost, "/products", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	customer := model.Customer{ID: "c1", Name: "Test Customer"}
+	body, _ = json.Marshal(customer)
+	req = httptest.NewRequest(http.MethodPost, "/customers", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	// Resume from the start, filtered to customers
+	streamReq, _ := http.NewRequest(http.MethodGet, baseURL+"/events?resource=customers", nil)
+	streamReq.Header.Set("Last-Event-ID", "0")
+	streamResp, err := http.DefaultClient.Do(streamReq)
+	assert.NoError(t, err)
+	defer streamResp.Body.Close()
+	assert.Equal(t, fiber.StatusOK, streamResp.StatusCode)
+	assert.Equal(t, "text/event-stream", streamResp.Header.Get("Content-Type"))
+	reader := bufio.NewReader(streamResp.Body)
+
+	frames, _ := readSSE(t, reader, 1)
+	assert.Equal(t, "2", frames[0]["id"])
+	assert.Equal(t, model.EventCustomerCreated, frames[0]["event"])
+	var event model.Event
+	json.Unmarshal([]byte(frames[0]["data"]), &event)
+	assert.Equal(t, "c1", event.ResourceID)
+
+	// Live events are pushed, with heartbeats while idle
+	req = httptest.NewRequest(http.MethodDelete, "/customers/c1", nil)
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	frames, _ = readSSE(t, reader, 1)
+	assert.Equal(t, "3", frames[0]["id"])
+	assert.Equal(t, model.EventCustomerDeleted, frames[0]["event"])
+
+	line, err := reader.ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, ": heartbeat\n", line)
+
+	// Invalid resume positions are rejected
+	req = httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set("Last-Event-ID", "abc")
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func TestEventStreamEndsOnClose(t *testing.T) {
+	app := setupEventTestApp()
+	defer teardownEventTestDB()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go app.Listener(listener)
+	baseURL := "http://" + listener.Addr().String()
+
+	streamResp, err := http.Get(baseURL + "/events")
+	assert.NoError(t, err)
+	defer streamResp.Body.Close()
+	reader := bufio.NewReader(streamResp.Body)
+	line, err := reader.ReadString('\n')
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(line, "retry:"))
+
+	// Closing the handler ends the open stream, letting shutdown drain the connection
+	testEventHandler.Close()
+	_, err = io.ReadAll(reader)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	assert.NoError(t, app.ShutdownWithContext(ctx))
+}
@@ -0,0 +1,115 @@
+package handler_test
+
+import (
+	"api/repository"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func listRequest(t *testing.T, app *fiber.App, path string) (int, []map[string]any) {
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+	assert.NoError(t, err)
+	var decoded []map[string]any
+	json.NewDecoder(resp.Body).Decode(&decoded)
+	return resp.StatusCode, decoded
+}
+
+func TestFieldsAndExpand(t *testing.T) {
+	app := setupVersionTestApp(nil)
+	defer teardownVersionTestDB()
+
+	rawRequest(t, app, http.MethodPost, "/v1/customers", `{"id":"c1","name":"Alice"}`)
+	rawRequest(t, app, http.MethodPost, "/v1/products", `{"id":"p1","name":"Widget","price":2.5}`)
+	status, _ := rawRequest(t, app, http.MethodPost, "/v1/orders", `{"id":"o1","order_date":"2026-10-19","customer_id":"c1","order_items":[{"id":"i1","product_id":"p1","quantity":2,"price":2.5}]}`)
+	assert.Equal(t, fiber.StatusCreated, status)
+
+	// Sparse fieldsets
+	status, orders := listRequest(t, app, "/v1/orders?fields=id,order_date")
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, []map[string]any{{"id": "o1", "order_date": "2026-10-19"}}, orders)
+	status, products := listRequest(t, app, "/v1/products?fields=name")
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, []map[string]any{{"name": "Widget"}}, products)
+	status, customer := rawRequest(t, app, http.MethodGet, "/v1/customers/c1?fields=id", "")
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, map[string]any{"id": "c1"}, customer)
+
+	// Expansion
+	status, order := rawRequest(t, app, http.MethodGet, "/v1/orders/o1?expand=customer", "")
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, []string{"customer", "customer_id", "id", "order_date"}, keys(order))
+	status, order = rawRequest(t, app, http.MethodGet, "/v1/orders/o1?expand=order_items", "")
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, []string{"customer_id", "id", "order_date", "order_items"}, keys(order))
+	item := order["order_items"].([]any)[0].(map[string]any)
+	assert.NotContains(t, item, "product")
+	assert.Equal(t, "p1", item["product_id"])
+	status, order = rawRequest(t, app, http.MethodGet, "/v1/orders/o1?expand=order_items.product&fields=id,order_items", "")
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, []string{"id", "order_items"}, keys(order))
+	item = order["order_items"].([]any)[0].(map[string]any)
+	assert.Equal(t, "Widget", item["product"].(map[string]any)["name"])
+
+	// Totals are computed from items that are not sent
+	status, orders = listRequest(t, app, "/v2/orders?expand=&fields=id,total")
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, []map[string]any{{"id": "o1", "total": 5.0}}, orders)
+	status, order = rawRequest(t, app, http.MethodGet, "/v2/orders/o1?expand=items.product", "")
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, []string{"id", "items", "order_date", "total"}, keys(order))
+
+	// Unknown fields and relations are rejected
+	for _, path := range []string{
+		"/v1/orders?fields=id,secret",
+		"/v1/orders?expand=items",
+		"/v2/orders/o1?expand=order_items.product",
+		"/v1/products/p1?fields=cost",
+		"/v1/customers?fields=customer.name",
+	} {
+		status, response := rawRequest(t, app, http.MethodGet, path, "")
+		assert.Equal(t, fiber.StatusBadRequest, status, path)
+		assert.Contains(t, response["error"], "Unknown", path)
+	}
+
+	// The repository only loads what is included
+	stored, err := repository.NewOrderRepository(testVersionDB).GetOrderByID(context.Background(), "o1", repository.OrderIncludes{})
+	assert.NoError(t, err)
+	assert.Equal(t, "c1", stored.CustomerID)
+	assert.Empty(t, stored.Customer.ID)
+	assert.Nil(t, stored.OrderItems)
+	stored, err = repository.NewOrderRepository(testVersionDB).GetOrderByID(context.Background(), "o1", repository.OrderIncludes{Items: true})
+	assert.NoError(t, err)
+	assert.Len(t, stored.OrderItems, 1)
+	assert.Empty(t, stored.OrderItems[0].Product.ID)
+}
+
+func TestExpandDoesNotFilter(t *testing.T) {
+	app := setupVersionTestApp(nil)
+	defer teardownVersionTestDB()
+
+	// An order whose customer and product are gone is served whatever is expanded
+	rawRequest(t, app, http.MethodPost, "/v1/customers", `{"id":"c1","name":"Alice"}`)
+	rawRequest(t, app, http.MethodPost, "/v1/products", `{"id":"p1","name":"Widget","price":2.5}`)
+	status, _ := rawRequest(t, app, http.MethodPost, "/v1/orders", `{"id":"o1","order_date":"2026-10-19","customer_id":"c1","order_items":[{"id":"i1","product_id":"p1","quantity":2,"price":2.5}]}`)
+	assert.Equal(t, fiber.StatusCreated, status)
+	_, err := testVersionDB.Exec("DELETE FROM customers; DELETE FROM products")
+	assert.NoError(t, err)
+
+	for _, expand := range []string{"", "customer", "order_items", "order_items.product", "customer,order_items.product"} {
+		status, orders := listRequest(t, app, "/v1/orders?expand="+expand)
+		assert.Equal(t, fiber.StatusOK, status, expand)
+		assert.Len(t, orders, 1, expand)
+		assert.NotContains(t, orders[0], "customer", expand)
+	}
+	status, order := rawRequest(t, app, http.MethodGet, "/v1/orders/o1?expand=order_items.product", "")
+	assert.Equal(t, fiber.StatusOK, status)
+	item := order["order_items"].([]any)[0].(map[string]any)
+	assert.Equal(t, "p1", item["product_id"])
+	assert.NotContains(t, item, "product")
+}
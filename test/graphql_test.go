@@ -0,0 +1,248 @@
+package handler_test
+
+import (
+	"api/auth"
+	"api/dto"
+	"api/graph"
+	"api/handler"
+	"api/model"
+	"api/repository"
+	"api/routes"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"api/database"
+
+	"github.com/gofiber/fiber/v2"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+var testGraphQLDB *sql.DB
+
+// setupGraphQLTestDB creates customers c1 to c3 with an order each, o1 to
+// o3, of two items of products p1 to p3.
+func setupGraphQLTestDB(t *testing.T) {
+	dbFile := "test_graphql_integration.db"
+	os.Remove(dbFile)
+	migrationDir := "file://../database/migrations"
+	db, err := database.InitializeDB(dbFile, migrationDir)
+	if err != nil {
+		panic(err)
+	}
+	testGraphQLDB = db
+
+	ctx := context.Background()
+	productRepo := repository.NewProductRepository(db)
+	customerRepo := repository.NewCustomerRepository(db)
+	orderRepo := repository.NewOrderRepository(db)
+	for i := 1; i <= 3; i++ {
+		id := fmt.Sprint(i)
+		if err := productRepo.CreateProduct(ctx, model.Product{ID: "p" + id, Name: "Product " + id, Price: float64(i)}); err != nil {
+			t.Fatal(err)
+		}
+		if err := customerRepo.CreateCustomer(ctx, model.Customer{ID: "c" + id, Name: "Customer " + id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 1; i <= 3; i++ {
+		id := fmt.Sprint(i)
+		order := model.Order{ID: "o" + id, CustomerID: "c" + id, OrderDate: "2026-10-0" + id, OrderItems: []model.OrderItem{
+			{ID: "i" + id + "a", ProductID: "p" + id, Quantity: 1, Price: float64(i)},
+			{ID: "i" + id + "b", ProductID: fmt.Sprintf("p%d", i%3+1), Quantity: 2, Price: 1},
+		}}
+		if err := orderRepo.CreateOrder(ctx, order); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func teardownGraphQLTestDB() {
+	if testGraphQLDB != nil {
+		testGraphQLDB.Close()
+		os.Remove("test_graphql_integration.db")
+	}
+}
+
+func graphQLTestApp(principal fiber.Handler) *fiber.App {
+	db := testGraphQLDB
+	app := fiber.New()
+	app.Use(principal)
+	routes.SetupGraphQLRoutes(app, handler.NewGraphQLHandler(graph.NewSchema(
+		repository.NewProductRepository(db), repository.NewCustomerRepository(db), repository.NewOrderRepository(db),
+	)))
+	return app
+}
+
+type graphQLResponse struct {
+	Data   map[string]any `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+func graphQL(t *testing.T, app *fiber.App, query string, variables map[string]any) graphQLResponse {
+	body, _ := json.Marshal(dto.GraphQLRequest{Query: query, Variables: variables})
+	req := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var response graphQLResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	return response
+}
+
+// countCalls counts the repository method calls made until it is stopped.
+func countCalls() (map[string]int, func()) {
+	var mu sync.Mutex
+	calls := map[string]int{}
+	repository.SetObserver(func(repository string, method string, duration time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls[method]++
+	})
+	return calls, func() { repository.SetObserver(nil) }
+}
+
+func TestGraphQLBatchesRelations(t *testing.T) {
+	setupGraphQLTestDB(t)
+	defer teardownGraphQLTestDB()
+	app := graphQLTestApp(asPrincipal("*"))
+
+	calls, stop := countCalls()
+	response := graphQL(t, app, `{
+		customers(first: 3) {
+			nodes { id orders(first: 1) { id customer { name } orderItems { quantity product { name } } } }
+		}
+	}`, nil)
+	stop()
+	assert.Empty(t, response.Errors)
+	customers := response.Data["customers"].(map[string]any)["nodes"].([]any)
+	assert.Len(t, customers, 3)
+	order := customers[1].(map[string]any)["orders"].([]any)[0].(map[string]any)
+	assert.Equal(t, "o2", order["id"])
+	assert.Equal(t, "Customer 2", order["customer"].(map[string]any)["name"])
+	assert.Equal(t, []any{
+		map[string]any{"quantity": 1.0, "product": map[string]any{"name": "Product 2"}},
+		map[string]any{"quantity": 2.0, "product": map[string]any{"name": "Product 3"}},
+	}, order["orderItems"])
+
+	// One query per relation, however many customers, orders and items
+	assert.Equal(t, map[string]int{
+		"ListCustomers":         1,
+		"GetOrdersByCustomers":  1,
+		"GetCustomersByIDs":     1,
+		"GetOrderItemsByOrders": 1,
+		"GetProductsByIDs":      1,
+	}, calls)
+}
+
+func TestGraphQLPaginationAndFilters(t *testing.T) {
+	setupGraphQLTestDB(t)
+	defer teardownGraphQLTestDB()
+	app := graphQLTestApp(asPrincipal("*"))
+
+	query := `query($after: ID) { products(first: 2, after: $after) { nodes { id } pageInfo { hasNextPage endCursor } } }`
+	response := graphQL(t, app, query, nil)
+	products := response.Data["products"].(map[string]any)
+	assert.Equal(t, []any{map[string]any{"id": "p1"}, map[string]any{"id": "p2"}}, products["nodes"])
+	assert.Equal(t, map[string]any{"hasNextPage": true, "endCursor": "p2"}, products["pageInfo"])
+	response = graphQL(t, app, query, map[string]any{"after": "p2"})
+	products = response.Data["products"].(map[string]any)
+	assert.Equal(t, []any{map[string]any{"id": "p3"}}, products["nodes"])
+	assert.Equal(t, map[string]any{"hasNextPage": false, "endCursor": "p3"}, products["pageInfo"])
+
+	response = graphQL(t, app, `{ products(filter: {minPrice: 2, nameContains: "duct"}) { nodes { id } } }`, nil)
+	assert.Equal(t, []any{map[string]any{"id": "p2"}, map[string]any{"id": "p3"}}, response.Data["products"].(map[string]any)["nodes"])
+	response = graphQL(t, app, `{ customers(filter: {nameContains: "2"}) { nodes { id } } }`, nil)
+	assert.Equal(t, []any{map[string]any{"id": "c2"}}, response.Data["customers"].(map[string]any)["nodes"])
+	response = graphQL(t, app, `{ orders(filter: {from: "2026-10-02"}) { nodes { id } } }`, nil)
+	assert.Equal(t, []any{map[string]any{"id": "o2"}, map[string]any{"id": "o3"}}, response.Data["orders"].(map[string]any)["nodes"])
+
+	response = graphQL(t, app, `{ orders(first: 500) { nodes { id } } }`, nil)
+	assert.Len(t, response.Errors, 1)
+	assert.Equal(t, graph.CodeBadUserInput, response.Errors[0].Extensions["code"])
+}
+
+func TestGraphQLMutations(t *testing.T) {
+	setupGraphQLTestDB(t)
+	defer teardownGraphQLTestDB()
+	app := graphQLTestApp(asPrincipal("*"))
+
+	response := graphQL(t, app, `mutation {
+		createProduct(id: "p4", input: {name: "Gadget", price: 4}) { id name }
+		createOrder(id: "o4", input: {customerId: "c1", orderDate: "2026-10-19", orderItems: [{id: "i4", productId: "p4", quantity: 3, price: 4}]}) {
+			id customer { id } orderItems { product { name } }
+		}
+	}`, nil)
+	assert.Empty(t, response.Errors)
+	assert.Equal(t, map[string]any{"id": "p4", "name": "Gadget"}, response.Data["createProduct"])
+	assert.Equal(t, map[string]any{
+		"id":         "o4",
+		"customer":   map[string]any{"id": "c1"},
+		"orderItems": []any{map[string]any{"product": map[string]any{"name": "Gadget"}}},
+	}, response.Data["createOrder"])
+
+	response = graphQL(t, app, `mutation { updateCustomer(id: "c1", input: {name: "Alice"}) { name orders { id } } }`, nil)
+	assert.Empty(t, response.Errors)
+	assert.Equal(t, map[string]any{"name": "Alice", "orders": []any{map[string]any{"id": "o4"}, map[string]any{"id": "o1"}}}, response.Data["updateCustomer"])
+
+	response = graphQL(t, app, `mutation { deleteOrder(id: "o4") }`, nil)
+	assert.Equal(t, true, response.Data["deleteOrder"])
+	response = graphQL(t, app, `mutation { deleteOrder(id: "o4") }`, nil)
+	assert.Equal(t, graph.CodeNotFound, response.Errors[0].Extensions["code"])
+	response = graphQL(t, app, `mutation { createProduct(id: "p1", input: {name: "Again", price: 1}) { id } }`, nil)
+	assert.Equal(t, graph.CodeConflict, response.Errors[0].Extensions["code"])
+}
+
+func TestGraphQLPermissions(t *testing.T) {
+	setupGraphQLTestDB(t)
+	defer teardownGraphQLTestDB()
+
+	// Fields need the permissions of the matching routes
+	app := graphQLTestApp(asPrincipal(auth.PermissionProductsRead, auth.PermissionProductsWrite))
+	response := graphQL(t, app, `{ customers { nodes { id } } }`, nil)
+	assert.Equal(t, "Missing permission customers:read", response.Errors[0].Message)
+	assert.Equal(t, graph.CodeForbidden, response.Errors[0].Extensions["code"])
+	response = graphQL(t, app, `mutation { updateProduct(id: "p1", input: {name: "Cheap", price: 0.5}) { id } }`, nil)
+	assert.Equal(t, graph.CodeForbidden, response.Errors[0].Extensions["code"])
+	response = graphQL(t, app, `mutation { updateProduct(id: "p1", input: {name: "Renamed", price: 1}) { name } }`, nil)
+	assert.Empty(t, response.Errors)
+
+	response = graphQL(t, graphQLTestApp(func(c *fiber.Ctx) error { return c.Next() }), `{ products { nodes { id } } }`, nil)
+	assert.Equal(t, graph.CodeUnauthenticated, response.Errors[0].Extensions["code"])
+
+	// Customers only see and place their own orders
+	app = graphQLTestApp(func(c *fiber.Ctx) error {
+		c.SetUserContext(auth.WithPrincipal(c.UserContext(), model.Principal{
+			Type:        model.PrincipalTypeCustomer,
+			CustomerID:  "c2",
+			Permissions: auth.CustomerPermissions,
+		}))
+		return c.Next()
+	})
+	response = graphQL(t, app, `{ orders { nodes { id customer { id } } } o1: order(id: "o1") { id } }`, nil)
+	assert.Empty(t, response.Errors)
+	assert.Equal(t, []any{map[string]any{"id": "o2", "customer": map[string]any{"id": "c2"}}}, response.Data["orders"].(map[string]any)["nodes"])
+	assert.Nil(t, response.Data["o1"])
+	response = graphQL(t, app, `mutation { createOrder(id: "o5", input: {customerId: "c1", orderDate: "2026-10-19", orderItems: []}) { id } }`, nil)
+	assert.Equal(t, graph.CodeForbidden, response.Errors[0].Extensions["code"])
+	response = graphQL(t, app, `mutation { createOrder(id: "o5", input: {orderDate: "2026-10-19", orderItems: []}) { customerId } }`, nil)
+	assert.Equal(t, map[string]any{"customerId": "c2"}, response.Data["createOrder"])
+	response = graphQL(t, app, `mutation { createOrder(id: "o6", input: {orderDate: "2026-10-19", orderItems: [{id: "i6", productId: "p3", quantity: 1, price: 0.01}]}) { orderItems { price } } }`, nil)
+	assert.Empty(t, response.Errors)
+	assert.Equal(t, map[string]any{"orderItems": []any{map[string]any{"price": 3.0}}}, response.Data["createOrder"])
+	response = graphQL(t, app, `mutation { updateOrder(id: "o1", input: {orderDate: "2026-10-19", orderItems: []}) { id } }`, nil)
+	assert.Equal(t, graph.CodeNotFound, response.Errors[0].Extensions["code"])
+}
@@ -0,0 +1,238 @@
+package handler_test
+
+import (
+	"api/auth"
+	"api/config"
+	"api/model"
+	storev1 "api/proto/store/v1"
+	"api/repository"
+	"api/rpc"
+	"context"
+	"database/sql"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+
+	"api/database"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+var testGRPCDB *sql.DB
+
+// setupGRPCTestConn serves customers c1 and c2 with an order each, o1 and
+// o2, over gRPC and returns a connection to the server and an admin API key.
+func setupGRPCTestConn(t *testing.T) (*grpc.ClientConn, string) {
+	dbFile := "test_grpc_integration.db"
+	os.Remove(dbFile)
+	migrationDir := "file://../database/migrations"
+	db, err := database.InitializeDB(dbFile, migrationDir)
+	if err != nil {
+		panic(err)
+	}
+	testGRPCDB = db
+
+	ctx := context.Background()
+	userRepo := repository.NewUserRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	roleRepo := repository.NewRoleRepository(db)
+	productRepo := repository.NewProductRepository(db)
+	customerRepo := repository.NewCustomerRepository(db)
+	orderRepo := repository.NewOrderRepository(db)
+	if _, err := userRepo.CreateUser(ctx, model.User{ID: "admin", Name: "Admin", Email: "admin@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := roleRepo.SetUserRoles(ctx, "admin", []string{"admin"}); err != nil {
+		t.Fatal(err)
+	}
+	key, secret, hash := auth.NewAPIKey("admin", "test", nil)
+	if _, err := apiKeyRepo.CreateAPIKey(ctx, key, hash); err != nil {
+		t.Fatal(err)
+	}
+	if err := productRepo.CreateProduct(ctx, model.Product{ID: "p1", Name: "Widget", Price: 2.5}); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"1", "2"} {
+		if err := customerRepo.CreateCustomer(ctx, model.Customer{ID: "c" + id, Name: "Customer " + id}); err != nil {
+			t.Fatal(err)
+		}
+		order := model.Order{ID: "o" + id, CustomerID: "c" + id, OrderDate: "2024-01-01", OrderItems: []model.OrderItem{
+			{ID: "i" + id, ProductID: "p1", Quantity: 1, Price: 2.5},
+		}}
+		if err := orderRepo.CreateOrder(ctx, order); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	authenticator, err := auth.NewAuthenticator(config.AuthConfig{Required: true, JWTSecret: testJWTSecret}, apiKeyRepo, userRepo, roleRepo, customerRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := rpc.NewServer(rpc.Options{Authenticator: authenticator, AuthRequired: true}, productRepo, customerRepo, orderRepo)
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, secret
+}
+
+func teardownGRPCTestDB() {
+	if testGRPCDB != nil {
+		testGRPCDB.Close()
+		os.Remove("test_grpc_integration.db")
+	}
+}
+
+// withBearer returns a context sending credential as a bearer token.
+func withBearer(credential string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+credential)
+}
+
+func TestGRPCProductsAndCustomers(t *testing.T) {
+	conn, key := setupGRPCTestConn(t)
+	defer teardownGRPCTestDB()
+	products := storev1.NewProductServiceClient(conn)
+	customers := storev1.NewCustomerServiceClient(conn)
+	ctx := withBearer(key)
+
+	product, err := products.CreateProduct(ctx, &storev1.CreateProductRequest{Product: &storev1.Product{Id: "p2", Name: "Gadget", Price: 4}})
+	assert.NoError(t, err)
+	assert.Equal(t, "Gadget", product.GetName())
+	_, err = products.UpdateProduct(ctx, &storev1.UpdateProductRequest{Product: &storev1.Product{Id: "p2", Name: "Gizmo", Price: 5}})
+	assert.NoError(t, err)
+	product, err = products.GetProduct(ctx, &storev1.GetProductRequest{Id: "p2"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Gizmo", product.GetName())
+	assert.Equal(t, 5.0, product.GetPrice())
+
+	// Pages continue after the last ID of the previous page
+	page, err := products.ListProducts(ctx, &storev1.ListProductsRequest{PageSize: 1})
+	assert.NoError(t, err)
+	assert.Len(t, page.GetProducts(), 1)
+	assert.Equal(t, "p1", page.GetNextPageToken())
+	page, err = products.ListProducts(ctx, &storev1.ListProductsRequest{PageSize: 1, PageToken: page.GetNextPageToken()})
+	assert.NoError(t, err)
+	assert.Equal(t, "p2", page.GetProducts()[0].GetId())
+	assert.Empty(t, page.GetNextPageToken())
+	minPrice := 3.0
+	page, err = products.ListProducts(ctx, &storev1.ListProductsRequest{MinPrice: &minPrice})
+	assert.NoError(t, err)
+	assert.Len(t, page.GetProducts(), 1)
+
+	customerPage, err := customers.ListCustomers(ctx, &storev1.ListCustomersRequest{NameContains: "2"})
+	assert.NoError(t, err)
+	assert.Equal(t, "c2", customerPage.GetCustomers()[0].GetId())
+
+	_, err = products.DeleteProduct(ctx, &storev1.DeleteProductRequest{Id: "p2"})
+	assert.NoError(t, err)
+	_, err = products.DeleteProduct(ctx, &storev1.DeleteProductRequest{Id: "p2"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = customers.CreateCustomer(ctx, &storev1.CreateCustomerRequest{Customer: &storev1.Customer{Id: "c1", Name: "Again"}})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	_, err = customers.CreateCustomer(ctx, &storev1.CreateCustomerRequest{Customer: &storev1.Customer{Name: "Nameless"}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = customers.ListCustomers(ctx, &storev1.ListCustomersRequest{PageSize: 500})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGRPCOrders(t *testing.T) {
+	conn, key := setupGRPCTestConn(t)
+	defer teardownGRPCTestDB()
+	orders := storev1.NewOrderServiceClient(conn)
+
+	// Orders are returned with their customer and the products of their items
+	order, err := orders.CreateOrder(withBearer(key), &storev1.CreateOrderRequest{Order: &storev1.Order{
+		Id: "o3", CustomerId: "c1", OrderDate: "2024-02-01",
+		Items: []*storev1.OrderItem{{Id: "i3", ProductId: "p1", Quantity: 2, Price: 2.5}},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, "Customer 1", order.GetCustomer().GetName())
+	assert.Equal(t, "Widget", order.GetItems()[0].GetProduct().GetName())
+
+	page, err := orders.ListOrders(withBearer(key), &storev1.ListOrdersRequest{CustomerId: "c1"})
+	assert.NoError(t, err)
+	assert.Len(t, page.GetOrders(), 2)
+
+	// Customers only see and change their own orders
+	ctx := withBearer(customerToken(t, "c2"))
+	page, err = orders.ListOrders(ctx, &storev1.ListOrdersRequest{})
+	assert.NoError(t, err)
+	assert.Len(t, page.GetOrders(), 1)
+	assert.Equal(t, "o2", page.GetOrders()[0].GetId())
+	_, err = orders.GetOrder(ctx, &storev1.GetOrderRequest{Id: "o1"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = orders.UpdateOrder(ctx, &storev1.UpdateOrderRequest{Order: &storev1.Order{Id: "o2", CustomerId: "c1", OrderDate: "2024-03-01"}})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	order, err = orders.UpdateOrder(ctx, &storev1.UpdateOrderRequest{Order: &storev1.Order{Id: "o2", OrderDate: "2024-03-01",
+		Items: []*storev1.OrderItem{{Id: "i2", ProductId: "p1", Quantity: 1, Price: 0.01}},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, "c2", order.GetCustomerId())
+	assert.Equal(t, 2.5, order.GetItems()[0].GetPrice())
+	_, err = orders.CreateOrder(ctx, &storev1.CreateOrderRequest{Order: &storev1.Order{Id: "o4", CustomerId: "c1"}})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = orders.DeleteOrder(ctx, &storev1.DeleteOrderRequest{Id: "o2"})
+	assert.Equal(t, "Missing permission orders:delete", status.Convert(err).Message())
+}
+
+func TestGRPCStatusCodes(t *testing.T) {
+	conn, _ := setupGRPCTestConn(t)
+	defer teardownGRPCTestDB()
+	products := storev1.NewProductServiceClient(conn)
+
+	_, err := products.ListProducts(context.Background(), &storev1.ListProductsRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.Equal(t, "Authentication required", status.Convert(err).Message())
+	_, err = products.ListProducts(withBearer("cg_invalid"), &storev1.ListProductsRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = products.ListProducts(withBearer(customerToken(t, "c1")), &storev1.ListProductsRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// Codes follow the status codes of the REST API
+	for httpStatus, code := range map[int]codes.Code{
+		http.StatusBadRequest:          codes.InvalidArgument,
+		http.StatusUnauthorized:        codes.Unauthenticated,
+		http.StatusForbidden:           codes.PermissionDenied,
+		http.StatusNotFound:            codes.NotFound,
+		http.StatusConflict:            codes.AlreadyExists,
+		http.StatusTooManyRequests:     codes.ResourceExhausted,
+		http.StatusInternalServerError: codes.Internal,
+	} {
+		assert.Equal(t, code, rpc.Code(httpStatus), httpStatus)
+	}
+}
+
+func TestGRPCReflection(t *testing.T) {
+	conn, _ := setupGRPCTestConn(t)
+	defer teardownGRPCTestDB()
+
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	assert.NoError(t, err)
+	assert.NoError(t, stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}))
+	response, err := stream.Recv()
+	assert.NoError(t, err)
+	var services []string
+	for _, service := range response.GetListServicesResponse().GetService() {
+		services = append(services, service.GetName())
+	}
+	assert.Subset(t, services, []string{"store.v1.ProductService", "store.v1.CustomerService", "store.v1.OrderService"})
+}
@@ -0,0 +1,138 @@
+package handler_test
+
+import (
+	"api/events"
+	"api/handler"
+	"api/health"
+	"api/model"
+	"api/repository"
+	"api/routes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"api/database"
+
+	"github.com/gofiber/fiber/v2"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+var testHealthDB *sql.DB
+
+func setupHealthTestApp(registry *health.Registry) *fiber.App {
+	dbFile := "test_health_integration.db"
+	os.Remove(dbFile)
+	migrationDir := "file://../database/migrations"
+	db, err := database.InitializeDB(dbFile, migrationDir)
+	if err != nil {
+		panic(err)
+	}
+	testHealthDB = db
+	registry.Register("database", health.Database(db))
+	registry.Register("database_writable", health.Writable(dbFile))
+	registry.Register("migrations", health.Migrations(db, "../database/migrations"))
+	healthHandler := handler.NewHealthHandler(registry)
+	app := fiber.New()
+	routes.SetupHealthRoutes(app, healthHandler)
+	return app
+}
+
+func teardownHealthTestDB() {
+	if testHealthDB != nil {
+		testHealthDB.Close()
+		os.Remove("test_health_integration.db")
+	}
+}
+
+func getHealthReport(t *testing.T, app *fiber.App, path string) (int, model.HealthReport) {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	var report model.HealthReport
+	json.NewDecoder(resp.Body).Decode(&report)
+	return resp.StatusCode, report
+}
+
+func checkStatuses(report model.HealthReport) map[string]string {
+	statuses := map[string]string{}
+	for _, check := range report.Checks {
+		statuses[check.Name] = check.Status
+	}
+	return statuses
+}
+
+func TestHealthIntegration(t *testing.T) {
+	registry := health.NewRegistry()
+	registry.Timeout = 100 * time.Millisecond
+	app := setupHealthTestApp(registry)
+	defer teardownHealthTestDB()
+
+	// Liveness does not depend on any check
+	status, report := getHealthReport(t, app, "/healthz")
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, model.HealthStatusOK, report.Status)
+
+	// Ready with the database up and the schema current
+	status, report = getHealthReport(t, app, "/readyz")
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, model.HealthStatusOK, report.Status)
+	assert.Equal(t, map[string]string{
+		"database":          model.HealthStatusOK,
+		"database_writable": model.HealthStatusOK,
+		"migrations":        model.HealthStatusOK,
+	}, checkStatuses(report))
+
+	// A worker that has not run yet and a check that hangs fail readiness
+	dispatcher := events.NewDispatcher(repository.NewOutboxRepository(testHealthDB))
+	registry.Register("outbox_dispatcher", dispatcher.Check)
+	registry.Register("slow", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	status, report = getHealthReport(t, app, "/readyz")
+	assert.Equal(t, fiber.StatusServiceUnavailable, status)
+	assert.Equal(t, model.HealthStatusFail, report.Status)
+	for _, check := range report.Checks {
+		switch check.Name {
+		case "outbox_dispatcher":
+			assert.Equal(t, "not running", check.Error)
+		case "slow":
+			assert.Equal(t, context.DeadlineExceeded.Error(), check.Error)
+		default:
+			assert.Equal(t, model.HealthStatusOK, check.Status)
+		}
+	}
+
+	// Registering under an existing name replaces the check
+	registry.Register("slow", func(ctx context.Context) error { return nil })
+	ctx, cancel := context.WithCancel(context.Background())
+	dispatcher.PollInterval = 10 * time.Millisecond
+	go dispatcher.Run(ctx)
+	assert.Eventually(t, func() bool {
+		return dispatcher.Check(context.Background()) == nil
+	}, time.Second, 10*time.Millisecond)
+	status, report = getHealthReport(t, app, "/readyz")
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Len(t, report.Checks, 5)
+	cancel()
+
+	// A schema behind the migrations on disk is not ready
+	m, err := database.NewMigrate("test_health_integration.db", "file://../database/migrations")
+	assert.NoError(t, err)
+	assert.NoError(t, m.Steps(-1))
+	m.Close()
+	registry.Register("failing", func(ctx context.Context) error { return errors.New("boom") })
+	status, report = getHealthReport(t, app, "/readyz")
+	assert.Equal(t, fiber.StatusServiceUnavailable, status)
+	statuses := checkStatuses(report)
+	assert.Equal(t, model.HealthStatusFail, statuses["migrations"])
+	assert.Equal(t, model.HealthStatusFail, statuses["failing"])
+	assert.Equal(t, model.HealthStatusOK, statuses["database"])
+}
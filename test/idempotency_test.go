@@ -0,0 +1,146 @@
+package handler_test
+
+import (
+	"api/dto"
+	"api/handler"
+	"api/middleware"
+	"api/model"
+	"api/repository"
+	"api/routes"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"api/database"
+
+	"github.com/gofiber/fiber/v2"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+var testIdempotencyDB *sql.DB
+
+func setupIdempotencyTestApp(ttl time.Duration) *fiber.App {
+	dbFile := "test_idempotency_integration.db"
+	os.Remove(dbFile)
+	migrationDir := "file://../database/migrations"
+	db, err := database.InitializeDB(dbFile, migrationDir)
+	if err != nil {
+		panic(err)
+	}
+	testIdempotencyDB = db
+	productRepo := repository.NewProductRepository(db)
+	customerRepo := repository.NewCustomerRepository(db)
+	orderRepo := repository.NewOrderRepository(db)
+	idempotencyRepo := repository.NewIdempotencyRepository(db)
+	app := fiber.New()
+	app.Use(asPrincipal("*"))
+	app.Use(middleware.Idempotency(idempotencyRepo, ttl))
+	routes.SetupProductRoutes(app, handler.NewProductHandler(productRepo))
+	routes.SetupCustomerRoutes(app, handler.NewCustomerHandler(customerRepo))
+	routes.SetupOrderRoutes(app, handler.NewOrderHandler(orderRepo))
+	routes.SetupAuthRoutes(app, handler.NewAuthHandler(repository.NewAPIKeyRepository(db)))
+	return app
+}
+
+func teardownIdempotencyTestDB() {
+	if testIdempotencyDB != nil {
+		testIdempotencyDB.Close()
+		os.Remove("test_idempotency_integration.db")
+	}
+}
+
+func postWithIdempotencyKey(app *fiber.App, path string, key string, payload any) (*http.Response, error) {
+	body, _ := json.Marshal(payload)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.IdempotencyKeyHeader, key)
+	return app.Test(req)
+}
+
+func TestIdempotencyIntegration(t *testing.T) {
+	app := setupIdempotencyTestApp(200 * time.Millisecond)
+	defer teardownIdempotencyTestDB()
+
+	resp, err := postWithIdempotencyKey(app, "/products", "product-1", model.Product{ID: "p1", Name: "Test Product", Price: 9.99})
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	resp, err = postWithIdempotencyKey(app, "/customers", "customer-1", model.Customer{ID: "c1", Name: "Test Customer"})
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	// A retried POST /orders replays the stored response
+	order := dto.OrderRequest{
+		ID:         "1",
+		OrderDate:  "2024-01-01",
+		CustomerID: "c1",
+		OrderItems: []dto.OrderItemRequest{{ID: "oi1", ProductID: "p1", Quantity: 2, Price: 10.0}},
+	}
+	resp, err = postWithIdempotencyKey(app, "/orders", "order-1", order)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	first, _ := io.ReadAll(resp.Body)
+	assert.Empty(t, resp.Header.Get(middleware.IdempotentReplayedHeader))
+
+	resp, err = postWithIdempotencyKey(app, "/orders", "order-1", order)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	replayed, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "true", resp.Header.Get(middleware.IdempotentReplayedHeader))
+	assert.Equal(t, first, replayed)
+
+	var count int
+	testIdempotencyDB.QueryRow("SELECT COUNT(*) FROM orders").Scan(&count)
+	assert.Equal(t, 1, count)
+
+	// Reusing the key with a different body is rejected
+	changed := order
+	changed.OrderDate = "2024-02-01"
+	resp, err = postWithIdempotencyKey(app, "/orders", "order-1", changed)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+
+	// Server errors are not stored: the retry runs again (and fails again on the duplicate ID)
+	resp, err = postWithIdempotencyKey(app, "/orders", "order-2", order)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusInternalServerError, resp.StatusCode)
+	resp, err = postWithIdempotencyKey(app, "/orders", "order-2", changed)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusInternalServerError, resp.StatusCode)
+
+	// Keys expire after the configured window
+	time.Sleep(250 * time.Millisecond)
+	resp, err = postWithIdempotencyKey(app, "/orders", "order-1", changed)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusInternalServerError, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get(middleware.IdempotentReplayedHeader))
+}
+
+func TestIdempotencyDoesNotStoreSecrets(t *testing.T) {
+	app := setupIdempotencyTestApp(time.Hour)
+	defer teardownIdempotencyTestDB()
+	if _, err := repository.NewUserRepository(testIdempotencyDB).CreateUser(context.Background(), model.User{ID: "test", Name: "Test", Email: "test@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The response carrying the new API key is neither stored nor replayed
+	resp, err := postWithIdempotencyKey(app, "/auth/keys", "key-1", model.APIKeyRequest{Name: "ci"})
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "no-store", resp.Header.Get(fiber.HeaderCacheControl))
+	var count int
+	testIdempotencyDB.QueryRow("SELECT COUNT(*) FROM idempotency_keys").Scan(&count)
+	assert.Equal(t, 0, count)
+
+	resp, err = postWithIdempotencyKey(app, "/auth/keys", "key-1", model.APIKeyRequest{Name: "ci"})
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get(middleware.IdempotentReplayedHeader))
+}
@@ -0,0 +1,183 @@
+package handler_test
+
+import (
+	"api/handler"
+	"api/logging"
+	"api/middleware"
+	"api/model"
+	"api/repository"
+	"api/routes"
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"api/database"
+
+	"github.com/gofiber/fiber/v2"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+var testLoggingDB *sql.DB
+
+// logBuffer collects log output; fiber's test server logs from another goroutine.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (buffer *logBuffer) Write(p []byte) (int, error) {
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+	return buffer.buf.Write(p)
+}
+
+// Records decodes every JSON line written so far and clears the buffer.
+func (buffer *logBuffer) Records(t *testing.T) []map[string]any {
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+	var records []map[string]any
+	scanner := bufio.NewScanner(&buffer.buf)
+	for scanner.Scan() {
+		var record map[string]any
+		if !assert.NoError(t, json.Unmarshal(scanner.Bytes(), &record), scanner.Text()) {
+			continue
+		}
+		records = append(records, record)
+	}
+	buffer.buf.Reset()
+	return records
+}
+
+func setupLoggingTestApp() (*fiber.App, *logBuffer) {
+	logs := &logBuffer{}
+	level := new(slog.LevelVar)
+	slog.SetDefault(logging.New(logs, "json", level))
+
+	dbFile := "test_logging_integration.db"
+	os.Remove(dbFile)
+	migrationDir := "file://../database/migrations"
+	db, err := database.InitializeDB(dbFile, migrationDir)
+	if err != nil {
+		panic(err)
+	}
+	testLoggingDB = db
+	productHandler := handler.NewProductHandler(repository.NewProductRepository(db))
+	app := fiber.New()
+	app.Use(asPrincipal("*"))
+	app.Use(middleware.RequestID())
+	app.Use(middleware.AccessLog())
+	routes.SetupProductRoutes(app, productHandler)
+	routes.SetupAdminRoutes(app, handler.NewAdminHandler(level))
+	return app, logs
+}
+
+func teardownLoggingTestDB() {
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, nil)))
+	if testLoggingDB != nil {
+		testLoggingDB.Close()
+		os.Remove("test_logging_integration.db")
+	}
+}
+
+func TestLoggingIntegration(t *testing.T) {
+	app, logs := setupLoggingTestApp()
+	defer teardownLoggingTestDB()
+
+	// Generate a request ID when none is sent
+	product := model.Product{ID: "p1", Name: "Widget", Price: 2.5}
+	body, _ := json.Marshal(product)
+	req := httptest.NewRequest(http.MethodPost, "/products", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	generated := resp.Header.Get("X-Request-ID")
+	assert.Regexp(t, `^[0-9a-f]{32}$`, generated)
+
+	records := logs.Records(t)
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, "request", records[0]["msg"])
+		assert.Equal(t, "INFO", records[0]["level"])
+		assert.Equal(t, generated, records[0]["request_id"])
+		assert.Equal(t, "POST", records[0]["method"])
+		assert.Equal(t, "/products", records[0]["path"])
+		assert.Equal(t, float64(fiber.StatusCreated), records[0]["status"])
+	}
+
+	// Propagate the caller's request ID to the repository error and the access log
+	req = httptest.NewRequest(http.MethodGet, "/products/missing", nil)
+	req.Header.Set("X-Request-ID", "req-123")
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusInternalServerError, resp.StatusCode)
+	assert.Equal(t, "req-123", resp.Header.Get("X-Request-ID"))
+
+	records = logs.Records(t)
+	if assert.Len(t, records, 2) {
+		assert.Equal(t, "Failed to retrieve product", records[0]["msg"])
+		assert.Equal(t, "ERROR", records[0]["level"])
+		assert.Equal(t, "req-123", records[0]["request_id"])
+		assert.Contains(t, records[0]["error"], "no rows")
+		assert.Equal(t, "request", records[1]["msg"])
+		assert.Equal(t, "ERROR", records[1]["level"])
+		assert.Equal(t, "req-123", records[1]["request_id"])
+	}
+
+	// Replace request IDs that are unsafe to log or echo
+	req = httptest.NewRequest(http.MethodGet, "/products", nil)
+	req.Header.Set("X-Request-ID", strings.Repeat("x", 200))
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Regexp(t, `^[0-9a-f]{32}$`, resp.Header.Get("X-Request-ID"))
+	logs.Records(t)
+}
+
+func TestLogLevelAdminEndpoint(t *testing.T) {
+	app, logs := setupLoggingTestApp()
+	defer teardownLoggingTestDB()
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/log-level", nil)
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var level model.LogLevel
+	json.NewDecoder(resp.Body).Decode(&level)
+	assert.Equal(t, "info", level.Level)
+
+	// Raise the level: successful requests are no longer logged
+	req = httptest.NewRequest(http.MethodPut, "/admin/log-level", strings.NewReader(`{"level":"ERROR"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	json.NewDecoder(resp.Body).Decode(&level)
+	assert.Equal(t, "error", level.Level)
+	logs.Records(t)
+
+	req = httptest.NewRequest(http.MethodGet, "/products", nil)
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Empty(t, logs.Records(t))
+
+	req = httptest.NewRequest(http.MethodGet, "/products/missing", nil)
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Len(t, logs.Records(t), 2)
+
+	// Reject unknown levels
+	req = httptest.NewRequest(http.MethodPut, "/admin/log-level", strings.NewReader(`{"level":"verbose"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
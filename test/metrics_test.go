@@ -0,0 +1,123 @@
+package handler_test
+
+import (
+	"api/dto"
+	"api/events"
+	"api/handler"
+	"api/metrics"
+	"api/middleware"
+	"api/model"
+	"api/repository"
+	"api/routes"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"api/database"
+
+	"github.com/gofiber/fiber/v2"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+var testMetricsDB *sql.DB
+
+func setupMetricsTestApp(appMetrics *metrics.Metrics) *fiber.App {
+	dbFile := "test_metrics_integration.db"
+	os.Remove(dbFile)
+	migrationDir := "file://../database/migrations"
+	db, err := database.InitializeDB(dbFile, migrationDir)
+	if err != nil {
+		panic(err)
+	}
+	testMetricsDB = db
+	appMetrics.RegisterDB(db, "main")
+	repository.SetObserver(appMetrics.ObserveQuery)
+	productHandler := handler.NewProductHandler(repository.NewProductRepository(db))
+	customerHandler := handler.NewCustomerHandler(repository.NewCustomerRepository(db))
+	orderRepository := repository.NewOrderRepository(db)
+	orderRepository.OnOrderCreated(appMetrics.RecordOrderCreated)
+	orderHandler := handler.NewOrderHandler(orderRepository)
+	app := fiber.New()
+	app.Use(asPrincipal("*"))
+	routes.SetupMetricsRoutes(app, appMetrics)
+	app.Use(middleware.Metrics(appMetrics))
+	routes.SetupProductRoutes(app, productHandler)
+	routes.SetupCustomerRoutes(app, customerHandler)
+	routes.SetupOrderRoutes(app, orderHandler)
+	return app
+}
+
+func teardownMetricsTestDB() {
+	repository.SetObserver(nil)
+	if testMetricsDB != nil {
+		testMetricsDB.Close()
+		os.Remove("test_metrics_integration.db")
+	}
+}
+
+func postMetricsJSON(t *testing.T, app *fiber.App, path string, value any) {
+	body, _ := json.Marshal(value)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+}
+
+func TestMetricsIntegration(t *testing.T) {
+	appMetrics := metrics.New()
+	app := setupMetricsTestApp(appMetrics)
+	defer teardownMetricsTestDB()
+
+	postMetricsJSON(t, app, "/products", model.Product{ID: "p1", Name: "Widget", Price: 2.5})
+	postMetricsJSON(t, app, "/customers", model.Customer{ID: "c1", Name: "Customer"})
+	postMetricsJSON(t, app, "/orders", dto.OrderRequest{ID: "o1", CustomerID: "c1", OrderDate: "2024-01-01", OrderItems: []dto.OrderItemRequest{
+		{ID: "i1", ProductID: "p1", Quantity: 4, Price: 2.5},
+		{ID: "i2", ProductID: "p1", Quantity: 1, Price: 10},
+	}})
+	req := httptest.NewRequest(http.MethodGet, "/products/missing", nil)
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusInternalServerError, resp.StatusCode)
+
+	// Orders that are not committed are not counted
+	body, _ := json.Marshal(dto.OrderRequest{ID: "o1", CustomerID: "c1", OrderDate: "2024-01-01"})
+	req = httptest.NewRequest(http.MethodPost, "/orders", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.NotEqual(t, fiber.StatusCreated, resp.StatusCode)
+
+	// Publishing the outbox counts the events, not the orders again
+	bus := events.NewBus()
+	bus.Subscribe(appMetrics.RecordEvent)
+	dispatcher := events.NewDispatcher(repository.NewOutboxRepository(testMetricsDB), bus)
+	published, err := dispatcher.DispatchPending(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, published)
+
+	req = httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	body, _ = io.ReadAll(resp.Body)
+	text := string(body)
+
+	// Requests are labelled by route pattern, not raw path
+	assert.Contains(t, text, `cleango_http_requests_total{method="POST",route="/orders",status="201"} 1`)
+	assert.Contains(t, text, `cleango_http_requests_total{method="GET",route="/products/:id",status="500"} 1`)
+	assert.Contains(t, text, `cleango_http_request_duration_seconds_count{method="POST",route="/products",status="201"} 1`)
+	assert.Contains(t, text, `cleango_repository_call_duration_seconds_count{method="CreateOrder",repository="OrderRepository"} 2`)
+	assert.Contains(t, text, `cleango_repository_call_duration_seconds_count{method="GetProductByID",repository="ProductRepository"} 1`)
+	assert.Contains(t, text, `go_sql_open_connections{db_name="main"}`)
+	assert.Contains(t, text, `cleango_events_published_total{type="order.created"} 1`)
+	assert.Contains(t, text, "cleango_orders_created_total 1")
+	assert.Contains(t, text, "cleango_order_value_total 20")
+}
@@ -0,0 +1,169 @@
+package handler_test
+
+import (
+	"api/events"
+	"api/handler"
+	"api/model"
+	"api/repository"
+	"api/routes"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"api/database"
+
+	"github.com/gofiber/fiber/v2"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+var testOutboxDB *sql.DB
+
+type failingSink struct{}
+
+func (failingSink) Name() string { return "failing" }
+
+func (failingSink) Publish(ctx context.Context, event model.Event) error {
+	return errors.New("unavailable")
+}
+
+func setupOutboxTestApp() *fiber.App {
+	dbFile := "test_outbox_integration.db"
+	os.Remove(dbFile)
+	migrationDir := "file://../database/migrations"
+	db, err := database.InitializeDB(dbFile, migrationDir)
+	if err != nil {
+		panic(err)
+	}
+	testOutboxDB = db
+	productRepo := repository.NewProductRepository(db)
+	productHandler := handler.NewProductHandler(productRepo)
+	app := fiber.New()
+	app.Use(asPrincipal("*"))
+	routes.SetupProductRoutes(app, productHandler)
+	return app
+}
+
+func teardownOutboxTestDB() {
+	if testOutboxDB != nil {
+		testOutboxDB.Close()
+		os.Remove("test_outbox_integration.db")
+	}
+}
+
+func TestOutboxIntegration(t *testing.T) {
+	app := setupOutboxTestApp()
+	defer teardownOutboxTestDB()
+
+	// Create a product and change its price
+	product := model.Product{ID: "1", Name: "Test Product", Price: 9.99}
+	body, _ := json.Marshal(product)
+	req := httptest.NewRequest(http.MethodPost, "/products", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	updated := model.Product{ID: "1", Name: "Test Product", Price: 19.99}
+	body, _ = json.Marshal(updated)
+	req = httptest.NewRequest(http.MethodPut, "/products/1", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	outboxRepo := repository.NewOutboxRepository(testOutboxDB)
+
+	// A failing sink leaves events pending and records the attempt
+	dispatcher := events.NewDispatcher(outboxRepo, failingSink{})
+	dispatcher.BaseBackoff = 0
+	published, err := dispatcher.DispatchPending(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, published)
+	var attempts int
+	var lastError string
+	testOutboxDB.QueryRow("SELECT attempts, last_error FROM outbox ORDER BY id LIMIT 1").Scan(&attempts, &lastError)
+	assert.Equal(t, 1, attempts)
+	assert.Contains(t, lastError, "failing: unavailable")
+
+	// Retrying delivers to the bus and the webhook sink
+	var mu sync.Mutex
+	var busTypes, webhookTypes []string
+	bus := events.NewBus()
+	bus.Subscribe(func(ctx context.Context, event model.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		busTypes = append(busTypes, event.Type)
+		return nil
+	})
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		webhookTypes = append(webhookTypes, r.Header.Get("X-Event-Type"))
+	}))
+	defer receiver.Close()
+
+	dispatcher = events.NewDispatcher(outboxRepo, bus, events.NewWebhookSink(receiver.URL))
+	published, err = dispatcher.DispatchPending(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, published)
+	expected := []string{model.EventProductCreated, model.EventProductUpdated, model.EventProductPriceChanged}
+	assert.Equal(t, expected, busTypes)
+	assert.Equal(t, expected, webhookTypes)
+
+	// Published events are not delivered again
+	published, err = dispatcher.DispatchPending(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, published)
+}
+
+func TestOutboxRetriesFailedSinksOnly(t *testing.T) {
+	app := setupOutboxTestApp()
+	defer teardownOutboxTestDB()
+
+	body, _ := json.Marshal(model.Product{ID: "1", Name: "Test Product", Price: 9.99})
+	req := httptest.NewRequest(http.MethodPost, "/products", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	received := 0
+	bus := events.NewBus()
+	bus.Subscribe(func(ctx context.Context, event model.Event) error {
+		received++
+		return nil
+	})
+	dispatcher := events.NewDispatcher(repository.NewOutboxRepository(testOutboxDB), bus, failingSink{})
+	dispatcher.BaseBackoff = 0
+	dispatcher.MaxAttempts = 3
+
+	// Retries skip the sinks that accepted the event
+	for range 2 {
+		published, err := dispatcher.DispatchPending(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 0, published)
+	}
+	assert.Equal(t, 1, received)
+
+	// The last attempt dead-letters the event, which is not retried any more
+	_, err = dispatcher.DispatchPending(context.Background())
+	assert.NoError(t, err)
+	var attempts int
+	var deadAt sql.NullString
+	testOutboxDB.QueryRow("SELECT attempts, dead_at FROM outbox").Scan(&attempts, &deadAt)
+	assert.Equal(t, 3, attempts)
+	assert.True(t, deadAt.Valid)
+	pending, err := repository.NewOutboxRepository(testOutboxDB).GetPendingEvents(context.Background(), time.Now(), 10)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+	assert.Equal(t, 1, received)
+}
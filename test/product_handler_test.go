@@ -34,6 +34,7 @@ func setupTestApp() *fiber.App {
 	productRepo := repository.NewProductRepository(db)
 	productHandler := handler.NewProductHandler(productRepo)
 	app := fiber.New()
+	app.Use(asPrincipal("*"))
 	routes.SetupProductRoutes(app, productHandler)
 	return app
 }
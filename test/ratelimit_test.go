@@ -0,0 +1,244 @@
+package handler_test
+
+import (
+	"api/auth"
+	"api/config"
+	"api/handler"
+	"api/middleware"
+	"api/model"
+	"api/ratelimit"
+	"api/repository"
+	"api/routes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"api/database"
+
+	"github.com/gofiber/fiber/v2"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+var testRateLimitDB *sql.DB
+
+func setupRateLimitTestDB() *sql.DB {
+	dbFile := "test_ratelimit_integration.db"
+	os.Remove(dbFile)
+	migrationDir := "file://../database/migrations"
+	db, err := database.InitializeDB(dbFile, migrationDir)
+	if err != nil {
+		panic(err)
+	}
+	testRateLimitDB = db
+	return db
+}
+
+// setupRateLimitTestApp limits callers as cfg says and returns API keys of
+// the admins "a" and "b".
+func setupRateLimitTestApp(t *testing.T, cfg config.RateLimitConfig, store ratelimit.Store) (*fiber.App, string, string) {
+	db := testRateLimitDB
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	userRepo := repository.NewUserRepository(db)
+	roleRepo := repository.NewRoleRepository(db)
+	authenticator, err := auth.NewAuthenticator(config.AuthConfig{}, apiKeyRepo, userRepo, roleRepo, repository.NewCustomerRepository(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	limiter, err := ratelimit.NewLimiter(cfg, store, apiKeyRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	app.Use(middleware.RateLimitIP(limiter))
+	app.Use(middleware.Authenticate(authenticator, false))
+	app.Use(middleware.RateLimit(limiter))
+	routes.SetupProductRoutes(app, handler.NewProductHandler(repository.NewProductRepository(db)))
+	routes.SetupOrderRoutes(app, handler.NewOrderHandler(repository.NewOrderRepository(db)))
+	routes.SetupAuthRoutes(app, handler.NewAuthHandler(apiKeyRepo))
+	return app, createAdmin(t, context.Background(), db, "a"), createAdmin(t, context.Background(), db, "b")
+}
+
+func teardownRateLimitTestDB() {
+	if testRateLimitDB != nil {
+		testRateLimitDB.Close()
+		os.Remove("test_ratelimit_integration.db")
+	}
+}
+
+func limitedRequest(t *testing.T, app *fiber.App, key string, path string) *http.Response {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	return resp
+}
+
+func TestRateLimitStores(t *testing.T) {
+	db := setupRateLimitTestDB()
+	defer teardownRateLimitTestDB()
+
+	for name, store := range map[string]ratelimit.Store{
+		"memory": ratelimit.NewMemoryStore(),
+		"sqlite": ratelimit.NewSQLiteStore(repository.NewRateLimitRepository(db)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			limit := config.RateLimit{Rate: 1, Burst: 2}
+			now := time.Now()
+
+			// Buckets start full, then refill at the rate up to the burst
+			for _, step := range []struct {
+				after     time.Duration
+				allowed   bool
+				remaining float64
+			}{
+				{0, true, 1},
+				{0, true, 0},
+				{0, false, 0},
+				{500 * time.Millisecond, false, 0.5},
+				{time.Second, true, 0},
+				{time.Minute, true, 1},
+			} {
+				remaining, allowed, err := store.Take(ctx, "k", limit, now.Add(step.after))
+				assert.NoError(t, err)
+				assert.Equal(t, step.allowed, allowed, step)
+				assert.InDelta(t, step.remaining, remaining, 0.01, step)
+			}
+			remaining, allowed, err := store.Take(ctx, "other", limit, now)
+			assert.NoError(t, err)
+			assert.True(t, allowed)
+			assert.Equal(t, 1.0, remaining)
+
+			// Pruned buckets start over
+			assert.NoError(t, store.Prune(ctx, now.Add(2*time.Minute)))
+			remaining, _, err = store.Take(ctx, "k", limit, now.Add(2*time.Minute))
+			assert.NoError(t, err)
+			assert.Equal(t, 1.0, remaining)
+		})
+	}
+}
+
+func TestRateLimitCallers(t *testing.T) {
+	db := setupRateLimitTestDB()
+	defer teardownRateLimitTestDB()
+
+	app, keyA, keyB := setupRateLimitTestApp(t, config.RateLimitConfig{
+		CallerRate:  0.001,
+		CallerBurst: 3,
+		IPRate:      0.001,
+		IPBurst:     100,
+		Routes:      []string{"orders=0.001/1"},
+	}, ratelimit.NewSQLiteStore(repository.NewRateLimitRepository(db)))
+
+	// Each key has its own bucket
+	for i := 2; i >= 0; i-- {
+		resp := limitedRequest(t, app, keyA, "/products")
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, "3", resp.Header.Get("RateLimit-Limit"))
+		assert.Equal(t, strconv.Itoa(i), resp.Header.Get("RateLimit-Remaining"))
+	}
+	resp := limitedRequest(t, app, keyA, "/products")
+	assert.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "0", resp.Header.Get("RateLimit-Remaining"))
+	retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	assert.NoError(t, err)
+	assert.Greater(t, retryAfter, 0)
+
+	// Route groups have their own, tighter limits
+	resp = limitedRequest(t, app, keyB, "/orders")
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "1", resp.Header.Get("RateLimit-Limit"))
+	assert.Equal(t, "0", resp.Header.Get("RateLimit-Remaining"))
+	assert.Equal(t, fiber.StatusTooManyRequests, limitedRequest(t, app, keyB, "/orders/o1").StatusCode)
+	assert.Equal(t, fiber.StatusOK, limitedRequest(t, app, keyB, "/products").StatusCode)
+}
+
+func TestRateLimitIP(t *testing.T) {
+	setupRateLimitTestDB()
+	defer teardownRateLimitTestDB()
+
+	app, keyA, _ := setupRateLimitTestApp(t, config.RateLimitConfig{
+		CallerRate:  0.001,
+		CallerBurst: 100,
+		IPRate:      0.001,
+		IPBurst:     2,
+	}, ratelimit.NewMemoryStore())
+
+	// Invalid credentials count against the IP before authentication
+	assert.Equal(t, fiber.StatusUnauthorized, limitedRequest(t, app, "cg_invalid", "/products").StatusCode)
+	resp := limitedRequest(t, app, keyA, "/products")
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "0", resp.Header.Get("RateLimit-Remaining"))
+	assert.Equal(t, fiber.StatusTooManyRequests, limitedRequest(t, app, keyA, "/products").StatusCode)
+	assert.Equal(t, fiber.StatusTooManyRequests, limitedRequest(t, app, "", "/products").StatusCode)
+}
+
+func TestRateLimitDailyQuota(t *testing.T) {
+	setupRateLimitTestDB()
+	defer teardownRateLimitTestDB()
+
+	app, keyA, _ := setupRateLimitTestApp(t, config.RateLimitConfig{
+		CallerRate:  100,
+		CallerBurst: 100,
+		IPRate:      100,
+		IPBurst:     100,
+		DailyQuota:  2,
+	}, ratelimit.NewMemoryStore())
+
+	// Keys can be created with their own quota, 0 meaning unlimited
+	req := httptest.NewRequest(http.MethodPost, "/auth/keys", strings.NewReader(`{"name":"unlimited","daily_quota":0}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", keyA)
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	var unlimited model.CreatedAPIKey
+	json.NewDecoder(resp.Body).Decode(&unlimited)
+	assert.Equal(t, 0, *unlimited.DailyQuota)
+
+	// The default quota is used up by creating the key and one more request
+	assert.Equal(t, fiber.StatusOK, limitedRequest(t, app, keyA, "/products").StatusCode)
+	resp = limitedRequest(t, app, keyA, "/products")
+	assert.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+	var body map[string]string
+	json.NewDecoder(resp.Body).Decode(&body)
+	assert.Equal(t, "Daily quota of 2 requests exceeded", body["error"])
+	retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, retryAfter, 24*60*60)
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, fiber.StatusOK, limitedRequest(t, app, unlimited.Key, "/products").StatusCode)
+	}
+
+	// Usage is tracked per key and day; rejected requests are not counted
+	resp = limitedRequest(t, app, unlimited.Key, "/auth/keys/"+unlimited.ID+"/usage")
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var usage []model.APIKeyUsage
+	json.NewDecoder(resp.Body).Decode(&usage)
+	if assert.Len(t, usage, 1) {
+		assert.Equal(t, time.Now().UTC().Format(time.DateOnly), usage[0].Day)
+		assert.Equal(t, 4, usage[0].Requests)
+	}
+	keys, err := repository.NewAPIKeyRepository(testRateLimitDB).GetAPIKeys(context.Background(), "a")
+	assert.NoError(t, err)
+	for _, key := range keys {
+		if key.ID != unlimited.ID {
+			usage, err := repository.NewAPIKeyRepository(testRateLimitDB).GetAPIKeyUsage(context.Background(), "a", key.ID, "2000-01-01")
+			assert.NoError(t, err)
+			assert.Equal(t, 2, usage[0].Requests)
+		}
+	}
+	assert.Equal(t, fiber.StatusNotFound, limitedRequest(t, app, unlimited.Key, "/auth/keys/missing/usage").StatusCode)
+}
@@ -0,0 +1,233 @@
+package handler_test
+
+import (
+	"api/auth"
+	"api/config"
+	"api/dto"
+	"api/handler"
+	"api/middleware"
+	"api/model"
+	"api/repository"
+	"api/routes"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"api/database"
+
+	"github.com/gofiber/fiber/v2"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+// asPrincipal stands in for middleware.Authenticate, serving every request as
+// a user holding permissions.
+func asPrincipal(permissions ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.SetUserContext(auth.WithPrincipal(c.UserContext(), model.Principal{
+			Type:        model.PrincipalTypeUser,
+			UserID:      "test",
+			Name:        "Test",
+			Method:      model.AuthMethodAPIKey,
+			Permissions: permissions,
+		}))
+		return c.Next()
+	}
+}
+
+var testRBACDB *sql.DB
+
+// setupRBACTestApp creates a user per role in users and returns their API keys.
+func setupRBACTestApp(t *testing.T, users map[string][]string) (*fiber.App, map[string]string) {
+	dbFile := "test_rbac_integration.db"
+	os.Remove(dbFile)
+	migrationDir := "file://../database/migrations"
+	db, err := database.InitializeDB(dbFile, migrationDir)
+	if err != nil {
+		panic(err)
+	}
+	testRBACDB = db
+
+	ctx := context.Background()
+	userRepo := repository.NewUserRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	roleRepo := repository.NewRoleRepository(db)
+	keys := map[string]string{}
+	for id, roles := range users {
+		if _, err := userRepo.CreateUser(ctx, model.User{ID: id, Name: id, Email: id + "@example.com"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := roleRepo.SetUserRoles(ctx, id, roles); err != nil {
+			t.Fatal(err)
+		}
+		key, secret, hash := auth.NewAPIKey(id, "test", nil)
+		if _, err := apiKeyRepo.CreateAPIKey(ctx, key, hash); err != nil {
+			t.Fatal(err)
+		}
+		keys[id] = secret
+	}
+	authenticator, err := auth.NewAuthenticator(config.AuthConfig{Required: true}, apiKeyRepo, userRepo, roleRepo, repository.NewCustomerRepository(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	app.Use(middleware.Authenticate(authenticator, true))
+	routes.SetupProductRoutes(app, handler.NewProductHandler(repository.NewProductRepository(db)))
+	routes.SetupCustomerRoutes(app, handler.NewCustomerHandler(repository.NewCustomerRepository(db)))
+	routes.SetupOrderRoutes(app, handler.NewOrderHandler(repository.NewOrderRepository(db)))
+	routes.SetupAuditRoutes(app, handler.NewAuditHandler(repository.NewAuditRepository(db)))
+	routes.SetupRoleRoutes(app, handler.NewRoleHandler(roleRepo))
+	return app, keys
+}
+
+func teardownRBACTestDB() {
+	if testRBACDB != nil {
+		testRBACDB.Close()
+		os.Remove("test_rbac_integration.db")
+	}
+}
+
+func rbacRequest(t *testing.T, app *fiber.App, key string, method string, path string, body any) *http.Response {
+	var reader *bytes.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", key)
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	return resp
+}
+
+func TestRBACRoutePermissions(t *testing.T) {
+	app, keys := setupRBACTestApp(t, map[string][]string{
+		"admin":   {"admin"},
+		"support": {"support"},
+		"nobody":  {},
+	})
+	defer teardownRBACTestDB()
+
+	product := model.Product{ID: "p1", Name: "Widget", Price: 2.5}
+	customer := model.Customer{ID: "c1", Name: "Customer"}
+	order := dto.OrderRequest{ID: "o1", CustomerID: "c1", OrderDate: "2024-01-01", OrderItems: []dto.OrderItemRequest{
+		{ID: "i1", ProductID: "p1", Quantity: 1, Price: 2.5},
+	}}
+	assert.Equal(t, fiber.StatusCreated, rbacRequest(t, app, keys["admin"], http.MethodPost, "/products", product).StatusCode)
+	assert.Equal(t, fiber.StatusCreated, rbacRequest(t, app, keys["admin"], http.MethodPost, "/customers", customer).StatusCode)
+
+	// Support staff read customers and manage orders, but do not change the catalog
+	assert.Equal(t, fiber.StatusOK, rbacRequest(t, app, keys["support"], http.MethodGet, "/customers", nil).StatusCode)
+	assert.Equal(t, fiber.StatusForbidden, rbacRequest(t, app, keys["support"], http.MethodPut, "/customers/c1", customer).StatusCode)
+	assert.Equal(t, fiber.StatusForbidden, rbacRequest(t, app, keys["support"], http.MethodPut, "/products/p1", product).StatusCode)
+	assert.Equal(t, fiber.StatusCreated, rbacRequest(t, app, keys["support"], http.MethodPost, "/orders", order).StatusCode)
+	assert.Equal(t, fiber.StatusForbidden, rbacRequest(t, app, keys["support"], http.MethodGet, "/audit", nil).StatusCode)
+
+	// Only admins delete orders, including through a batch
+	resp := rbacRequest(t, app, keys["support"], http.MethodDelete, "/orders/o1", nil)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	var body map[string]string
+	json.NewDecoder(resp.Body).Decode(&body)
+	assert.Equal(t, "Missing permission orders:delete", body["error"])
+	batch := model.BatchRequest[dto.OrderRequest]{Operations: []model.BatchOperation[dto.OrderRequest]{{Op: model.BatchOpDelete, Data: dto.OrderRequest{ID: "o1"}}}}
+	assert.Equal(t, fiber.StatusForbidden, rbacRequest(t, app, keys["support"], http.MethodPost, "/orders:batch", batch).StatusCode)
+	assert.Equal(t, fiber.StatusOK, rbacRequest(t, app, keys["admin"], http.MethodDelete, "/orders/o1", nil).StatusCode)
+
+	// Users without roles can do nothing; roles are managed by admins
+	assert.Equal(t, fiber.StatusForbidden, rbacRequest(t, app, keys["nobody"], http.MethodGet, "/products", nil).StatusCode)
+	assert.Equal(t, fiber.StatusForbidden, rbacRequest(t, app, keys["support"], http.MethodGet, "/admin/roles", nil).StatusCode)
+
+	resp = rbacRequest(t, app, keys["admin"], http.MethodPut, "/admin/roles/viewer", model.Role{
+		Description: "Read the catalog",
+		Permissions: []string{"products:*", "customers:read"},
+	})
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, fiber.StatusBadRequest, rbacRequest(t, app, keys["admin"], http.MethodPut, "/admin/roles/bad", model.Role{Permissions: []string{"products read"}}).StatusCode)
+	assert.Equal(t, fiber.StatusBadRequest, rbacRequest(t, app, keys["admin"], http.MethodPut, "/admin/users/nobody/roles", model.UserRoles{Roles: []string{"missing"}}).StatusCode)
+	assert.Equal(t, fiber.StatusNotFound, rbacRequest(t, app, keys["admin"], http.MethodPut, "/admin/users/missing/roles", model.UserRoles{Roles: []string{"viewer"}}).StatusCode)
+	assert.Equal(t, fiber.StatusOK, rbacRequest(t, app, keys["admin"], http.MethodPut, "/admin/users/nobody/roles", model.UserRoles{Roles: []string{"viewer"}}).StatusCode)
+
+	// The new role applies from the next request
+	assert.Equal(t, fiber.StatusOK, rbacRequest(t, app, keys["nobody"], http.MethodGet, "/products", nil).StatusCode)
+	assert.Equal(t, fiber.StatusForbidden, rbacRequest(t, app, keys["nobody"], http.MethodGet, "/orders", nil).StatusCode)
+
+	resp = rbacRequest(t, app, keys["admin"], http.MethodGet, "/admin/roles", nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var roles []model.Role
+	json.NewDecoder(resp.Body).Decode(&roles)
+	names := []string{}
+	for _, role := range roles {
+		names = append(names, role.Name)
+	}
+	assert.Equal(t, []string{"admin", "catalog", "pricing", "support", "viewer"}, names)
+	assert.Equal(t, []string{"customers:read", "products:*"}, roles[4].Permissions)
+}
+
+func TestRBACPriceField(t *testing.T) {
+	app, keys := setupRBACTestApp(t, map[string][]string{
+		"catalog": {"catalog"},
+		"pricing": {"pricing"},
+	})
+	defer teardownRBACTestDB()
+
+	// Creating a product sets its price
+	product := model.Product{ID: "p1", Name: "Widget", Price: 2.5}
+	assert.Equal(t, fiber.StatusForbidden, rbacRequest(t, app, keys["catalog"], http.MethodPost, "/products", product).StatusCode)
+	assert.Equal(t, fiber.StatusCreated, rbacRequest(t, app, keys["pricing"], http.MethodPost, "/products", product).StatusCode)
+
+	// The catalog role may edit other fields as long as the price is unchanged
+	product.Name = "Renamed"
+	assert.Equal(t, fiber.StatusOK, rbacRequest(t, app, keys["catalog"], http.MethodPut, "/products/p1", product).StatusCode)
+	product.Price = 1
+	assert.Equal(t, fiber.StatusForbidden, rbacRequest(t, app, keys["catalog"], http.MethodPut, "/products/p1", product).StatusCode)
+	batch := model.BatchRequest[model.Product]{Operations: []model.BatchOperation[model.Product]{{Op: model.BatchOpUpdate, Data: product}}}
+	resp := rbacRequest(t, app, keys["catalog"], http.MethodPost, "/products:batch", batch)
+	assert.Equal(t, fiber.StatusMultiStatus, resp.StatusCode)
+	var batchResponse model.BatchResponse
+	json.NewDecoder(resp.Body).Decode(&batchResponse)
+	if assert.Len(t, batchResponse.Results, 1) {
+		assert.Equal(t, fiber.StatusForbidden, batchResponse.Results[0].Status)
+	}
+	assert.Equal(t, fiber.StatusOK, rbacRequest(t, app, keys["pricing"], http.MethodPost, "/products:batch", batch).StatusCode)
+
+	stored, err := repository.NewProductRepository(testRBACDB).GetProductByID(context.Background(), "p1")
+	assert.NoError(t, err)
+	assert.Equal(t, "Renamed", stored.Name)
+	assert.Equal(t, 1.0, stored.Price)
+
+	// Imports create products, prices included
+	req := httptest.NewRequest(http.MethodPost, "/products/import?format=csv", bytes.NewReader([]byte("id,name,price\np2,Gadget,3\n")))
+	req.Header.Set("X-API-Key", keys["catalog"])
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+}
+
+func TestRBACAnonymousPermissions(t *testing.T) {
+	_, keys := setupRBACTestApp(t, map[string][]string{"nobody": {}})
+	defer teardownRBACTestDB()
+	db := testRBACDB
+	authenticator, err := auth.NewAuthenticator(config.AuthConfig{AnonymousPermissions: []string{auth.PermissionProductsRead}},
+		repository.NewAPIKeyRepository(db), repository.NewUserRepository(db), repository.NewRoleRepository(db), repository.NewCustomerRepository(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := fiber.New()
+	app.Use(middleware.Authenticate(authenticator, false))
+	routes.SetupProductRoutes(app, handler.NewProductHandler(repository.NewProductRepository(db)))
+
+	// Without credentials callers hold the anonymous permissions only
+	assert.Equal(t, fiber.StatusOK, rbacRequest(t, app, "", http.MethodGet, "/products", nil).StatusCode)
+	assert.Equal(t, fiber.StatusUnauthorized, rbacRequest(t, app, "", http.MethodPost, "/products", model.Product{ID: "p1", Name: "Widget"}).StatusCode)
+	// Authenticated callers hold the permissions of their roles only
+	assert.Equal(t, fiber.StatusForbidden, rbacRequest(t, app, keys["nobody"], http.MethodGet, "/products", nil).StatusCode)
+}
@@ -0,0 +1,214 @@
+package handler_test
+
+import (
+	"api/auth"
+	"api/config"
+	"api/handler"
+	"api/middleware"
+	"api/model"
+	"api/repository"
+	"api/routes"
+	"api/tenant"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"api/database"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testTenantDB      *sql.DB
+	testTenantManager *tenant.Manager
+)
+
+// setupTenantTestApp serves tenants from a temporary directory and returns
+// an API key of the main database's admin.
+func setupTenantTestApp(t *testing.T) (*fiber.App, *config.Config, string) {
+	dbFile := "test_tenant_integration.db"
+	os.Remove(dbFile)
+	migrationDir := "file://../database/migrations"
+	db, err := database.InitializeDB(dbFile, migrationDir)
+	if err != nil {
+		panic(err)
+	}
+	testTenantDB = db
+
+	cfg := config.Default()
+	cfg.Database.MigrationsDir = "../database/migrations"
+	cfg.Auth.JWTSecret = testJWTSecret
+	cfg.Tenancy = config.TenancyConfig{Enabled: true, Dir: t.TempDir()}
+	testTenantManager = tenant.NewManager(cfg, repository.NewTenantRepository(db))
+	if err := testTenantManager.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	userRepo := repository.NewUserRepository(db)
+	roleRepo := repository.NewRoleRepository(db)
+	authenticator, err := auth.NewAuthenticator(cfg.Auth, apiKeyRepo, userRepo, roleRepo, repository.NewCustomerRepository(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	app.Use(middleware.Tenant(testTenantManager, authenticator))
+	app.Use(middleware.Authenticate(authenticator, true))
+	routes.SetupProductRoutes(app, handler.NewProductHandler(repository.NewProductRepository(db)))
+	routes.SetupTenantRoutes(app, handler.NewTenantHandler(testTenantManager))
+	routes.SetupAdminRoutes(app, handler.NewAdminHandler(new(slog.LevelVar)))
+	return app, cfg, createAdmin(t, context.Background(), db, "admin")
+}
+
+func teardownTenantTestDB() {
+	if testTenantManager != nil {
+		testTenantManager.Close()
+	}
+	if testTenantDB != nil {
+		testTenantDB.Close()
+		os.Remove("test_tenant_integration.db")
+	}
+}
+
+// createAdmin creates an admin user in the database ctx selects, falling
+// back to db, and returns an API key for it.
+func createAdmin(t *testing.T, ctx context.Context, db *sql.DB, userID string) string {
+	if _, err := repository.NewUserRepository(db).CreateUser(ctx, model.User{ID: userID, Name: userID, Email: userID + "@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repository.NewRoleRepository(db).SetUserRoles(ctx, userID, []string{"admin"}); err != nil {
+		t.Fatal(err)
+	}
+	key, secret, hash := auth.NewAPIKey(userID, "test", nil)
+	if _, err := repository.NewAPIKeyRepository(db).CreateAPIKey(ctx, key, hash); err != nil {
+		t.Fatal(err)
+	}
+	return secret
+}
+
+func tenantRequest(t *testing.T, app *fiber.App, host string, credential string, method string, path string, body any) *http.Response {
+	var reader *bytes.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(method, "http://"+host+path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+credential)
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	return resp
+}
+
+func productNames(t *testing.T, resp *http.Response) []string {
+	var products []model.Product
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&products))
+	names := []string{}
+	for _, product := range products {
+		names = append(names, product.Name)
+	}
+	return names
+}
+
+func TestTenantIsolation(t *testing.T) {
+	app, cfg, adminKey := setupTenantTestApp(t)
+	defer teardownTenantTestDB()
+
+	// Provision tenants from the main database
+	resp := tenantRequest(t, app, "api.example.com", adminKey, http.MethodPost, "/admin/tenants", model.Tenant{ID: "acme", Name: "Acme", Host: "Acme.Example.com"})
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	var created model.Tenant
+	json.NewDecoder(resp.Body).Decode(&created)
+	assert.Equal(t, "acme.example.com", created.Host)
+	assert.Equal(t, fiber.StatusCreated, tenantRequest(t, app, "api.example.com", adminKey, http.MethodPost, "/admin/tenants", model.Tenant{ID: "globex", Name: "Globex"}).StatusCode)
+	assert.Equal(t, fiber.StatusConflict, tenantRequest(t, app, "api.example.com", adminKey, http.MethodPost, "/admin/tenants", model.Tenant{ID: "acme", Name: "Again"}).StatusCode)
+	assert.Equal(t, fiber.StatusConflict, tenantRequest(t, app, "api.example.com", adminKey, http.MethodPost, "/admin/tenants", model.Tenant{ID: "other", Name: "Other", Host: "acme.example.com"}).StatusCode)
+	assert.Equal(t, fiber.StatusBadRequest, tenantRequest(t, app, "api.example.com", adminKey, http.MethodPost, "/admin/tenants", model.Tenant{ID: "../acme", Name: "Bad"}).StatusCode)
+
+	resp = tenantRequest(t, app, "api.example.com", adminKey, http.MethodGet, "/admin/tenants", nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var tenants []model.Tenant
+	json.NewDecoder(resp.Body).Decode(&tenants)
+	assert.Len(t, tenants, 2)
+
+	// Each tenant gets its own users and data
+	acmeCtx, err := testTenantManager.Context(context.Background(), "acme")
+	assert.NoError(t, err)
+	acmeKey := createAdmin(t, acmeCtx, testTenantDB, "admin")
+	globexCtx, err := testTenantManager.Context(context.Background(), "globex")
+	assert.NoError(t, err)
+	createAdmin(t, globexCtx, testTenantDB, "g1")
+
+	assert.Equal(t, fiber.StatusCreated, tenantRequest(t, app, "acme.example.com:8080", acmeKey, http.MethodPost, "/products", model.Product{ID: "p1", Name: "Anvil", Price: 10}).StatusCode)
+	resp = tenantRequest(t, app, "acme.example.com", acmeKey, http.MethodGet, "/products", nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"Anvil"}, productNames(t, resp))
+	resp = tenantRequest(t, app, "api.example.com", adminKey, http.MethodGet, "/products", nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{}, productNames(t, resp))
+
+	// Credentials only work for the tenant they belong to
+	assert.Equal(t, fiber.StatusUnauthorized, tenantRequest(t, app, "api.example.com", acmeKey, http.MethodGet, "/products", nil).StatusCode)
+	assert.Equal(t, fiber.StatusUnauthorized, tenantRequest(t, app, "acme.example.com", adminKey, http.MethodGet, "/products", nil).StatusCode)
+
+	// Tenants without a host are selected by the token's tenant claim
+	token := func(tenantID string) string {
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, auth.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{Subject: "g1", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+			Tenant:           tenantID,
+		}).SignedString([]byte(testJWTSecret))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	resp = tenantRequest(t, app, "api.example.com", token("globex"), http.MethodGet, "/products", nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{}, productNames(t, resp))
+	assert.Equal(t, fiber.StatusUnauthorized, tenantRequest(t, app, "acme.example.com", token("globex"), http.MethodGet, "/products", nil).StatusCode)
+	assert.Equal(t, fiber.StatusUnauthorized, tenantRequest(t, app, "api.example.com", token(""), http.MethodGet, "/products", nil).StatusCode)
+	assert.Equal(t, fiber.StatusNotFound, tenantRequest(t, app, "api.example.com", token("initech"), http.MethodGet, "/products", nil).StatusCode)
+
+	// Tenant admins cannot manage tenants
+	assert.Equal(t, fiber.StatusForbidden, tenantRequest(t, app, "acme.example.com", acmeKey, http.MethodGet, "/admin/tenants", nil).StatusCode)
+	assert.Equal(t, fiber.StatusForbidden, tenantRequest(t, app, "acme.example.com", acmeKey, http.MethodDelete, "/admin/tenants/globex", nil).StatusCode)
+	assert.Equal(t, fiber.StatusForbidden, tenantRequest(t, app, "acme.example.com", acmeKey, http.MethodPut, "/admin/log-level", model.LogLevel{Level: "debug"}).StatusCode)
+	assert.Equal(t, fiber.StatusOK, tenantRequest(t, app, "api.example.com", adminKey, http.MethodPut, "/admin/log-level", model.LogLevel{Level: "debug"}).StatusCode)
+
+	// Deleting a tenant removes its database; its host then serves the main database
+	path, err := cfg.TenantDatabasePath("acme")
+	assert.NoError(t, err)
+	assert.FileExists(t, path)
+	assert.Equal(t, fiber.StatusNoContent, tenantRequest(t, app, "api.example.com", adminKey, http.MethodDelete, "/admin/tenants/acme", nil).StatusCode)
+	assert.NoFileExists(t, path)
+	assert.Equal(t, fiber.StatusNotFound, tenantRequest(t, app, "api.example.com", adminKey, http.MethodDelete, "/admin/tenants/acme", nil).StatusCode)
+	assert.Equal(t, fiber.StatusUnauthorized, tenantRequest(t, app, "acme.example.com", acmeKey, http.MethodGet, "/products", nil).StatusCode)
+	assert.Equal(t, fiber.StatusOK, tenantRequest(t, app, "acme.example.com", adminKey, http.MethodGet, "/products", nil).StatusCode)
+	_, err = testTenantManager.Context(context.Background(), "acme")
+	assert.ErrorIs(t, err, tenant.ErrUnknownTenant)
+
+	// Tenants are reopened on restart
+	assert.NoError(t, testTenantManager.Close())
+	restarted := tenant.NewManager(cfg, repository.NewTenantRepository(testTenantDB))
+	assert.NoError(t, restarted.Start(context.Background()))
+	defer restarted.Close()
+	id, ok := restarted.Resolve("acme.example.com")
+	assert.False(t, ok, id)
+	_, err = restarted.Context(context.Background(), "globex")
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(cfg.Tenancy.Dir, "globex.db"))
+}
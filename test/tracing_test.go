@@ -0,0 +1,150 @@
+package handler_test
+
+import (
+	"api/config"
+	"api/dto"
+	"api/handler"
+	"api/middleware"
+	"api/model"
+	"api/repository"
+	"api/routes"
+	"api/tracing"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"api/database"
+
+	"github.com/gofiber/fiber/v2"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+var testTracingDB *sql.DB
+
+func setupTracingTestApp() (*fiber.App, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	dbFile := "test_tracing_integration.db"
+	os.Remove(dbFile)
+	migrationDir := "file://../database/migrations"
+	db, err := database.InitializeDB(dbFile, migrationDir)
+	if err != nil {
+		panic(err)
+	}
+	testTracingDB = db
+	productHandler := handler.NewProductHandler(repository.NewProductRepository(db))
+	customerHandler := handler.NewCustomerHandler(repository.NewCustomerRepository(db))
+	orderHandler := handler.NewOrderHandler(repository.NewOrderRepository(db))
+	app := fiber.New()
+	app.Use(asPrincipal("*"))
+	app.Use(middleware.Tracing())
+	routes.SetupProductRoutes(app, productHandler)
+	routes.SetupCustomerRoutes(app, customerHandler)
+	routes.SetupOrderRoutes(app, orderHandler)
+	return app, recorder
+}
+
+func teardownTracingTestDB() {
+	otel.SetTracerProvider(noop.NewTracerProvider())
+	if testTracingDB != nil {
+		testTracingDB.Close()
+		os.Remove("test_tracing_integration.db")
+	}
+}
+
+func TestTracingIntegration(t *testing.T) {
+	app, recorder := setupTracingTestApp()
+	defer teardownTracingTestDB()
+
+	for path, value := range map[string]any{
+		"/products":  model.Product{ID: "p1", Name: "Widget", Price: 2.5},
+		"/customers": model.Customer{ID: "c1", Name: "Customer"},
+	} {
+		body, _ := json.Marshal(value)
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	}
+
+	// Continue the caller's trace
+	order := dto.OrderRequest{ID: "o1", CustomerID: "c1", OrderDate: "2024-01-01", OrderItems: []dto.OrderItemRequest{
+		{ID: "i1", ProductID: "p1", Quantity: 1, Price: 2.5},
+		{ID: "i2", ProductID: "p1", Quantity: 2, Price: 2.5},
+	}}
+	body, _ := json.Marshal(order)
+	req := httptest.NewRequest(http.MethodPost, "/orders", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	assert.Regexp(t, `^00-4bf92f3577b34da6a3ce929d0e0e4736-[0-9a-f]{16}-01$`, resp.Header.Get("traceparent"))
+
+	var server, create sdktrace.ReadOnlySpan
+	var inserts int
+	for _, span := range recorder.Ended() {
+		if span.SpanContext().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			continue
+		}
+		switch {
+		case span.Name() == "POST /orders":
+			server = span
+		case span.Name() == "OrderRepository.CreateOrder":
+			create = span
+		}
+	}
+	if !assert.NotNil(t, server) || !assert.NotNil(t, create) {
+		return
+	}
+	assert.Equal(t, trace.SpanKindServer, server.SpanKind())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+	assert.Equal(t, server.SpanContext().SpanID(), create.Parent().SpanID())
+
+	// Every statement of the order insert is a child of the repository span
+	for _, span := range recorder.Ended() {
+		if span.Parent().SpanID() != create.SpanContext().SpanID() {
+			continue
+		}
+		for _, attr := range span.Attributes() {
+			if attr.Key == "db.statement" && bytes.HasPrefix([]byte(attr.Value.AsString()), []byte("INSERT INTO order_items")) {
+				inserts++
+			}
+		}
+	}
+	assert.Equal(t, 2, inserts)
+}
+
+func TestTracingStdoutExporter(t *testing.T) {
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+	file := filepath.Join(t.TempDir(), "spans.json")
+
+	shutdown, err := tracing.Setup(context.Background(), config.TracingConfig{
+		Exporter: "stdout", ServiceName: "cleango-test", SampleRatio: 1, File: file,
+	})
+	assert.NoError(t, err)
+	_, span := otel.Tracer("test").Start(context.Background(), "offline-span")
+	span.End()
+	assert.NoError(t, shutdown(context.Background()))
+
+	data, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"Name":"offline-span"`)
+	assert.Contains(t, string(data), "cleango-test")
+}
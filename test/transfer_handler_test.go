@@ -0,0 +1,152 @@
+package handler_test
+
+import (
+	"api/handler"
+	"api/model"
+	"api/repository"
+	"api/routes"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"api/database"
+
+	"github.com/gofiber/fiber/v2"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+var testTransferDB *sql.DB
+
+func setupTransferTestApp() *fiber.App {
+	dbFile := "test_transfer_integration.db"
+	os.Remove(dbFile)
+	migrationDir := "file://../database/migrations"
+	db, err := database.InitializeDB(dbFile, migrationDir)
+	if err != nil {
+		panic(err)
+	}
+	testTransferDB = db
+	app := fiber.New()
+	app.Use(asPrincipal("*"))
+	routes.SetupProductRoutes(app, handler.NewProductHandler(repository.NewProductRepository(db)))
+	routes.SetupCustomerRoutes(app, handler.NewCustomerHandler(repository.NewCustomerRepository(db)))
+	routes.SetupOrderRoutes(app, handler.NewOrderHandler(repository.NewOrderRepository(db)))
+	return app
+}
+
+func teardownTransferTestDB() {
+	if testTransferDB != nil {
+		testTransferDB.Close()
+		os.Remove("test_transfer_integration.db")
+	}
+}
+
+func importData(t *testing.T, app *fiber.App, path string, data string) (int, model.ImportReport) {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(data))
+	req.Header.Set("Content-Type", "text/csv")
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	var report model.ImportReport
+	json.NewDecoder(resp.Body).Decode(&report)
+	return resp.StatusCode, report
+}
+
+func exportData(t *testing.T, app *fiber.App, path string) (*http.Response, string) {
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	return resp, string(body)
+}
+
+func TestTransferIntegration(t *testing.T) {
+	app := setupTransferTestApp()
+	defer teardownTransferTestDB()
+
+	products := "SKU,Title,price\n" +
+		"p1,First,1.50\n" +
+		"p2,,2\n" +
+		"p3,Third,abc\n" +
+		"p1,Again,1\n" +
+		"p4,Fourth,4\n"
+
+	// Dry run reports row-level errors and writes nothing
+	status, report := importData(t, app, "/products/import?map=SKU:id,Title:name&dry_run=true", products)
+	assert.Equal(t, fiber.StatusMultiStatus, status)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 5, report.Rows)
+	assert.Equal(t, 2, report.Imported)
+	assert.Equal(t, 3, report.Failed)
+	assert.Equal(t, 3, report.Errors[0].Line)
+	assert.Equal(t, "name: is required", report.Errors[0].Error)
+	assert.Equal(t, 4, report.Errors[1].Line)
+	assert.Equal(t, "duplicate of line 2", report.Errors[2].Error)
+	assert.Equal(t, 0, countTransferRows("products"))
+
+	// Atomic imports write nothing unless every row is valid
+	status, report = importData(t, app, "/products/import?map=SKU:id,Title:name&atomic=true", products)
+	assert.Equal(t, fiber.StatusMultiStatus, status)
+	assert.Equal(t, 0, report.Imported)
+	assert.Equal(t, 0, countTransferRows("products"))
+
+	// Best-effort import writes the valid rows
+	status, report = importData(t, app, "/products/import?map=SKU:id,Title:name", products)
+	assert.Equal(t, fiber.StatusMultiStatus, status)
+	assert.Equal(t, 2, report.Imported)
+	assert.Equal(t, 2, countTransferRows("products"))
+
+	// Missing required columns are rejected
+	status, _ = importData(t, app, "/products/import", "id,name\np9,Nine\n")
+	assert.Equal(t, fiber.StatusBadRequest, status)
+
+	// NDJSON import reports conflicts with existing rows
+	status, report = importData(t, app, "/customers/import?format=ndjson",
+		`{"id": "c1", "name": "Customer One"}`+"\n"+`{"id": "c2", "name": "Customer Two"}`+"\n")
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, 2, report.Imported)
+	status, report = importData(t, app, "/customers/import?format=ndjson", `{"id": "c1", "name": "Again"}`)
+	assert.Equal(t, fiber.StatusMultiStatus, status)
+	assert.Equal(t, 1, report.Errors[0].Line)
+	assert.Equal(t, "c1", report.Errors[0].ID)
+
+	// Orders are grouped from flattened rows
+	orders := "order_id,customer_id,order_date,item_id,product_id,quantity,price\n" +
+		"o1,c1,2024-01-01,i1,p1,2,1.5\n" +
+		"o1,c1,2024-01-01,i2,p4,1,4\n" +
+		"o2,c2,2024-01-02,,,,\n"
+	status, report = importData(t, app, "/orders/import", orders)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, 3, report.Rows)
+	assert.Equal(t, 2, report.Imported)
+	assert.Equal(t, 2, countTransferRows("order_items"))
+
+	// Test GET /products/export
+	resp, body := exportData(t, app, "/products/export")
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/csv; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "id,name,price\np1,First,1.5\np4,Fourth,4\n", body)
+
+	resp, body = exportData(t, app, "/customers/export?format=ndjson")
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+	assert.Equal(t, `{"id":"c1","name":"Customer One"}`+"\n"+`{"id":"c2","name":"Customer Two"}`+"\n", body)
+
+	// Exported orders round-trip through import
+	resp, body = exportData(t, app, "/orders/export")
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, orders, body)
+
+	resp, _ = exportData(t, app, "/orders/export?format=xml")
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func countTransferRows(table string) int {
+	var count int
+	testTransferDB.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&count)
+	return count
+}
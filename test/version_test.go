@@ -0,0 +1,156 @@
+package handler_test
+
+import (
+	"api/config"
+	"api/dto"
+	"api/handler"
+	"api/model"
+	"api/repository"
+	"api/routes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"api/database"
+
+	"github.com/gofiber/fiber/v2"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+var testVersionDB *sql.DB
+
+// setupVersionTestApp serves every API version, deprecated as in schedule.
+func setupVersionTestApp(schedule map[string]config.Deprecation) *fiber.App {
+	dbFile := "test_version_integration.db"
+	os.Remove(dbFile)
+	migrationDir := "file://../database/migrations"
+	db, err := database.InitializeDB(dbFile, migrationDir)
+	if err != nil {
+		panic(err)
+	}
+	testVersionDB = db
+
+	customerRepo := repository.NewCustomerRepository(db)
+	orderRepo := repository.NewOrderRepository(db)
+	app := fiber.New()
+	app.Use(asPrincipal("*"))
+	routes.SetupVersionRoutes(app, routes.Handlers{
+		Product:  handler.NewProductHandler(repository.NewProductRepository(db)),
+		Customer: handler.NewCustomerHandler(customerRepo),
+		Order:    handler.NewOrderHandler(orderRepo),
+		Audit:    handler.NewAuditHandler(repository.NewAuditRepository(db)),
+		Webhook:  handler.NewWebhookHandler(repository.NewWebhookRepository(db)),
+		Event:    handler.NewEventHandler(repository.NewOutboxRepository(db)),
+		Admin:    handler.NewAdminHandler(new(slog.LevelVar)),
+		Auth:     handler.NewAuthHandler(repository.NewAPIKeyRepository(db)),
+		Role:     handler.NewRoleHandler(repository.NewRoleRepository(db)),
+		Me:       handler.NewMeHandler(customerRepo, orderRepo),
+	}, schedule)
+	return app
+}
+
+func teardownVersionTestDB() {
+	if testVersionDB != nil {
+		testVersionDB.Close()
+		os.Remove("test_version_integration.db")
+	}
+}
+
+func TestAPIVersions(t *testing.T) {
+	deprecated := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Now().AddDate(1, 0, 0).Truncate(24 * time.Hour)
+	app := setupVersionTestApp(map[string]config.Deprecation{
+		"unversioned": {Date: deprecated, Sunset: sunset},
+	})
+	defer teardownVersionTestDB()
+
+	resp, _ := cacheRequest(t, app, http.MethodPost, "/v1/customers", model.Customer{ID: "c1", Name: "Alice"}, nil)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	resp, _ = cacheRequest(t, app, http.MethodPost, "/v1/products", model.Product{ID: "p1", Name: "Widget", Price: 2.5}, nil)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	// v2 accepts its own order shape
+	resp, _ = cacheRequest(t, app, http.MethodPost, "/v2/orders", dto.OrderV2Request{
+		ID:         "o1",
+		OrderDate:  "2026-10-19",
+		CustomerID: "c1",
+		Items:      []dto.OrderItemV2Request{{ID: "i1", ProductID: "p1", Quantity: 4, UnitPrice: 2.5}},
+	}, nil)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	// The same order in both representations
+	resp, body := cacheRequest(t, app, http.MethodGet, "/v1/orders/o1", nil, nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var v1 model.Order
+	assert.NoError(t, json.Unmarshal([]byte(body), &v1))
+	assert.Equal(t, "c1", v1.CustomerID)
+	if assert.Len(t, v1.OrderItems, 1) {
+		assert.Equal(t, 2.5, v1.OrderItems[0].Price)
+	}
+	assert.Empty(t, resp.Header.Get("Deprecation"))
+
+	resp, body = cacheRequest(t, app, http.MethodGet, "/v2/orders/o1", nil, nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var v2 map[string]any
+	assert.NoError(t, json.Unmarshal([]byte(body), &v2))
+	assert.NotContains(t, v2, "customer_id")
+	assert.NotContains(t, v2, "order_items")
+	assert.Equal(t, 10.0, v2["total"])
+	assert.Equal(t, "Alice", v2["customer"].(map[string]any)["name"])
+	items := v2["items"].([]any)
+	if assert.Len(t, items, 1) {
+		assert.Equal(t, 2.5, items[0].(map[string]any)["unit_price"])
+		assert.Equal(t, 10.0, items[0].(map[string]any)["subtotal"])
+	}
+
+	resp, body = cacheRequest(t, app, http.MethodGet, "/v2/orders", nil, nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, `"total":10`)
+
+	// v2 batches use the v2 shape too
+	resp, _ = cacheRequest(t, app, http.MethodPost, "/v2/orders:batch", map[string]any{
+		"operations": []map[string]any{{"op": "update", "data": map[string]any{
+			"id": "o1", "order_date": "2026-10-20", "customer_id": "c1",
+			"items": []map[string]any{{"id": "i1", "product_id": "p1", "quantity": 1, "unit_price": 3}},
+		}}},
+	}, nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	_, body = cacheRequest(t, app, http.MethodGet, "/v2/orders/o1", nil, nil)
+	assert.Contains(t, body, `"total":3`)
+
+	// The unversioned routes are v1, deprecated
+	resp, body = cacheRequest(t, app, http.MethodGet, "/orders/o1", nil, nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, `"order_items"`)
+	assert.Equal(t, fmt.Sprintf("@%d", deprecated.Unix()), resp.Header.Get("Deprecation"))
+	assert.Equal(t, sunset.Format(http.TimeFormat), resp.Header.Get("Sunset"))
+	assert.Equal(t, `</v2/orders/o1>; rel="successor-version"`, resp.Header.Get("Link"))
+	resp, _ = cacheRequest(t, app, http.MethodGet, "/v2/products", nil, nil)
+	assert.Empty(t, resp.Header.Get("Deprecation"))
+	assert.Empty(t, resp.Header.Get("Link"))
+}
+
+func TestAPIVersionSunset(t *testing.T) {
+	app := setupVersionTestApp(map[string]config.Deprecation{
+		"v1": {Date: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Sunset: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+	})
+	defer teardownVersionTestDB()
+
+	resp, body := cacheRequest(t, app, http.MethodGet, "/v1/products", nil, nil)
+	assert.Equal(t, fiber.StatusGone, resp.StatusCode)
+	assert.Contains(t, body, "retired on 2026-01-01")
+	assert.Equal(t, "Thu, 01 Jan 2026 00:00:00 GMT", resp.Header.Get("Sunset"))
+
+	// Other versions are unaffected
+	resp, _ = cacheRequest(t, app, http.MethodGet, "/v2/products", nil, nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp, _ = cacheRequest(t, app, http.MethodGet, "/products", nil, nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Deprecation"))
+}
@@ -0,0 +1,248 @@
+package handler_test
+
+import (
+	"api/dto"
+	"api/events"
+	"api/handler"
+	"api/model"
+	"api/repository"
+	"api/routes"
+	"api/webhooks"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+
+	"api/database"
+
+	"github.com/gofiber/fiber/v2"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+var testWebhookDB *sql.DB
+
+func setupWebhookTestApp() *fiber.App {
+	dbFile := "test_webhook_integration.db"
+	os.Remove(dbFile)
+	migrationDir := "file://../database/migrations"
+	db, err := database.InitializeDB(dbFile, migrationDir)
+	if err != nil {
+		panic(err)
+	}
+	testWebhookDB = db
+	customerRepo := repository.NewCustomerRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
+	customerHandler := handler.NewCustomerHandler(customerRepo)
+	webhookHandler := handler.NewWebhookHandler(webhookRepo)
+	app := fiber.New()
+	app.Use(asPrincipal("*"))
+	routes.SetupCustomerRoutes(app, customerHandler)
+	routes.SetupWebhookRoutes(app, webhookHandler)
+	return app
+}
+
+func teardownWebhookTestDB() {
+	if testWebhookDB != nil {
+		testWebhookDB.Close()
+		os.Remove("test_webhook_integration.db")
+	}
+}
+
+func TestWebhookIntegration(t *testing.T) {
+	app := setupWebhookTestApp()
+	defer teardownWebhookTestDB()
+
+	// Local receiver that verifies signatures
+	var mu sync.Mutex
+	var received []string
+	failing := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ := io.ReadAll(r.Body)
+		if !webhooks.Verify("s3cret", r.Header.Get(webhooks.TimestampHeader), body, r.Header.Get(webhooks.SignatureHeader)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		received = append(received, r.Header.Get(webhooks.EventTypeHeader))
+	}))
+	defer receiver.Close()
+
+	// Test POST /webhooks
+	subscription := dto.WebhookRequest{
+		ID:         "erp",
+		URL:        receiver.URL,
+		EventTypes: []string{model.EventCustomerCreated, model.EventCustomerDeleted},
+		Secret:     "s3cret",
+	}
+	body, _ := json.Marshal(subscription)
+	req := httptest.NewRequest(http.MethodPost, "/webhooks", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	// Invalid URLs are rejected
+	invalid := dto.WebhookRequest{ID: "bad", URL: "not a url", Secret: "x"}
+	body, _ = json.Marshal(invalid)
+	req = httptest.NewRequest(http.MethodPost, "/webhooks", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	// Test GET /webhooks/:id does not expose the secret
+	req = httptest.NewRequest(http.MethodGet, "/webhooks/erp", nil)
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var gotSubscription model.WebhookSubscription
+	json.NewDecoder(resp.Body).Decode(&gotSubscription)
+	assert.True(t, gotSubscription.Active)
+	assert.Empty(t, gotSubscription.Secret)
+	assert.Equal(t, subscription.EventTypes, gotSubscription.EventTypes)
+
+	// Create and update a customer; only the create matches the subscription
+	customer := model.Customer{ID: "c1", Name: "Test Customer"}
+	body, _ = json.Marshal(customer)
+	req = httptest.NewRequest(http.MethodPost, "/customers", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	body, _ = json.Marshal(model.Customer{ID: "c1", Name: "Renamed"})
+	req = httptest.NewRequest(http.MethodPut, "/customers/c1", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	webhookRepo := repository.NewWebhookRepository(testWebhookDB)
+	dispatcher := events.NewDispatcher(repository.NewOutboxRepository(testWebhookDB), webhooks.NewSink(webhookRepo))
+	_, err = dispatcher.DispatchPending(context.Background())
+	assert.NoError(t, err)
+
+	deliverer := webhooks.NewDeliverer(webhookRepo)
+	deliverer.BaseBackoff = 0
+	deliverer.DisableAfter = 3
+	delivered, err := deliverer.DeliverPending(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+	assert.Equal(t, []string{model.EventCustomerCreated}, received)
+
+	// Test GET /webhooks/:id/deliveries
+	req = httptest.NewRequest(http.MethodGet, "/webhooks/erp/deliveries", nil)
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var deliveries []model.WebhookDelivery
+	json.NewDecoder(resp.Body).Decode(&deliveries)
+	assert.Len(t, deliveries, 1)
+	assert.Equal(t, model.WebhookDeliverySucceeded, deliveries[0].Status)
+
+	// Test POST /webhooks/:id/deliveries/:deliveryId/replay
+	req = httptest.NewRequest(http.MethodPost, "/webhooks/erp/deliveries/1/replay", nil)
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusAccepted, resp.StatusCode)
+	delivered, err = deliverer.DeliverPending(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+	assert.Equal(t, []string{model.EventCustomerCreated, model.EventCustomerCreated}, received)
+
+	req = httptest.NewRequest(http.MethodPost, "/webhooks/erp/deliveries/99/replay", nil)
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+	// An endpoint that keeps failing is disabled
+	mu.Lock()
+	failing = true
+	mu.Unlock()
+	req = httptest.NewRequest(http.MethodDelete, "/customers/c1", nil)
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	_, err = dispatcher.DispatchPending(context.Background())
+	assert.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err = deliverer.DeliverPending(context.Background())
+		assert.NoError(t, err)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/webhooks/erp", nil)
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	gotSubscription = model.WebhookSubscription{}
+	json.NewDecoder(resp.Body).Decode(&gotSubscription)
+	assert.False(t, gotSubscription.Active)
+	assert.NotNil(t, gotSubscription.DisabledAt)
+	assert.Equal(t, 3, gotSubscription.ConsecutiveFailures)
+
+	// Updates that omit active keep the endpoint disabled
+	body, _ = json.Marshal(dto.WebhookRequest{URL: receiver.URL, EventTypes: subscription.EventTypes})
+	req = httptest.NewRequest(http.MethodPut, "/webhooks/erp", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	req = httptest.NewRequest(http.MethodGet, "/webhooks/erp", nil)
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	gotSubscription = model.WebhookSubscription{}
+	json.NewDecoder(resp.Body).Decode(&gotSubscription)
+	assert.False(t, gotSubscription.Active)
+
+	// Re-enabling the endpoint resumes delivery
+	mu.Lock()
+	failing = false
+	mu.Unlock()
+	active := true
+	body, _ = json.Marshal(dto.WebhookRequest{URL: receiver.URL, EventTypes: subscription.EventTypes, Active: &active})
+	req = httptest.NewRequest(http.MethodPut, "/webhooks/erp", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	delivered, err = deliverer.DeliverPending(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+	assert.Equal(t, model.EventCustomerDeleted, received[len(received)-1])
+
+	// Test DELETE /webhooks/:id
+	req = httptest.NewRequest(http.MethodDelete, "/webhooks/erp", nil)
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	req = httptest.NewRequest(http.MethodGet, "/webhooks/erp", nil)
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+	// Unknown webhooks cannot be updated or deleted
+	for _, setActive := range []*bool{nil, &active} {
+		body, _ = json.Marshal(dto.WebhookRequest{URL: receiver.URL, Active: setActive})
+		req = httptest.NewRequest(http.MethodPut, "/webhooks/erp", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err = app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	}
+	req = httptest.NewRequest(http.MethodDelete, "/webhooks/erp", nil)
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,22 @@
+package transfer
+
+import "api/model"
+
+var CustomerColumns = []string{"id", "name"}
+
+func CustomerRecord(customer model.Customer) []string {
+	return []string{customer.ID, customer.Name}
+}
+
+// ParseCustomer builds a customer from a row and validates it.
+func ParseCustomer(fields map[string]string) (model.Customer, error) {
+	var customer model.Customer
+	var err error
+	if customer.ID, err = requiredField(fields, "id"); err != nil {
+		return customer, err
+	}
+	if customer.Name, err = requiredField(fields, "name"); err != nil {
+		return customer, err
+	}
+	return customer, nil
+}
@@ -0,0 +1,148 @@
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Row is one input row as field name to raw value, after header mapping.
+type Row struct {
+	Line   int
+	Fields map[string]string
+}
+
+// ParseMapping parses "Source Column:field,Other:field2" into a map from
+// source column (or NDJSON key) to field name.
+func ParseMapping(value string) (map[string]string, error) {
+	mapping := map[string]string{}
+	if value == "" {
+		return mapping, nil
+	}
+	for _, pair := range strings.Split(value, ",") {
+		source, field, ok := strings.Cut(pair, ":")
+		source, field = strings.TrimSpace(source), strings.TrimSpace(field)
+		if !ok || source == "" || field == "" {
+			return nil, fmt.Errorf("invalid mapping %q, expected source:field", pair)
+		}
+		mapping[source] = field
+	}
+	return mapping, nil
+}
+
+// Decoder reads CSV rows, keyed by the header line, or NDJSON objects.
+type Decoder struct {
+	mapping    map[string]string
+	csvReader  *csv.Reader
+	header     []string
+	lineReader *bufio.Reader
+	line       int
+}
+
+func NewDecoder(r io.Reader, format Format, mapping map[string]string) (*Decoder, error) {
+	decoder := &Decoder{mapping: mapping}
+	if format == FormatNDJSON {
+		decoder.lineReader = bufio.NewReader(r)
+		return decoder, nil
+	}
+
+	decoder.csvReader = csv.NewReader(r)
+	decoder.csvReader.FieldsPerRecord = -1
+	decoder.csvReader.TrimLeadingSpace = true
+	header, err := decoder.csvReader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("missing CSV header")
+		}
+		return nil, err
+	}
+	for i, column := range header {
+		header[i] = decoder.field(strings.TrimSpace(strings.TrimPrefix(column, "\uFEFF")))
+	}
+	decoder.header = header
+	return decoder, nil
+}
+
+// Header returns the mapped CSV column names, or nil for NDJSON.
+func (decoder *Decoder) Header() []string {
+	return decoder.header
+}
+
+// Next returns the next row, or io.EOF when there are no more. A malformed
+// row is returned with its line number and a non-nil error; decoding can continue.
+func (decoder *Decoder) Next() (Row, error) {
+	if decoder.csvReader != nil {
+		return decoder.nextCSV()
+	}
+	return decoder.nextNDJSON()
+}
+
+func (decoder *Decoder) nextCSV() (Row, error) {
+	record, err := decoder.csvReader.Read()
+	if errors.Is(err, io.EOF) {
+		return Row{}, io.EOF
+	}
+	line, _ := decoder.csvReader.FieldPos(0)
+	row := Row{Line: line, Fields: map[string]string{}}
+	if err != nil {
+		return row, err
+	}
+	if len(record) > len(decoder.header) {
+		return row, fmt.Errorf("expected %d columns, got %d", len(decoder.header), len(record))
+	}
+	for i, value := range record {
+		row.Fields[decoder.header[i]] = strings.TrimSpace(value)
+	}
+	return row, nil
+}
+
+func (decoder *Decoder) nextNDJSON() (Row, error) {
+	for {
+		data, err := decoder.lineReader.ReadBytes('\n')
+		if len(data) == 0 && errors.Is(err, io.EOF) {
+			return Row{}, io.EOF
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return Row{}, err
+		}
+		decoder.line++
+		data = bytes.TrimSpace(data)
+		if len(data) == 0 {
+			continue
+		}
+
+		row := Row{Line: decoder.line, Fields: map[string]string{}}
+		jsonDecoder := json.NewDecoder(bytes.NewReader(data))
+		jsonDecoder.UseNumber()
+		var object map[string]any
+		if err := jsonDecoder.Decode(&object); err != nil {
+			return row, fmt.Errorf("invalid JSON: %w", err)
+		}
+		for key, value := range object {
+			switch value := value.(type) {
+			case nil:
+			case string:
+				row.Fields[decoder.field(key)] = strings.TrimSpace(value)
+			case json.Number:
+				row.Fields[decoder.field(key)] = value.String()
+			case bool:
+				row.Fields[decoder.field(key)] = fmt.Sprint(value)
+			default:
+				return row, fmt.Errorf("field %q must be a scalar value", key)
+			}
+		}
+		return row, nil
+	}
+}
+
+func (decoder *Decoder) field(source string) string {
+	if field, ok := decoder.mapping[source]; ok {
+		return field
+	}
+	return source
+}
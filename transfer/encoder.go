@@ -0,0 +1,59 @@
+package transfer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+)
+
+// Encoder writes flat rows as CSV, with a header line, or as NDJSON objects.
+type Encoder struct {
+	format      Format
+	columns     []string
+	csvWriter   *csv.Writer
+	jsonEncoder *json.Encoder
+	wroteHeader bool
+}
+
+func NewEncoder(w io.Writer, format Format, columns []string) *Encoder {
+	encoder := &Encoder{
+		format:  format,
+		columns: columns,
+	}
+	if format == FormatNDJSON {
+		encoder.jsonEncoder = json.NewEncoder(w)
+	} else {
+		encoder.csvWriter = csv.NewWriter(w)
+	}
+	return encoder
+}
+
+// Encode writes one row. CSV uses record, in column order; NDJSON marshals value,
+// whose JSON keys are expected to match the columns.
+func (encoder *Encoder) Encode(record []string, value any) error {
+	if encoder.jsonEncoder != nil {
+		return encoder.jsonEncoder.Encode(value)
+	}
+	if !encoder.wroteHeader {
+		if err := encoder.csvWriter.Write(encoder.columns); err != nil {
+			return err
+		}
+		encoder.wroteHeader = true
+	}
+	return encoder.csvWriter.Write(record)
+}
+
+// Flush writes any buffered CSV data, including the header of an empty export.
+func (encoder *Encoder) Flush() error {
+	if encoder.csvWriter == nil {
+		return nil
+	}
+	if !encoder.wroteHeader {
+		if err := encoder.csvWriter.Write(encoder.columns); err != nil {
+			return err
+		}
+		encoder.wroteHeader = true
+	}
+	encoder.csvWriter.Flush()
+	return encoder.csvWriter.Error()
+}
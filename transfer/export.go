@@ -0,0 +1,48 @@
+package transfer
+
+import (
+	"api/model"
+	"api/repository"
+	"context"
+	"io"
+)
+
+// Export writes every record produced by each to w. each is called with the
+// function encoding one row.
+func Export(w io.Writer, format Format, columns []string, each func(encode func(record []string, value any) error) error) error {
+	encoder := NewEncoder(w, format, columns)
+	if err := each(encoder.Encode); err != nil {
+		return err
+	}
+	return encoder.Flush()
+}
+
+func ExportProducts(ctx context.Context, w io.Writer, format Format, productRepository *repository.ProductRepository) error {
+	return Export(w, format, ProductColumns, func(encode func([]string, any) error) error {
+		return productRepository.EachProduct(ctx, func(product model.Product) error {
+			return encode(ProductRecord(product), product)
+		})
+	})
+}
+
+func ExportCustomers(ctx context.Context, w io.Writer, format Format, customerRepository *repository.CustomerRepository) error {
+	return Export(w, format, CustomerColumns, func(encode func([]string, any) error) error {
+		return customerRepository.EachCustomer(ctx, func(customer model.Customer) error {
+			return encode(CustomerRecord(customer), customer)
+		})
+	})
+}
+
+// ExportOrders writes one row per order item, or a single row for an order without items.
+func ExportOrders(ctx context.Context, w io.Writer, format Format, orderRepository *repository.OrderRepository) error {
+	return Export(w, format, OrderColumns, func(encode func([]string, any) error) error {
+		return orderRepository.EachOrder(ctx, func(order model.Order) error {
+			for _, row := range OrderRows(order) {
+				if err := encode(row.Record(), row); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
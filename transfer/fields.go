@@ -0,0 +1,59 @@
+package transfer
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// MissingColumns returns the required columns absent from a CSV header.
+func MissingColumns(header []string, required []string) []string {
+	present := map[string]bool{}
+	for _, column := range header {
+		present[column] = true
+	}
+	var missing []string
+	for _, column := range required {
+		if !present[column] {
+			missing = append(missing, column)
+		}
+	}
+	return missing
+}
+
+func requiredField(fields map[string]string, name string) (string, error) {
+	value := fields[name]
+	if value == "" {
+		return "", fmt.Errorf("%s: is required", name)
+	}
+	return value, nil
+}
+
+func nonNegativeFloatField(fields map[string]string, name string) (float64, error) {
+	raw, err := requiredField(fields, name)
+	if err != nil {
+		return 0, err
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %q is not a number", name, raw)
+	}
+	if value < 0 {
+		return 0, fmt.Errorf("%s: must not be negative", name)
+	}
+	return value, nil
+}
+
+func positiveIntField(fields map[string]string, name string) (int, error) {
+	raw, err := requiredField(fields, name)
+	if err != nil {
+		return 0, err
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %q is not an integer", name, raw)
+	}
+	if value <= 0 {
+		return 0, fmt.Errorf("%s: must be positive", name)
+	}
+	return value, nil
+}
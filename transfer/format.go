@@ -0,0 +1,28 @@
+package transfer
+
+import "fmt"
+
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+)
+
+// ParseFormat returns the format named by value, defaulting to CSV when empty.
+func ParseFormat(value string) (Format, error) {
+	switch Format(value) {
+	case "", FormatCSV:
+		return FormatCSV, nil
+	case FormatNDJSON:
+		return FormatNDJSON, nil
+	}
+	return "", fmt.Errorf("unsupported format %q", value)
+}
+
+func (format Format) ContentType() string {
+	if format == FormatNDJSON {
+		return "application/x-ndjson"
+	}
+	return "text/csv; charset=utf-8"
+}
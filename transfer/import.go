@@ -0,0 +1,172 @@
+package transfer
+
+import (
+	"api/model"
+	"api/repository"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// InputError reports a problem with an import as a whole, such as a missing
+// column, as opposed to an error with a single row.
+type InputError struct {
+	message string
+}
+
+func (err *InputError) Error() string {
+	return err.message
+}
+
+type ImportOptions struct {
+	Format Format
+	// Mapping renames source columns (or NDJSON keys) to field names.
+	Mapping map[string]string
+	// DryRun validates against the database without writing.
+	DryRun bool
+	// Atomic imports nothing unless every row is valid and can be written.
+	Atomic bool
+}
+
+// Item is a record to import and the input lines it was read from.
+type Item[T any] struct {
+	Value T
+	Lines []int
+}
+
+// Collector reads every row from decoder, recording invalid ones in report.
+type Collector[T any] func(decoder *Decoder, report *model.ImportReport) []Item[T]
+
+// ApplyFunc writes a batch, such as ProductRepository.ApplyProductBatch.
+type ApplyFunc[T any] func(context.Context, []model.BatchOperation[T], repository.BatchOptions) ([]error, error)
+
+// Import creates the records read from r and reports the outcome of every
+// row. Errors with the input as a whole are returned as *InputError.
+func Import[T any](ctx context.Context, r io.Reader, options ImportOptions, required []string, collect Collector[T], apply ApplyFunc[T], id func(T) string) (model.ImportReport, error) {
+	report := model.ImportReport{
+		Format: string(options.Format),
+		DryRun: options.DryRun,
+		Atomic: options.Atomic,
+		Errors: []model.ImportRowError{},
+	}
+
+	decoder, err := NewDecoder(r, options.Format, options.Mapping)
+	if err != nil {
+		return report, &InputError{"Invalid import data: " + err.Error()}
+	}
+	if header := decoder.Header(); header != nil {
+		if missing := MissingColumns(header, required); len(missing) > 0 {
+			return report, &InputError{"Missing columns: " + strings.Join(missing, ", ")}
+		}
+	}
+
+	items := collect(decoder, &report)
+
+	if len(items) > 0 && !(options.Atomic && len(report.Errors) > 0) {
+		operations := make([]model.BatchOperation[T], len(items))
+		for i, item := range items {
+			operations[i] = model.BatchOperation[T]{Op: model.BatchOpCreate, Data: item.Value}
+		}
+		errs, err := apply(ctx, operations, repository.BatchOptions{
+			BestEffort: !options.Atomic,
+			DryRun:     options.DryRun,
+		})
+		if err != nil {
+			return report, err
+		}
+		for i, itemErr := range errs {
+			if itemErr == nil {
+				report.Imported++
+				continue
+			}
+			if errors.Is(itemErr, repository.ErrBatchRolledBack) {
+				continue
+			}
+			for _, line := range items[i].Lines {
+				report.Errors = append(report.Errors, model.ImportRowError{Line: line, ID: id(items[i].Value), Error: itemErr.Error()})
+			}
+		}
+	}
+
+	slices.SortStableFunc(report.Errors, func(a, b model.ImportRowError) int {
+		return a.Line - b.Line
+	})
+	report.Failed = len(report.Errors)
+	return report, nil
+}
+
+// CollectEach returns a Collector importing one record per row.
+func CollectEach[T any](parse func(fields map[string]string) (T, error), id func(T) string) Collector[T] {
+	return func(decoder *Decoder, report *model.ImportReport) []Item[T] {
+		var items []Item[T]
+		seen := map[string]int{}
+		for {
+			row, err := decoder.Next()
+			if errors.Is(err, io.EOF) {
+				return items
+			}
+			report.Rows++
+			if err != nil {
+				report.Errors = append(report.Errors, model.ImportRowError{Line: row.Line, Error: err.Error()})
+				continue
+			}
+			value, err := parse(row.Fields)
+			if err != nil {
+				report.Errors = append(report.Errors, model.ImportRowError{Line: row.Line, ID: id(value), Error: err.Error()})
+				continue
+			}
+			if line, ok := seen[id(value)]; ok {
+				report.Errors = append(report.Errors, model.ImportRowError{
+					Line: row.Line, ID: id(value), Error: fmt.Sprintf("duplicate of line %d", line),
+				})
+				continue
+			}
+			seen[id(value)] = row.Line
+			items = append(items, Item[T]{Value: value, Lines: []int{row.Line}})
+		}
+	}
+}
+
+// CollectOrders groups flattened order rows into orders.
+func CollectOrders(decoder *Decoder, report *model.ImportReport) []Item[model.Order] {
+	assembler := NewOrderAssembler()
+	for {
+		row, err := decoder.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		report.Rows++
+		if err == nil {
+			err = assembler.Add(row.Line, row.Fields)
+		}
+		if err != nil {
+			report.Errors = append(report.Errors, model.ImportRowError{Line: row.Line, ID: row.Fields["order_id"], Error: err.Error()})
+		}
+	}
+
+	orders, lines := assembler.Orders()
+	items := make([]Item[model.Order], len(orders))
+	for i, order := range orders {
+		items[i] = Item[model.Order]{Value: order, Lines: lines[i]}
+	}
+	return items
+}
+
+func ImportProducts(ctx context.Context, r io.Reader, options ImportOptions, productRepository *repository.ProductRepository) (model.ImportReport, error) {
+	id := func(product model.Product) string { return product.ID }
+	return Import(ctx, r, options, ProductColumns, CollectEach(ParseProduct, id), productRepository.ApplyProductBatch, id)
+}
+
+func ImportCustomers(ctx context.Context, r io.Reader, options ImportOptions, customerRepository *repository.CustomerRepository) (model.ImportReport, error) {
+	id := func(customer model.Customer) string { return customer.ID }
+	return Import(ctx, r, options, CustomerColumns, CollectEach(ParseCustomer, id), customerRepository.ApplyCustomerBatch, id)
+}
+
+// ImportOrders requires the order columns; item columns may be absent for orders without items.
+func ImportOrders(ctx context.Context, r io.Reader, options ImportOptions, orderRepository *repository.OrderRepository) (model.ImportReport, error) {
+	id := func(order model.Order) string { return order.ID }
+	return Import(ctx, r, options, OrderColumns[:3], CollectOrders, orderRepository.ApplyOrderBatch, id)
+}
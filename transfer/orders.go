@@ -0,0 +1,116 @@
+package transfer
+
+import (
+	"api/model"
+	"fmt"
+	"strconv"
+)
+
+// OrderColumns describe an order flattened to one row per item. An order
+// without items is a single row with empty item columns.
+var OrderColumns = []string{"order_id", "customer_id", "order_date", "item_id", "product_id", "quantity", "price"}
+
+// OrderRow is one flattened order row, used as the NDJSON object.
+type OrderRow struct {
+	OrderID    string   `json:"order_id"`
+	CustomerID string   `json:"customer_id"`
+	OrderDate  string   `json:"order_date"`
+	ItemID     string   `json:"item_id,omitempty"`
+	ProductID  string   `json:"product_id,omitempty"`
+	Quantity   *int     `json:"quantity,omitempty"`
+	Price      *float64 `json:"price,omitempty"`
+}
+
+func (row OrderRow) Record() []string {
+	record := []string{row.OrderID, row.CustomerID, row.OrderDate, row.ItemID, row.ProductID, "", ""}
+	if row.Quantity != nil {
+		record[5] = strconv.Itoa(*row.Quantity)
+	}
+	if row.Price != nil {
+		record[6] = formatFloat(*row.Price)
+	}
+	return record
+}
+
+// OrderRows flattens an order into one row per item.
+func OrderRows(order model.Order) []OrderRow {
+	base := OrderRow{OrderID: order.ID, CustomerID: order.CustomerID, OrderDate: order.OrderDate}
+	if len(order.OrderItems) == 0 {
+		return []OrderRow{base}
+	}
+	rows := make([]OrderRow, len(order.OrderItems))
+	for i, orderItem := range order.OrderItems {
+		row := base
+		row.ItemID = orderItem.ID
+		row.ProductID = orderItem.ProductID
+		row.Quantity = &orderItem.Quantity
+		row.Price = &orderItem.Price
+		rows[i] = row
+	}
+	return rows
+}
+
+// OrderAssembler groups flattened rows back into orders, in order of first appearance.
+type OrderAssembler struct {
+	orders []model.Order
+	lines  [][]int
+	index  map[string]int
+}
+
+func NewOrderAssembler() *OrderAssembler {
+	return &OrderAssembler{index: map[string]int{}}
+}
+
+// Add validates a row and merges it into its order.
+func (assembler *OrderAssembler) Add(line int, fields map[string]string) error {
+	var order model.Order
+	var err error
+	if order.ID, err = requiredField(fields, "order_id"); err != nil {
+		return err
+	}
+	if order.CustomerID, err = requiredField(fields, "customer_id"); err != nil {
+		return err
+	}
+	if order.OrderDate, err = requiredField(fields, "order_date"); err != nil {
+		return err
+	}
+
+	var orderItem *model.OrderItem
+	if fields["item_id"] != "" {
+		orderItem = &model.OrderItem{ID: fields["item_id"], OrderID: order.ID}
+		if orderItem.ProductID, err = requiredField(fields, "product_id"); err != nil {
+			return err
+		}
+		if orderItem.Quantity, err = positiveIntField(fields, "quantity"); err != nil {
+			return err
+		}
+		if orderItem.Price, err = nonNegativeFloatField(fields, "price"); err != nil {
+			return err
+		}
+	}
+
+	i, seen := assembler.index[order.ID]
+	if !seen {
+		i = len(assembler.orders)
+		assembler.index[order.ID] = i
+		order.OrderItems = []model.OrderItem{}
+		assembler.orders = append(assembler.orders, order)
+		assembler.lines = append(assembler.lines, nil)
+	} else {
+		existing := assembler.orders[i]
+		if existing.CustomerID != order.CustomerID || existing.OrderDate != order.OrderDate {
+			return fmt.Errorf("order %s: customer_id and order_date must match its other rows", order.ID)
+		}
+	}
+
+	if orderItem != nil {
+		assembler.orders[i].OrderItems = append(assembler.orders[i].OrderItems, *orderItem)
+	}
+	assembler.lines[i] = append(assembler.lines[i], line)
+	return nil
+}
+
+// Orders returns the assembled orders and, for each, the lines it came from.
+func (assembler *OrderAssembler) Orders() ([]model.Order, [][]int) {
+	return assembler.orders, assembler.lines
+}
@@ -0,0 +1,32 @@
+package transfer
+
+import (
+	"api/model"
+	"strconv"
+)
+
+var ProductColumns = []string{"id", "name", "price"}
+
+func ProductRecord(product model.Product) []string {
+	return []string{product.ID, product.Name, formatFloat(product.Price)}
+}
+
+// ParseProduct builds a product from a row and validates it.
+func ParseProduct(fields map[string]string) (model.Product, error) {
+	var product model.Product
+	var err error
+	if product.ID, err = requiredField(fields, "id"); err != nil {
+		return product, err
+	}
+	if product.Name, err = requiredField(fields, "name"); err != nil {
+		return product, err
+	}
+	if product.Price, err = nonNegativeFloatField(fields, "price"); err != nil {
+		return product, err
+	}
+	return product, nil
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
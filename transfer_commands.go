@@ -0,0 +1,161 @@
+package main
+
+import (
+	"api/model"
+	"api/repository"
+	"api/transfer"
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const resources = "products, customers or orders"
+
+// exportCommand writes every record of a resource to a file or stdout.
+func exportCommand(args []string) error {
+	resource, args, err := resourceArg("export", args)
+	if err != nil {
+		return err
+	}
+	fs, flags := newFlagSet("export " + resource)
+	formatName := fs.String("format", string(transfer.FormatCSV), "output format: csv or ndjson")
+	output := fs.String("output", "-", "output file, or - for stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cfg, err := flags.Load()
+	if err != nil {
+		return err
+	}
+	format, err := transfer.ParseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	buffered := bufio.NewWriter(w)
+
+	switch resource {
+	case "products":
+		err = transfer.ExportProducts(context.Background(), buffered, format, repository.NewProductRepository(db))
+	case "customers":
+		err = transfer.ExportCustomers(context.Background(), buffered, format, repository.NewCustomerRepository(db))
+	case "orders":
+		err = transfer.ExportOrders(context.Background(), buffered, format, repository.NewOrderRepository(db))
+	}
+	if err != nil {
+		return err
+	}
+	return buffered.Flush()
+}
+
+// importCommand creates records of a resource from a file or stdin and prints
+// the import report as JSON. It fails when any row was rejected.
+func importCommand(args []string) error {
+	resource, args, err := resourceArg("import", args)
+	if err != nil {
+		return err
+	}
+	fs, flags := newFlagSet("import " + resource)
+	formatName := fs.String("format", "", "input format: csv or ndjson (default: from the file extension, else csv)")
+	input := fs.String("file", "-", "input file, or - for stdin")
+	mappingSpec := fs.String("map", "", `header mapping, e.g. "SKU:id,Title:name"`)
+	dryRun := fs.Bool("dry-run", false, "validate against the database without writing")
+	atomic := fs.Bool("atomic", false, "import nothing unless every row succeeds")
+	actor := fs.String("actor", "cli", "actor recorded in the audit log")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cfg, err := flags.Load()
+	if err != nil {
+		return err
+	}
+
+	if *formatName == "" && strings.HasSuffix(*input, ".ndjson") {
+		*formatName = string(transfer.FormatNDJSON)
+	}
+	format, err := transfer.ParseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+	mapping, err := transfer.ParseMapping(*mappingSpec)
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if *input != "-" {
+		file, err := os.Open(*input)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := repository.WithActor(context.Background(), *actor)
+	options := transfer.ImportOptions{Format: format, Mapping: mapping, DryRun: *dryRun, Atomic: *atomic}
+	var report model.ImportReport
+	switch resource {
+	case "products":
+		report, err = transfer.ImportProducts(ctx, r, options, repository.NewProductRepository(db))
+	case "customers":
+		report, err = transfer.ImportCustomers(ctx, r, options, repository.NewCustomerRepository(db))
+	case "orders":
+		report, err = transfer.ImportOrders(ctx, r, options, repository.NewOrderRepository(db))
+	}
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("import %s: %d of %d rows failed", resource, report.Failed, report.Rows)
+	}
+	return nil
+}
+
+// resourceArg splits off the leading resource name of export and import.
+func resourceArg(command string, args []string) (string, []string, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+			fmt.Fprintf(os.Stderr, "Usage: cleango %s products|customers|orders [flags]\n", command)
+			return "", nil, flag.ErrHelp
+		}
+		return "", nil, fmt.Errorf("%s: expected %s", command, resources)
+	}
+	switch args[0] {
+	case "products", "customers", "orders":
+		return args[0], args[1:], nil
+	}
+	return "", nil, fmt.Errorf("%s: unknown resource %q, expected %s", command, args[0], resources)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"api/model"
+	"api/repository"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// userCommand runs "user create" and "user roles".
+func userCommand(args []string) error {
+	if len(args) > 0 && args[0] == "roles" {
+		return userRolesCommand(args[1:])
+	}
+	if len(args) == 0 || args[0] != "create" {
+		fmt.Fprintln(os.Stderr, "Usage: cleango user create -name NAME -email EMAIL [flags]")
+		fmt.Fprintln(os.Stderr, "       cleango user roles -id ID [-set ROLE,...]")
+		return errors.New("user: expected create or roles")
+	}
+
+	fs, flags := newFlagSet("user create")
+	id := fs.String("id", "", "user ID (default: random)")
+	name := fs.String("name", "", "display name")
+	email := fs.String("email", "", "email address, unique across users")
+	roles := fs.String("roles", "", "comma-separated roles to assign, e.g. admin")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	cfg, err := flags.Load()
+	if err != nil {
+		return err
+	}
+	if *name == "" || *email == "" {
+		return errors.New("user create: -name and -email are required")
+	}
+	if *id == "" {
+		*id = randomID()
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	user, err := repository.NewUserRepository(db).CreateUser(context.Background(), model.User{ID: *id, Name: *name, Email: *email})
+	if repository.IsConflict(err) {
+		return fmt.Errorf("user create: a user with ID %q or email %q already exists", user.ID, user.Email)
+	}
+	if err != nil {
+		return err
+	}
+	if *roles != "" {
+		if err := setUserRoles(context.Background(), db, user.ID, *roles); err != nil {
+			return fmt.Errorf("user create: user was created but %w", err)
+		}
+	}
+	return json.NewEncoder(os.Stdout).Encode(user)
+}
+
+// userRolesCommand prints the roles of a user, after replacing them when -set is given.
+func userRolesCommand(args []string) error {
+	fs, flags := newFlagSet("user roles")
+	id := fs.String("id", "", "user ID")
+	set := fs.String("set", "", "comma-separated roles replacing the current ones; \"-\" removes all")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cfg, err := flags.Load()
+	if err != nil {
+		return err
+	}
+	if *id == "" {
+		return errors.New("user roles: -id is required")
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	if *set != "" {
+		if err := setUserRoles(ctx, db, *id, *set); err != nil {
+			return fmt.Errorf("user roles: %w", err)
+		}
+	}
+	roles, err := repository.NewRoleRepository(db).GetUserRoles(ctx, *id)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(os.Stdout).Encode(model.UserRoles{Roles: roles})
+}
+
+func setUserRoles(ctx context.Context, db *sql.DB, userID string, list string) error {
+	roles := []string{}
+	if list != "-" {
+		roles = strings.Split(list, ",")
+		for i := range roles {
+			roles[i] = strings.TrimSpace(roles[i])
+		}
+	}
+	err := repository.NewRoleRepository(db).SetUserRoles(ctx, userID, roles)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("no user with ID %q", userID)
+	}
+	if errors.Is(err, repository.ErrUnknownRole) {
+		return fmt.Errorf("unknown role in %q", list)
+	}
+	return err
+}
+
+func randomID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
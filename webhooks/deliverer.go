@@ -0,0 +1,147 @@
+package webhooks
+
+import (
+	"api/health"
+	"api/repository"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Deliverer sends pending webhook deliveries, retrying failures with
+// exponential backoff and disabling subscriptions that keep failing.
+type Deliverer struct {
+	webhookRepository *repository.WebhookRepository
+	client            *http.Client
+	heartbeat         health.Heartbeat
+
+	PollInterval time.Duration
+	BatchSize    int
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	DisableAfter int
+	// StallAfter is how long Check tolerates no completed batch.
+	StallAfter time.Duration
+}
+
+func NewDeliverer(webhookRepository *repository.WebhookRepository) *Deliverer {
+	return &Deliverer{
+		webhookRepository: webhookRepository,
+		client:            &http.Client{Timeout: 10 * time.Second},
+		PollInterval:      time.Second,
+		BatchSize:         50,
+		MaxAttempts:       8,
+		BaseBackoff:       5 * time.Second,
+		MaxBackoff:        time.Hour,
+		DisableAfter:      20,
+		StallAfter:        15 * time.Minute,
+	}
+}
+
+// Run sends due deliveries every PollInterval until ctx is done.
+func (deliverer *Deliverer) Run(ctx context.Context) {
+	ticker := time.NewTicker(deliverer.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		_, err := deliverer.DeliverPending(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "webhook deliverer failed", "error", err)
+		}
+		deliverer.heartbeat.Beat(err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check reports whether Run is making progress, for the readiness probe.
+func (deliverer *Deliverer) Check(ctx context.Context) error {
+	return deliverer.heartbeat.Check(deliverer.StallAfter)
+}
+
+// DeliverPending sends one batch of due deliveries and returns how many succeeded.
+func (deliverer *Deliverer) DeliverPending(ctx context.Context) (int, error) {
+	pending, err := deliverer.webhookRepository.GetDueWebhookDeliveries(ctx, time.Now(), deliverer.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	// The outcome of a request that was sent is recorded even when ctx is
+	// cancelled meanwhile, so that it is not sent again
+	record := context.WithoutCancel(ctx)
+	succeeded := 0
+	for _, item := range pending {
+		if ctx.Err() != nil {
+			return succeeded, ctx.Err()
+		}
+
+		responseStatus, err := deliverer.send(ctx, item)
+		if err == nil {
+			if err := deliverer.webhookRepository.MarkWebhookDeliverySucceeded(record, item.Delivery, *responseStatus, time.Now()); err != nil {
+				return succeeded, err
+			}
+			succeeded++
+			continue
+		}
+
+		var nextAttemptAt *time.Time
+		if item.Delivery.Attempts+1 < deliverer.MaxAttempts {
+			next := time.Now().Add(deliverer.backoff(item.Delivery.Attempts))
+			nextAttemptAt = &next
+		}
+		disabled, markErr := deliverer.webhookRepository.MarkWebhookDeliveryFailed(record, item.Delivery, responseStatus, err, nextAttemptAt, deliverer.DisableAfter)
+		if markErr != nil {
+			return succeeded, markErr
+		}
+		if disabled {
+			slog.WarnContext(ctx, "webhook subscription disabled after repeated failures", "subscription_id", item.Delivery.SubscriptionID)
+		}
+	}
+
+	return succeeded, nil
+}
+
+// send POSTs the delivery and returns the response status, if any was received.
+func (deliverer *Deliverer) send(ctx context.Context, item repository.PendingWebhookDelivery) (*int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, item.URL, bytes.NewReader(item.Payload))
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventTypeHeader, item.Delivery.EventType)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(item.Delivery.ID, 10))
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(item.Secret, timestamp, item.Payload))
+
+	resp, err := deliverer.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &resp.StatusCode, fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+	return &resp.StatusCode, nil
+}
+
+func (deliverer *Deliverer) backoff(attempts int) time.Duration {
+	delay := deliverer.BaseBackoff
+	for i := 0; i < attempts && delay < deliverer.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, deliverer.MaxBackoff)
+}
@@ -0,0 +1,27 @@
+package webhooks
+
+import (
+	"api/model"
+	"api/repository"
+	"context"
+)
+
+// Sink is an outbox sink that turns each domain event into a pending delivery
+// for every matching subscription. The Deliverer sends them.
+type Sink struct {
+	webhookRepository *repository.WebhookRepository
+}
+
+func NewSink(webhookRepository *repository.WebhookRepository) *Sink {
+	return &Sink{
+		webhookRepository: webhookRepository,
+	}
+}
+
+func (sink *Sink) Name() string {
+	return "webhooks"
+}
+
+func (sink *Sink) Publish(ctx context.Context, event model.Event) error {
+	return sink.webhookRepository.EnqueueWebhookDeliveries(ctx, event)
+}